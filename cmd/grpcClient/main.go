@@ -33,7 +33,7 @@ func main() {
 
 func getShortURL(ctx context.Context, c pb.ShortenerClient) {
 	var req pb.GetShortURLRequest
-	req.OriginalURL = "grpc1.ru"
+	req.OriginalURL = "https://grpc1.ru"
 
 	resp, err := c.GetShortURL(ctx, &req)
 	if err != nil {
@@ -44,7 +44,7 @@ func getShortURL(ctx context.Context, c pb.ShortenerClient) {
 
 func getShortURLs(ctx context.Context, c pb.ShortenerClient) {
 	var originalURLWithID pb.OriginalURLWithID
-	originalURLWithID.OriginalURL = "getShortURLs.ru"
+	originalURLWithID.OriginalURL = "https://getShortURLs.ru"
 	originalURLWithID.CorrelationID = "1"
 
 	var req pb.GetShortURLsRequest
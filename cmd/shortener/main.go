@@ -17,10 +17,14 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/geoip"
 	"github.com/nasik90/url-shortener/internal/app/grpcapi"
 	"github.com/nasik90/url-shortener/internal/app/grpcserver"
 	handler "github.com/nasik90/url-shortener/internal/app/handlers"
+	"github.com/nasik90/url-shortener/internal/app/health"
 	"github.com/nasik90/url-shortener/internal/app/logger"
+	"github.com/nasik90/url-shortener/internal/app/metadata"
+	"github.com/nasik90/url-shortener/internal/app/policy"
 	"github.com/nasik90/url-shortener/internal/app/server"
 	"github.com/nasik90/url-shortener/internal/app/service"
 	"github.com/nasik90/url-shortener/internal/app/storage"
@@ -44,6 +48,45 @@ func main() {
 		panic(err)
 	}
 
+	if options.DedupMode == "" {
+		options.DedupMode = string(settings.DefaultDedupMode(options.DatabaseDSN != ""))
+	}
+	dedupMode, err := settings.ParseDedupMode(options.DedupMode)
+	if err != nil {
+		logger.Log.Fatal("parse dedup mode", zap.String("error", err.Error()))
+	}
+	interstitial, err := settings.ParseInterstitialMode(options.Interstitial)
+	if err != nil {
+		logger.Log.Fatal("parse interstitial mode", zap.String("error", err.Error()))
+	}
+	if err := settings.ValidateRedirectStatus(options.RedirectStatus); err != nil {
+		logger.Log.Fatal("validate redirect status", zap.String("error", err.Error()))
+	}
+	redirectCacheMaxAge, err := time.ParseDuration(options.RedirectCacheMaxAge)
+	if err != nil {
+		logger.Log.Fatal("parse redirect cache max age", zap.String("error", err.Error()))
+	}
+	healthCheckInterval, err := time.ParseDuration(options.HealthCheckInterval)
+	if err != nil {
+		logger.Log.Fatal("parse health check interval", zap.String("error", err.Error()))
+	}
+	deletedRetention, err := time.ParseDuration(options.DeletedRetention)
+	if err != nil {
+		logger.Log.Fatal("parse deleted retention", zap.String("error", err.Error()))
+	}
+	clickRetention, err := time.ParseDuration(options.ClickRetention)
+	if err != nil {
+		logger.Log.Fatal("parse click retention", zap.String("error", err.Error()))
+	}
+	if err = service.ValidateCodeLength(options.CodeLength); err != nil {
+		logger.Log.Fatal("validate code length", zap.String("error", err.Error()))
+	}
+	if err = service.ValidateCodeLength(options.CodeMaxLength); err != nil {
+		logger.Log.Fatal("validate max code length", zap.String("error", err.Error()))
+	}
+	// длина кодов может расти до CodeMaxLength, колонка в БД должна вмещать такие коды
+	maxCodeLength := max(options.CodeLength, options.CodeMaxLength)
+
 	if options.EnablePprofServ {
 		go func() {
 			if err := http.ListenAndServe(options.PprofServerAddress, nil); err != nil {
@@ -53,37 +96,101 @@ func main() {
 		}()
 	}
 
-	var (
-		repo service.Repository
-		err  error
-	)
+	var repo service.Repository
+	var clickRepo service.ClickRepository
 	if options.DatabaseDSN != "" {
 		conn, err := sql.Open("pgx", options.DatabaseDSN)
 		if err != nil {
 			logger.Log.Fatal("open pgx conn", zap.String("DatabaseDSN", options.DatabaseDSN), zap.String("error", err.Error()))
 		}
-		repo, err = pg.NewStore(conn)
+		repo, err = pg.NewStore(conn, dedupMode, maxCodeLength)
 		if err != nil {
 			logger.Log.Fatal("create pg repo", zap.String("DatabaseDSN", options.DatabaseDSN), zap.String("error", err.Error()))
 		}
+		clickRepo, err = pg.NewClickStore(conn)
+		if err != nil {
+			logger.Log.Fatal("create pg click repo", zap.String("DatabaseDSN", options.DatabaseDSN), zap.String("error", err.Error()))
+		}
 
 	} else if options.FilePath != "" {
-		repo, err = storage.NewFileStorage(options.FilePath)
+		repo, err = storage.NewFileStorage(options.FilePath, dedupMode)
 		if err != nil {
 			logger.Log.Fatal("create file repo", zap.String("FilePath", options.FilePath), zap.String("error", err.Error()))
 		}
+		// события переходов пишутся рядом с файлом ссылок
+		clickRepo, err = storage.NewClickFileStorage(options.FilePath + ".clicks")
+		if err != nil {
+			logger.Log.Fatal("create file click repo", zap.String("FilePath", options.FilePath), zap.String("error", err.Error()))
+		}
 
 	} else {
-		repo = storage.NewLocalCahce()
+		repo = storage.NewLocalCahce(dedupMode)
+		clickRepo = storage.NewClickCache()
 	}
 
-	service := service.NewService(repo, options.BaseURL)
+	// счетчик последовательных стратегий продолжается с границы, сохраненной в хранилище
+	codeGen, err := service.NewCodeGenerator(options.CodeStrategy, options.CodeAlphabet, options.CodeSalt, repo)
+	if err != nil {
+		logger.Log.Fatal("create code generator", zap.String("error", err.Error()))
+	}
+
+	serviceOpts := []service.Option{
+		service.WithAllowedSchemes(options.AllowedURLSchemes),
+		service.WithSortedQuery(options.SortQueryParams),
+		service.WithCodeGenerator(codeGen, options.CodeLength),
+		service.WithMaxCodeLength(maxCodeLength),
+		service.WithClickRepository(clickRepo),
+		service.WithClickRetention(clickRetention),
+		service.WithInterstitial(interstitial),
+		service.WithRedirectStatus(options.RedirectStatus),
+		service.WithRedirectCacheMaxAge(redirectCacheMaxAge),
+		service.WithDeletedRetention(deletedRetention),
+		service.WithReservedCodes(options.ReservePurgedCodes),
+	}
+	// без базы GeoIP события переходов сохраняются без местоположения
+	if options.GeoIPDatabase != "" {
+		geo, err := geoip.NewResolver(options.GeoIPDatabase)
+		if err != nil {
+			logger.Log.Fatal("open geoip database", zap.String("path", options.GeoIPDatabase), zap.String("error", err.Error()))
+		}
+		go geo.HandleReload()
+		serviceOpts = append(serviceOpts, service.WithGeoResolver(geo))
+	}
+
+	// нулевой интервал выключает проверку доступности оригинальных урлов
+	if healthCheckInterval > 0 {
+		serviceOpts = append(serviceOpts, service.WithHealthChecker(health.NewChecker(), healthCheckInterval))
+	}
+
+	// списки доменов перечитываются при изменении файлов и по сигналу SIGHUP
+	if options.BlocklistFile != "" || options.AllowlistFile != "" {
+		urlPolicy, err := policy.NewEngine(options.BlocklistFile, options.AllowlistFile)
+		if err != nil {
+			logger.Log.Fatal("load policy lists", zap.String("blocklist", options.BlocklistFile),
+				zap.String("allowlist", options.AllowlistFile), zap.String("error", err.Error()))
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go urlPolicy.HandleReload(hup)
+		serviceOpts = append(serviceOpts, service.WithURLPolicy(urlPolicy))
+	}
+
+	if options.FetchMetadata {
+		serviceOpts = append(serviceOpts, service.WithMetadataFetcher(metadata.NewFetcher()))
+	}
+	service := service.NewService(repo, options.BaseURL, serviceOpts...)
 	handler := handler.NewHandler(service, options.TrustedSubnet)
-	shortenerServer := grpcapi.NewShortenerServer(service)
+	shortenerServer := grpcapi.NewShortenerServer(service, options.TrustedSubnet)
 	server := server.NewServer(handler, options.ServerAddress, options.EnableHTTPS)
 	grpcServer := grpcserver.NewGRPCServer(shortenerServer, ":3200", options.TrustedSubnet)
 
 	go service.HandleRecords()
+	go service.HandleExpiredRecords()
+	go service.HandleDeletedRecords()
+	go service.HandleClicks()
+	go service.HandleOldClicks()
+	go service.HandleHealthChecks()
+	go service.HandleMetadataFetches()
 
 	var wg sync.WaitGroup
 
@@ -120,6 +227,11 @@ func main() {
 		}
 		logger.Log.Info("closing grpc server")
 		grpcServer.StopServer()
+		logger.Log.Info("flushing clicks")
+		service.CloseClicks()
+		if err := clickRepo.Close(); err != nil {
+			logger.Log.Error("close click storage", zap.String("error", err.Error()))
+		}
 		logger.Log.Info("closing the storage")
 		if err := repo.Close(); err != nil {
 			logger.Log.Error("close storage", zap.String("error", err.Error()))
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
 )
 
 // Настройки короткого URL.
@@ -15,8 +19,134 @@ const (
 	ShortURLlen = 8
 	// TemplateForRand - допустимые символы для формирования короткого URL.
 	TemplateForRand = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// TemplateUnambiguous - алфавит без похожих символов 0/O, 1/l/I.
+	TemplateUnambiguous = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	// MaxCodeLen - максимальная длина генерируемого короткого кода.
+	MaxCodeLen = 64
+	// AliasMinLen - минимальная длина пользовательского алиаса.
+	AliasMinLen = 3
+	// AliasMaxLen - максимальная длина пользовательского алиаса.
+	AliasMaxLen = 32
 )
 
+// DedupMode - режим дедупликации оригинальных URL.
+type DedupMode string
+
+// Режимы дедупликации оригинальных URL.
+const (
+	// DedupGlobal - оригинальный URL уникален во всем сервисе.
+	DedupGlobal DedupMode = "global"
+	// DedupPerUser - оригинальный URL уникален в пределах пользователя.
+	DedupPerUser DedupMode = "per_user"
+	// DedupOff - дедупликация выключена, на один URL можно создать несколько ссылок.
+	DedupOff DedupMode = "off"
+)
+
+// DefaultDedupMode возвращает режим дедупликации по умолчанию для хранилища: в БД оригинальные URL
+// всегда были уникальны, а хранилища в памяти и в файле сохраняли каждый URL заново.
+func DefaultDedupMode(database bool) DedupMode {
+	if database {
+		return DedupGlobal
+	}
+	return DedupOff
+}
+
+// ParseDedupMode проверяет и возвращает режим дедупликации.
+func ParseDedupMode(mode string) (DedupMode, error) {
+	switch m := DedupMode(mode); m {
+	case DedupGlobal, DedupPerUser, DedupOff:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown dedup mode %q", mode)
+}
+
+// InterstitialMode - режим показа промежуточной страницы перед редиректом.
+type InterstitialMode string
+
+// Режимы показа промежуточной страницы.
+const (
+	// InterstitialOff - промежуточная страница показывается только для ссылок, у которых она включена.
+	InterstitialOff InterstitialMode = "off"
+	// InterstitialExternal - промежуточная страница показывается для ссылок на сторонние домены.
+	InterstitialExternal InterstitialMode = "external"
+	// InterstitialAlways - промежуточная страница показывается для всех ссылок.
+	InterstitialAlways InterstitialMode = "always"
+)
+
+// ParseInterstitialMode проверяет и возвращает режим показа промежуточной страницы.
+func ParseInterstitialMode(mode string) (InterstitialMode, error) {
+	switch m := InterstitialMode(mode); m {
+	case InterstitialOff, InterstitialExternal, InterstitialAlways:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown interstitial mode %q", mode)
+}
+
+// QueryPassthrough - режим передачи параметров запроса короткой ссылки в адрес назначения.
+type QueryPassthrough string
+
+// Режимы передачи параметров запроса.
+const (
+	// QueryPassthroughOff - параметры запроса короткой ссылки отбрасываются.
+	QueryPassthroughOff QueryPassthrough = "off"
+	// QueryPassthroughMerge - параметры запроса добавляются к адресу назначения и заменяют его одноименные параметры.
+	QueryPassthroughMerge QueryPassthrough = "merge"
+	// QueryPassthroughPreserve - параметры запроса добавляются к адресу назначения, одноименные параметры адреса сохраняются.
+	QueryPassthroughPreserve QueryPassthrough = "preserve"
+)
+
+// ParseQueryPassthrough проверяет и возвращает режим передачи параметров запроса.
+// Пустая строка означает режим off.
+func ParseQueryPassthrough(mode string) (QueryPassthrough, error) {
+	switch m := QueryPassthrough(mode); m {
+	case "":
+		return QueryPassthroughOff, nil
+	case QueryPassthroughOff, QueryPassthroughMerge, QueryPassthroughPreserve:
+		return m, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidQueryPassthrough, mode)
+}
+
+// UTMParams - UTM метки, которые добавляются к адресу назначения при переходе, если их там нет.
+type UTMParams struct {
+	Source   string `json:"source,omitzero"`
+	Medium   string `json:"medium,omitzero"`
+	Campaign string `json:"campaign,omitzero"`
+	Term     string `json:"term,omitzero"`
+	Content  string `json:"content,omitzero"`
+}
+
+// Values возвращает непустые метки в виде параметров запроса utm_*.
+func (p UTMParams) Values() url.Values {
+	values := make(url.Values)
+	for name, value := range map[string]string{
+		"utm_source":   p.Source,
+		"utm_medium":   p.Medium,
+		"utm_campaign": p.Campaign,
+		"utm_term":     p.Term,
+		"utm_content":  p.Content,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// ValidateRedirectStatus проверяет http статус редиректа по ссылке: 301, 302, 307 или 308.
+func ValidateRedirectStatus(status int) error {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	}
+	return fmt.Errorf("%w: %d", ErrInvalidRedirectStatus, status)
+}
+
+// PermanentRedirect сообщает, является ли редирект со статусом status постоянным.
+func PermanentRedirect(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
 // Переменные - ошибки.
 var (
 	// ErrOriginalURLNotFound - ошибка - оригинальный URL не найден.
@@ -25,20 +155,119 @@ var (
 	ErrOriginalURLNotUnique = errors.New("original URL is not unique")
 	// ErrShortURLNotUnique - ошибка - короткий URL не найден.
 	ErrShortURLNotUnique = errors.New("short URL is not unique")
+	// ErrAliasNotUnique - ошибка - пользовательский алиас уже занят.
+	ErrAliasNotUnique = errors.New("alias is already taken")
+	// ErrInvalidAlias - ошибка - пользовательский алиас не прошел проверку.
+	ErrInvalidAlias = errors.New("invalid alias")
+	// ErrInvalidExpiration - ошибка - некорректно задан срок действия ссылки.
+	ErrInvalidExpiration = errors.New("invalid link expiration")
+	// ErrInvalidMaxClicks - ошибка - некорректно задано ограничение числа переходов.
+	ErrInvalidMaxClicks = errors.New("invalid max clicks")
+	// ErrInvalidPassword - ошибка - пароль ссылки не прошел проверку.
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrPasswordRequired - ошибка - для перехода по ссылке нужен пароль.
+	ErrPasswordRequired = errors.New("password required")
+	// ErrInterstitialRequired - ошибка - перед переходом по ссылке нужно показать промежуточную страницу.
+	ErrInterstitialRequired = errors.New("interstitial page required")
+	// ErrWrongPassword - ошибка - передан неверный пароль ссылки.
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrTooManyAttempts - ошибка - превышено число попыток ввода пароля.
+	ErrTooManyAttempts = errors.New("too many password attempts")
+	// ErrInvalidURL - ошибка - оригинальный URL не прошел проверку.
+	ErrInvalidURL = errors.New("invalid URL")
+	// ErrNotOwner - ошибка - ссылка принадлежит другому пользователю.
+	ErrNotOwner = errors.New("short URL belongs to another user")
+	// ErrInvalidStatsQuery - ошибка - некорректные параметры запроса статистики.
+	ErrInvalidStatsQuery = errors.New("invalid stats query")
+	// ErrInvalidQROptions - ошибка - некорректные параметры QR кода.
+	ErrInvalidQROptions = errors.New("invalid QR code options")
+	// ErrInvalidQueryPassthrough - ошибка - неизвестный режим передачи параметров запроса.
+	ErrInvalidQueryPassthrough = errors.New("invalid query passthrough mode")
+	// ErrInvalidRedirectStatus - ошибка - недопустимый http статус редиректа.
+	ErrInvalidRedirectStatus = errors.New("invalid redirect status")
+	// ErrInvalidRoutingRule - ошибка - некорректное правило маршрутизации ссылки.
+	ErrInvalidRoutingRule = errors.New("invalid routing rule")
+	// ErrInvalidVariants - ошибка - некорректные варианты адреса назначения ссылки.
+	ErrInvalidVariants = errors.New("invalid variants")
+	// ErrInvalidTags - ошибка - некорректные теги или папка ссылки.
+	ErrInvalidTags = errors.New("invalid tags")
+	// ErrMetadataDisabled - ошибка - загрузка сведений о страницах назначения отключена.
+	ErrMetadataDisabled = errors.New("metadata fetching is disabled")
+	// ErrInvalidUserURLsQuery - ошибка - некорректные параметры выборки урлов пользователя.
+	ErrInvalidUserURLsQuery = errors.New("invalid user urls query")
+	// ErrVersionNotFound - ошибка - версия ссылки не найдена.
+	ErrVersionNotFound = errors.New("link version not found")
+	// ErrVersionConflict - ошибка - ссылку одновременно изменили в другом запросе.
+	ErrVersionConflict = errors.New("link version conflict")
+	// ErrURLBlocked - ошибка - оригинальный URL запрещен политикой доменов.
+	ErrURLBlocked = errors.New("URL is blocked by policy")
+	// ErrShortURLAllocation - ошибка - не удалось подобрать свободный короткий URL.
+	ErrShortURLAllocation = errors.New("failed to allocate unique short URL")
 )
 
+// InvalidURLError - ошибка проверки оригинального URL с указанием причины.
+type InvalidURLError struct {
+	URL    string
+	Reason string
+}
+
+// Error возвращает текст ошибки.
+func (e *InvalidURLError) Error() string {
+	return fmt.Sprintf("invalid URL %q: %s", e.URL, e.Reason)
+}
+
+// Unwrap позволяет сравнивать ошибку с ErrInvalidURL через errors.Is.
+func (e *InvalidURLError) Unwrap() error {
+	return ErrInvalidURL
+}
+
+// BlockedURLError - ошибка запрета оригинального URL политикой доменов с указанием причины.
+type BlockedURLError struct {
+	URL    string
+	Reason string
+}
+
+// Error возвращает текст ошибки.
+func (e *BlockedURLError) Error() string {
+	return fmt.Sprintf("URL %q is blocked: %s", e.URL, e.Reason)
+}
+
+// Unwrap позволяет сравнивать ошибку с ErrURLBlocked через errors.Is.
+func (e *BlockedURLError) Unwrap() error {
+	return ErrURLBlocked
+}
+
 // Options - структура для хранения настроек сервиса.
 type Options struct {
-	ServerAddress      string `json:"server_address"`
-	BaseURL            string `json:"base_url"`
-	LogLevel           string `json:"log_level"`
-	FilePath           string `json:"file_storage_path"`
-	DatabaseDSN        string `json:"database_dsn"`
-	EnablePprofServ    bool   `json:"enable_pprof_server"`
-	PprofServerAddress string `json:"pprof_server_address"`
-	EnableHTTPS        bool   `json:"enable_https,omitempty"`
-	Config             string
-	TrustedSubnet      string `json:"trusted_subnet"`
+	ServerAddress       string `json:"server_address"`
+	BaseURL             string `json:"base_url"`
+	LogLevel            string `json:"log_level"`
+	FilePath            string `json:"file_storage_path"`
+	DatabaseDSN         string `json:"database_dsn"`
+	EnablePprofServ     bool   `json:"enable_pprof_server"`
+	PprofServerAddress  string `json:"pprof_server_address"`
+	EnableHTTPS         bool   `json:"enable_https,omitempty"`
+	Config              string
+	TrustedSubnet       string `json:"trusted_subnet"`
+	AllowedURLSchemes   string `json:"allowed_url_schemes"`
+	SortQueryParams     bool   `json:"sort_query_params"`
+	DedupMode           string `json:"dedup_mode"`
+	CodeStrategy        string `json:"code_strategy"`
+	CodeLength          int    `json:"code_length"`
+	CodeMaxLength       int    `json:"code_max_length"`
+	CodeAlphabet        string `json:"code_alphabet"`
+	CodeSalt            string `json:"code_salt"`
+	GeoIPDatabase       string `json:"geoip_database"`
+	Interstitial        string `json:"interstitial"`
+	BlocklistFile       string `json:"blocklist_file"`
+	AllowlistFile       string `json:"allowlist_file"`
+	HealthCheckInterval string `json:"health_check_interval"`
+	RedirectStatus      int    `json:"redirect_status"`
+	RedirectCacheMaxAge string `json:"redirect_cache_max_age"`
+	DeletedRetention    string `json:"deleted_retention"`
+	ReservePurgedCodes  bool   `json:"reserve_purged_codes"`
+	FetchMetadata       bool   `json:"fetch_metadata"`
+	ClickRetention      string `json:"click_retention"`
 }
 
 // Record - структура для хранения короткого URL - UserID.
@@ -47,6 +276,465 @@ type Record struct {
 	UserID   string
 }
 
+// LinkOptions - структура для хранения необязательных параметров создаваемой короткой ссылки.
+type LinkOptions struct {
+	// Alias - желаемый короткий код. Если пустой, код генерируется сервисом.
+	Alias string
+	// ExpiresAt - момент, после которого ссылка перестает работать. Нулевое значение - бессрочно.
+	ExpiresAt time.Time
+	// TTL - время жизни ссылки с момента создания. Нельзя задавать вместе с ExpiresAt.
+	TTL time.Duration
+	// MaxClicks - допустимое число переходов по ссылке. 0 - без ограничений.
+	MaxClicks int64
+	// Password - пароль для перехода по ссылке. Пустой - без пароля.
+	Password string
+	// Interstitial - всегда показывать промежуточную страницу перед переходом.
+	Interstitial bool
+	// FallbackURL - адрес, на который ведет ссылка, если оригинальный URL перестал отвечать. Пустой - не задан.
+	FallbackURL string
+	// QueryPassthrough - режим передачи параметров запроса в адрес назначения. Пустой - off.
+	QueryPassthrough string
+	// UTM - UTM метки по умолчанию.
+	UTM UTMParams
+	// RedirectStatus - http статус редиректа: 301, 302, 307 или 308. 0 - статус по умолчанию сервиса.
+	RedirectStatus int
+	// Routing - упорядоченный список правил выбора адреса назначения по клиенту.
+	Routing []RoutingRule
+	// Variants - взвешенные варианты адреса назначения для A/B тестов.
+	Variants []Variant
+	// Tags - произвольные теги ссылки, Folder - папка ссылки. Пустая - без папки.
+	Tags   []string
+	Folder string
+}
+
+// LinkAttributes - структура для хранения атрибутов короткой ссылки в репозитории.
+type LinkAttributes struct {
+	// ExpiresAt - момент истечения срока действия ссылки. Нулевое значение - бессрочно.
+	ExpiresAt time.Time
+	// MaxClicks - допустимое число переходов по ссылке. 0 - без ограничений.
+	MaxClicks int64
+	// Clicks - число совершенных переходов по ссылке.
+	Clicks int64
+	// PasswordHash - bcrypt хеш пароля ссылки. Пустой - ссылка без пароля.
+	PasswordHash string
+	// CreatedAt - момент создания ссылки. Нулевое значение - неизвестен (ссылки, созданные до его учета).
+	CreatedAt time.Time
+	// Interstitial - всегда показывать промежуточную страницу перед переходом.
+	Interstitial bool
+	// FallbackURL - адрес, на который ведет мертвая ссылка. Пустой - не задан.
+	FallbackURL string
+	// Health - результат последней проверки доступности оригинального URL.
+	Health LinkHealth
+	// QueryPassthrough - режим передачи параметров запроса в адрес назначения.
+	QueryPassthrough QueryPassthrough
+	// UTM - UTM метки по умолчанию.
+	UTM UTMParams
+	// RedirectStatus - http статус редиректа. 0 - статус по умолчанию сервиса.
+	RedirectStatus int
+	// Routing - упорядоченный список правил выбора адреса назначения по клиенту.
+	Routing []RoutingRule
+	// Variants - взвешенные варианты адреса назначения для A/B тестов.
+	Variants []Variant
+	// Tags, Folder - теги и папка ссылки. Служат для организации ссылок и не входят в версии ссылки.
+	Tags   []string
+	Folder string
+	// Metadata - сведения о странице назначения. Загружаются сервисом и не входят в версии ссылки.
+	Metadata LinkMetadata
+}
+
+// Editable возвращает изменяемые владельцем атрибуты ссылки, которые входят в версию ссылки:
+// без счетчика переходов, момента создания, состояния проверок, тегов, папки и сведений о странице.
+func (a LinkAttributes) Editable() LinkAttributes {
+	a.Clicks = 0
+	a.CreatedAt = time.Time{}
+	a.Health = LinkHealth{}
+	a.Tags = nil
+	a.Folder = ""
+	a.Metadata = LinkMetadata{}
+	return a
+}
+
+// LinkUpdate - изменения ссылки владельцем. Поля со значением nil не меняются.
+type LinkUpdate struct {
+	OriginalURL *string
+	// ExpiresAt - новый срок действия, нулевое время снимает ограничение.
+	ExpiresAt *time.Time
+	MaxClicks *int64
+	// Password - новый пароль, пустая строка снимает пароль.
+	Password         *string
+	Interstitial     *bool
+	FallbackURL      *string
+	QueryPassthrough *string
+	UTM              *UTMParams
+	RedirectStatus   *int
+	Routing          *[]RoutingRule
+	Variants         *[]Variant
+	// Tags, Folder - новые теги и папка. Их изменение не создает новую версию ссылки.
+	Tags   *[]string
+	Folder *string
+}
+
+// Versioned сообщает, меняет ли изменение что-то кроме тегов и папки, то есть создает ли оно новую версию ссылки.
+func (u LinkUpdate) Versioned() bool {
+	u.Tags, u.Folder = nil, nil
+	return u != LinkUpdate{}
+}
+
+// TagCount - тег и число ссылок пользователя с этим тегом.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// LinkVersion - версия ссылки: адрес назначения и изменяемые атрибуты, действующие с момента CreatedAt.
+// Первая версия - ссылка в момент создания, каждое изменение и откат добавляют новую версию.
+type LinkVersion struct {
+	Version     int            `json:"version"`
+	OriginalURL string         `json:"original_url"`
+	Attrs       LinkAttributes `json:"attrs"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// MaxVariants - предельное число вариантов адреса назначения у одной ссылки.
+const MaxVariants = 10
+
+// Variant - вариант адреса назначения ссылки в A/B тесте.
+type Variant struct {
+	// Name - имя варианта, попадает в события переходов. По умолчанию - буква по порядку: a, b, ...
+	Name string `json:"name"`
+	// URL - адрес назначения.
+	URL string `json:"url"`
+	// Weight - вес варианта: доля переходов пропорциональна весу.
+	Weight int `json:"weight"`
+}
+
+// Платформы клиентов в правилах маршрутизации.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// RoutingRule - правило маршрутизации ссылки: переход на URL, если клиент подходит под все заданные условия.
+// Пустое условие подходит любому клиенту.
+type RoutingRule struct {
+	// Platform - платформа клиента по User-Agent: ios, android, windows, macos или linux.
+	Platform string `json:"platform,omitzero"`
+	// Language - язык клиента по Accept-Language, например "ru" или "en-us".
+	Language string `json:"language,omitzero"`
+	// Country - ISO код страны клиента, определяется при наличии базы GeoIP.
+	Country string `json:"country,omitzero"`
+	// URL - адрес назначения.
+	URL string `json:"url"`
+}
+
+// DeadLinkFailures - число неудачных проверок подряд, после которого ссылка считается мертвой.
+const DeadLinkFailures = 3
+
+// LinkHealth - результат проверки доступности оригинального URL.
+type LinkHealth struct {
+	// Status - http статус последней проверки, 0 - проверка не выполнялась или завершилась сетевой ошибкой.
+	Status int `json:"status,omitzero"`
+	// CheckedAt - момент последней проверки. Нулевое значение - ссылка еще не проверялась.
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	// Failures - число неудачных проверок подряд.
+	Failures int `json:"failures,omitzero"`
+}
+
+// Dead сообщает, считается ли ссылка мертвой.
+func (h LinkHealth) Dead() bool {
+	return h.Failures >= DeadLinkFailures
+}
+
+// UserURL - ссылка в списке ссылок пользователя.
+type UserURL struct {
+	ShortURL    string
+	OriginalURL string
+	Health      LinkHealth
+	// CreatedAt - момент создания ссылки. Нулевое значение - неизвестен.
+	CreatedAt time.Time
+	// Clicks - число совершенных переходов по ссылке.
+	Clicks int64
+	// ExpiresAt - момент истечения срока действия ссылки. Нулевое значение - бессрочно.
+	ExpiresAt time.Time
+	// Deleted - ссылка помечена на удаление.
+	Deleted bool
+	Tags    []string
+	Folder  string
+	// Metadata - сведения о странице назначения.
+	Metadata LinkMetadata
+}
+
+// LinkMetadata - сведения о странице назначения ссылки: заголовок и метки OpenGraph.
+type LinkMetadata struct {
+	// Title - содержимое тега <title>.
+	Title     string    `json:"title,omitzero"`
+	OpenGraph OpenGraph `json:"og,omitzero"`
+	// FetchedAt - момент последней загрузки. Нулевое значение - страница еще не загружалась.
+	FetchedAt time.Time `json:"fetched_at,omitzero"`
+	// Error - причина неудачи последней загрузки. Пустая - загрузка успешна.
+	Error string `json:"error,omitzero"`
+}
+
+// OpenGraph - метки og:* страницы назначения.
+type OpenGraph struct {
+	Title       string `json:"title,omitzero"`
+	Description string `json:"description,omitzero"`
+	Image       string `json:"image,omitzero"`
+	SiteName    string `json:"site_name,omitzero"`
+	Type        string `json:"type,omitzero"`
+	URL         string `json:"url,omitzero"`
+}
+
+// UserURLsSort - поле сортировки урлов пользователя.
+type UserURLsSort string
+
+// Поля сортировки урлов пользователя. При равных значениях ссылки упорядочиваются по короткому урлу.
+// clicks сортирует по счетчику переходов ссылки, который хранилища увеличивают при каждом переходе.
+const (
+	UserURLsSortCreated UserURLsSort = "created_at"
+	UserURLsSortClicks  UserURLsSort = "clicks"
+)
+
+// SortOrder - направление сортировки.
+type SortOrder string
+
+// Направления сортировки.
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// LinkFilter - режим отбора ссылок по признаку, например по пометке на удаление.
+type LinkFilter string
+
+// Режимы отбора ссылок.
+const (
+	// LinkFilterExclude - ссылки с признаком не выбираются.
+	LinkFilterExclude LinkFilter = "exclude"
+	// LinkFilterInclude - признак не влияет на выборку.
+	LinkFilterInclude LinkFilter = "include"
+	// LinkFilterOnly - выбираются только ссылки с признаком.
+	LinkFilterOnly LinkFilter = "only"
+)
+
+// UserURLsCursor - позиция в выборке урлов пользователя: ключ сортировки и короткий урл последней выданной ссылки.
+type UserURLsCursor struct {
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Clicks    int64     `json:"clicks,omitzero"`
+	ShortURL  string    `json:"short_url"`
+}
+
+// UserURLsQuery - параметры выборки урлов пользователя.
+type UserURLsQuery struct {
+	// Limit - наибольшее число ссылок в выборке. 0 - без ограничения.
+	Limit int
+	// Cursor - курсор следующей страницы из предыдущего ответа. Пустой - первая страница.
+	Cursor string
+	// After - разобранный сервисом Cursor: выборка начинается со ссылки, следующей за этой позицией.
+	After *UserURLsCursor
+	// Sort, Order - поле и направление сортировки.
+	Sort  UserURLsSort
+	Order SortOrder
+	// Deleted, Expired - отбор помеченных на удаление и истекших ссылок.
+	Deleted LinkFilter
+	Expired LinkFilter
+	// Search - подстрока оригинального урла, регистр не учитывается. Пустая - без поиска.
+	Search string
+	// Tag, Folder - отбор ссылок с тегом и в папке. Пустые - без отбора.
+	Tag    string
+	Folder string
+}
+
+// UserURLsPage - страница урлов пользователя.
+type UserURLsPage struct {
+	URLs []UserURL
+	// NextCursor - курсор следующей страницы. Пустой - страница последняя.
+	NextCursor string
+}
+
+// Match сообщает, проходит ли ссылка с признаком flag отбор f.
+func (f LinkFilter) Match(flag bool) bool {
+	switch f {
+	case LinkFilterExclude:
+		return !flag
+	case LinkFilterOnly:
+		return flag
+	}
+	return true
+}
+
+// Expired сообщает, истек ли срок действия ссылки на момент now.
+func (a LinkAttributes) Expired(now time.Time) bool {
+	return !a.ExpiresAt.IsZero() && !now.Before(a.ExpiresAt)
+}
+
+// ClicksExhausted сообщает, исчерпан ли лимит переходов по ссылке.
+func (a LinkAttributes) ClicksExhausted() bool {
+	return a.MaxClicks > 0 && a.Clicks >= a.MaxClicks
+}
+
+// RedirectRequest - параметры запроса перехода по короткой ссылке.
+type RedirectRequest struct {
+	// Confirmed - пользователь подтвердил переход на промежуточной странице.
+	Confirmed bool
+	// Query - параметры запроса короткой ссылки.
+	Query url.Values
+	// UserAgent, AcceptLanguage, IP - сведения о клиенте для правил маршрутизации и выбора варианта.
+	UserAgent      string
+	AcceptLanguage string
+	IP             string
+	// Variant - вариант адреса назначения, который клиент уже получал раньше.
+	Variant string
+}
+
+// AcceptRedirect проверяет доступную ссылку перед тем, как хранилище засчитает переход по ней.
+// Ошибка означает, что переход не состоялся и не засчитывается.
+type AcceptRedirect func(originalURL string, attrs LinkAttributes) error
+
+// Redirect - результат перехода по короткой ссылке.
+type Redirect struct {
+	// URL - адрес перехода.
+	URL string
+	// Status - http статус редиректа.
+	Status int
+	// CacheMaxAge - сколько браузеры и общие кэши могут кэшировать редирект. 0 - кэшировать нельзя.
+	CacheMaxAge time.Duration
+	// Variant - имя выбранного варианта адреса назначения, пустое - у ссылки нет вариантов.
+	Variant string
+}
+
+// LinkPreview - сведения о ссылке для страницы предпросмотра.
+type LinkPreview struct {
+	ShortURL string
+	// OriginalURL - адрес назначения, пустой для ссылок, защищенных паролем.
+	OriginalURL string
+	CreatedAt   time.Time
+	// Clicks - число переходов по ссылке, в том числе по ссылкам без лимита переходов.
+	Clicks int64
+	// Protected - ссылка защищена паролем.
+	Protected bool
+	// Metadata - сведения о странице назначения, пустые для ссылок, защищенных паролем.
+	Metadata LinkMetadata
+}
+
+// ClickEvent - структура для хранения события перехода по короткой ссылке.
+type ClickEvent struct {
+	Time      time.Time `json:"time"`
+	ShortURL  string    `json:"short_url"`
+	Referer   string    `json:"referer,omitzero"`
+	UserAgent string    `json:"user_agent,omitzero"`
+	// IP - адрес клиента. Используется для местоположения и уникальных посетителей и не сохраняется.
+	IP             string `json:"ip,omitzero"`
+	AcceptLanguage string `json:"accept_language,omitzero"`
+	// Country, Region, City - ISO код страны, код региона и город клиента, заполняются при наличии базы GeoIP.
+	Country string `json:"country,omitzero"`
+	Region  string `json:"region,omitzero"`
+	City    string `json:"city,omitzero"`
+	// Variant - имя варианта адреса назначения, на который был выполнен переход.
+	Variant string `json:"variant,omitzero"`
+}
+
+// GeoLocation - местоположение клиента, определенное по IP адресу.
+type GeoLocation struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// VisitorSketchKey - ключ скетча уникальных посетителей: короткий урл и день (полночь UTC).
+// Пустой ShortURL обозначает скетч посетителей всех ссылок сервиса.
+type VisitorSketchKey struct {
+	ShortURL string
+	Day      time.Time
+}
+
+// SketchDay возвращает день скетча, в который попадает момент t.
+func SketchDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// StatsBucket - шаг временного ряда статистики переходов.
+type StatsBucket string
+
+// Шаги временного ряда статистики переходов.
+const (
+	StatsBucketHour StatsBucket = "hour"
+	StatsBucketDay  StatsBucket = "day"
+)
+
+// Duration возвращает длительность шага.
+func (b StatsBucket) Duration() time.Duration {
+	if b == StatsBucketHour {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// StatsQuery - параметры запроса статистики переходов по ссылке.
+type StatsQuery struct {
+	// From, To - полуинтервал [From, To) времени переходов.
+	From time.Time
+	To   time.Time
+	// Bucket - шаг временного ряда.
+	Bucket StatsBucket
+	// Top - число значений в топах рефереров, user agent и стран.
+	Top int
+}
+
+// StatsPoint - точка временного ряда статистики: число переходов за шаг, начинающийся в Time.
+type StatsPoint struct {
+	Time   time.Time
+	Clicks int64
+}
+
+// StatsCount - значение с числом переходов для топов статистики.
+type StatsCount struct {
+	Value string
+	Count int64
+}
+
+// LinkStats - статистика переходов по ссылке за интервал.
+type LinkStats struct {
+	TotalClicks int64
+	// UniqueVisitors - оценка числа уникальных посетителей по дневным скетчам HyperLogLog
+	// за дни, пересекающиеся с интервалом.
+	UniqueVisitors int64
+	Series         []StatsPoint
+	TopReferrers   []StatsCount
+	TopUserAgents  []StatsCount
+	TopCountries   []StatsCount
+	// Variants - число переходов по каждому варианту адреса назначения.
+	Variants []StatsCount
+}
+
+// Форматы изображения QR кода.
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// QROptions - параметры изображения QR кода короткой ссылки.
+// Нулевые значения заменяются значениями по умолчанию.
+type QROptions struct {
+	// Format - формат изображения: png или svg.
+	Format string
+	// Size - ширина и высота изображения в пикселях.
+	Size int
+	// Level - уровень коррекции ошибок: L, M, Q или H.
+	Level string
+	// Margin - ширина пустой рамки в модулях QR кода. Отрицательное значение - рамка по умолчанию.
+	Margin int
+}
+
+// BatchURL - структура для хранения элемента пакетного сокращения URL.
+type BatchURL struct {
+	OriginalURL string
+	Alias       string
+}
+
 // ParseFlags - парсит флаги командной строки или переменные окружения.
 // Результат сохраняет в структуру Options.
 func ParseFlags(o *Options) {
@@ -56,7 +744,7 @@ func ParseFlags(o *Options) {
 		o.Config = config
 	}
 
-	var config Options
+	var config configFile
 	var err error
 	if o.Config != "" {
 		config, err = readConfig(o.Config)
@@ -83,9 +771,41 @@ func fillDefaultOptions(o *Options) {
 	o.PprofServerAddress = ":8181"
 	o.EnableHTTPS = false
 	o.TrustedSubnet = "192.168.0.1/24"
+	o.AllowedURLSchemes = "http,https"
+	o.SortQueryParams = false
+	// пустой режим выбирается по хранилищу в DefaultDedupMode
+	o.DedupMode = ""
+	o.CodeStrategy = "random"
+	o.CodeLength = ShortURLlen
+	o.CodeMaxLength = 16
+	o.CodeAlphabet = TemplateForRand
+	o.CodeSalt = ""
+	o.GeoIPDatabase = ""
+	o.Interstitial = string(InterstitialOff)
+	o.BlocklistFile = ""
+	o.AllowlistFile = ""
+	o.HealthCheckInterval = "0"
+	o.RedirectStatus = http.StatusTemporaryRedirect
+	o.RedirectCacheMaxAge = "8760h"
+	// безвозвратное удаление помеченных на удаление ссылок и их статистики переходов включается явно
+	o.DeletedRetention = "0"
+	o.ReservePurgedCodes = false
+	o.FetchMetadata = true
+	// удаление старой статистики переходов включается явно
+	o.ClickRetention = "0"
+}
+
+// configFile - содержимое файла конфигурации.
+// Флаги, включенные по умолчанию или добавленные позже, читаются указателями,
+// чтобы отсутствующий в файле ключ не менял значение по умолчанию.
+type configFile struct {
+	Options
+	SortQueryParams    *bool `json:"sort_query_params"`
+	ReservePurgedCodes *bool `json:"reserve_purged_codes"`
+	FetchMetadata      *bool `json:"fetch_metadata"`
 }
 
-func overrideOptionsFromConfig(o *Options, c *Options) {
+func overrideOptionsFromConfig(o *Options, c *configFile) {
 	if c.ServerAddress != "" {
 		o.ServerAddress = c.ServerAddress
 	}
@@ -109,21 +829,72 @@ func overrideOptionsFromConfig(o *Options, c *Options) {
 	if c.TrustedSubnet != "" {
 		o.TrustedSubnet = c.TrustedSubnet
 	}
+	if c.AllowedURLSchemes != "" {
+		o.AllowedURLSchemes = c.AllowedURLSchemes
+	}
+	if c.SortQueryParams != nil {
+		o.SortQueryParams = *c.SortQueryParams
+	}
+	if c.DedupMode != "" {
+		o.DedupMode = c.DedupMode
+	}
+	if c.CodeStrategy != "" {
+		o.CodeStrategy = c.CodeStrategy
+	}
+	if c.CodeLength != 0 {
+		o.CodeLength = c.CodeLength
+	}
+	if c.CodeMaxLength != 0 {
+		o.CodeMaxLength = c.CodeMaxLength
+	}
+	if c.CodeAlphabet != "" {
+		o.CodeAlphabet = c.CodeAlphabet
+	}
+	if c.CodeSalt != "" {
+		o.CodeSalt = c.CodeSalt
+	}
+	if c.GeoIPDatabase != "" {
+		o.GeoIPDatabase = c.GeoIPDatabase
+	}
+	if c.Interstitial != "" {
+		o.Interstitial = c.Interstitial
+	}
+	if c.BlocklistFile != "" {
+		o.BlocklistFile = c.BlocklistFile
+	}
+	if c.AllowlistFile != "" {
+		o.AllowlistFile = c.AllowlistFile
+	}
+	if c.HealthCheckInterval != "" {
+		o.HealthCheckInterval = c.HealthCheckInterval
+	}
+	if c.RedirectStatus != 0 {
+		o.RedirectStatus = c.RedirectStatus
+	}
+	if c.RedirectCacheMaxAge != "" {
+		o.RedirectCacheMaxAge = c.RedirectCacheMaxAge
+	}
+	if c.DeletedRetention != "" {
+		o.DeletedRetention = c.DeletedRetention
+	}
+	if c.ReservePurgedCodes != nil {
+		o.ReservePurgedCodes = *c.ReservePurgedCodes
+	}
+	if c.FetchMetadata != nil {
+		o.FetchMetadata = *c.FetchMetadata
+	}
+	if c.ClickRetention != "" {
+		o.ClickRetention = c.ClickRetention
+	}
 }
 
-func readConfig(fname string) (Options, error) {
-	var config Options
+func readConfig(fname string) (configFile, error) {
+	var config configFile
 
-	f, err := os.Open(fname)
+	data, err := os.ReadFile(fname)
 	if err != nil {
 		return config, err
 	}
-	out := make([]byte, 1024)
-	var n int
-	if n, err = f.Read(out); err != nil {
-		return config, err
-	}
-	data := out[:n]
 	err = json.Unmarshal(data, &config)
 	return config, err
 
@@ -140,6 +911,25 @@ func overrideOptionsFromCmd(o *Options) {
 	flag.StringVar(&o.PprofServerAddress, "pa", o.PprofServerAddress, "address and port to run pprof server")
 	flag.BoolVar(&o.EnableHTTPS, "s", o.EnableHTTPS, "enable HTPPS connection")
 	flag.StringVar(&o.TrustedSubnet, "t", o.TrustedSubnet, "trusted subnet")
+	flag.StringVar(&o.AllowedURLSchemes, "schemes", o.AllowedURLSchemes, "comma separated list of allowed original URL schemes")
+	flag.BoolVar(&o.SortQueryParams, "sort-query", o.SortQueryParams, "sort query parameters of original URLs")
+	flag.StringVar(&o.DedupMode, "dedup", o.DedupMode, "original URL deduplication mode: global, per_user or off (default global with a database, off with file or memory storage)")
+	flag.StringVar(&o.CodeStrategy, "code-strategy", o.CodeStrategy, "short code strategy: random, counter, hashids or hash")
+	flag.IntVar(&o.CodeLength, "code-len", o.CodeLength, "short code length")
+	flag.IntVar(&o.CodeMaxLength, "code-max-len", o.CodeMaxLength, "max short code length reachable by adaptive growth")
+	flag.StringVar(&o.CodeAlphabet, "code-alphabet", o.CodeAlphabet, "short code alphabet")
+	flag.StringVar(&o.CodeSalt, "code-salt", o.CodeSalt, "salt for hashids short code strategy")
+	flag.StringVar(&o.GeoIPDatabase, "geoip", o.GeoIPDatabase, "path to MaxMind .mmdb database for click geolocation")
+	flag.StringVar(&o.Interstitial, "interstitial", o.Interstitial, "show interstitial page before redirect: off, external or always")
+	flag.StringVar(&o.BlocklistFile, "blocklist", o.BlocklistFile, "path to file with blocked domain and URL rules")
+	flag.StringVar(&o.AllowlistFile, "allowlist", o.AllowlistFile, "path to file with allowed domain and URL rules")
+	flag.StringVar(&o.HealthCheckInterval, "health-interval", o.HealthCheckInterval, "original URL health check interval, 0 (default) disables checks")
+	flag.IntVar(&o.RedirectStatus, "redirect-status", o.RedirectStatus, "default redirect status: 301, 302, 307 or 308")
+	flag.StringVar(&o.RedirectCacheMaxAge, "redirect-cache-max-age", o.RedirectCacheMaxAge, "how long browsers and CDNs can cache permanent redirects, 0 disables caching")
+	flag.StringVar(&o.DeletedRetention, "deleted-retention", o.DeletedRetention, "how long deleted links can be restored before they and their click statistics are purged, 0 (default) keeps them forever")
+	flag.BoolVar(&o.ReservePurgedCodes, "reserve-purged-codes", o.ReservePurgedCodes, "keep short codes of purged links reserved instead of freeing them for reuse")
+	flag.BoolVar(&o.FetchMetadata, "fetch-metadata", o.FetchMetadata, "fetch title and OpenGraph tags of destination pages in the background")
+	flag.StringVar(&o.ClickRetention, "click-retention", o.ClickRetention, "how long click events and visitor statistics are kept, 0 (default) keeps them forever")
 	flag.Parse()
 }
 
@@ -170,4 +960,85 @@ func overrideOptionsFromEnv(o *Options) {
 	if trustedSubnet := os.Getenv("TRUSTED_SUBNET"); trustedSubnet != "" {
 		o.TrustedSubnet = trustedSubnet
 	}
+	if allowedURLSchemes := os.Getenv("ALLOWED_URL_SCHEMES"); allowedURLSchemes != "" {
+		o.AllowedURLSchemes = allowedURLSchemes
+	}
+	if sortQueryParams := os.Getenv("SORT_QUERY_PARAMS"); sortQueryParams != "" {
+		val, err := strconv.ParseBool(sortQueryParams)
+		if err != nil {
+			panic("error parsing env var SORT_QUERY_PARAMS: " + err.Error())
+		}
+		o.SortQueryParams = val
+	}
+	if dedupMode := os.Getenv("DEDUP_MODE"); dedupMode != "" {
+		o.DedupMode = dedupMode
+	}
+	if codeStrategy := os.Getenv("CODE_STRATEGY"); codeStrategy != "" {
+		o.CodeStrategy = codeStrategy
+	}
+	if codeLength := os.Getenv("CODE_LENGTH"); codeLength != "" {
+		val, err := strconv.Atoi(codeLength)
+		if err != nil {
+			panic("error parsing env var CODE_LENGTH: " + err.Error())
+		}
+		o.CodeLength = val
+	}
+	if codeMaxLength := os.Getenv("CODE_MAX_LENGTH"); codeMaxLength != "" {
+		val, err := strconv.Atoi(codeMaxLength)
+		if err != nil {
+			panic("error parsing env var CODE_MAX_LENGTH: " + err.Error())
+		}
+		o.CodeMaxLength = val
+	}
+	if codeAlphabet := os.Getenv("CODE_ALPHABET"); codeAlphabet != "" {
+		o.CodeAlphabet = codeAlphabet
+	}
+	if codeSalt := os.Getenv("CODE_SALT"); codeSalt != "" {
+		o.CodeSalt = codeSalt
+	}
+	if geoIPDatabase := os.Getenv("GEOIP_DATABASE"); geoIPDatabase != "" {
+		o.GeoIPDatabase = geoIPDatabase
+	}
+	if interstitial := os.Getenv("INTERSTITIAL"); interstitial != "" {
+		o.Interstitial = interstitial
+	}
+	if blocklistFile := os.Getenv("BLOCKLIST_FILE"); blocklistFile != "" {
+		o.BlocklistFile = blocklistFile
+	}
+	if allowlistFile := os.Getenv("ALLOWLIST_FILE"); allowlistFile != "" {
+		o.AllowlistFile = allowlistFile
+	}
+	if healthCheckInterval := os.Getenv("HEALTH_CHECK_INTERVAL"); healthCheckInterval != "" {
+		o.HealthCheckInterval = healthCheckInterval
+	}
+	if redirectStatus := os.Getenv("REDIRECT_STATUS"); redirectStatus != "" {
+		val, err := strconv.Atoi(redirectStatus)
+		if err != nil {
+			panic("error parsing env var REDIRECT_STATUS: " + err.Error())
+		}
+		o.RedirectStatus = val
+	}
+	if redirectCacheMaxAge := os.Getenv("REDIRECT_CACHE_MAX_AGE"); redirectCacheMaxAge != "" {
+		o.RedirectCacheMaxAge = redirectCacheMaxAge
+	}
+	if deletedRetention := os.Getenv("DELETED_RETENTION"); deletedRetention != "" {
+		o.DeletedRetention = deletedRetention
+	}
+	if reservePurgedCodes := os.Getenv("RESERVE_PURGED_CODES"); reservePurgedCodes != "" {
+		val, err := strconv.ParseBool(reservePurgedCodes)
+		if err != nil {
+			panic("error parsing env var RESERVE_PURGED_CODES: " + err.Error())
+		}
+		o.ReservePurgedCodes = val
+	}
+	if fetchMetadata := os.Getenv("FETCH_METADATA"); fetchMetadata != "" {
+		val, err := strconv.ParseBool(fetchMetadata)
+		if err != nil {
+			panic("error parsing env var FETCH_METADATA: " + err.Error())
+		}
+		o.FetchMetadata = val
+	}
+	if clickRetention := os.Getenv("CLICK_RETENTION"); clickRetention != "" {
+		o.ClickRetention = clickRetention
+	}
 }
@@ -0,0 +1,124 @@
+// Пакет geoip определяет местоположение клиента по IP адресу с помощью локальной базы MaxMind (.mmdb).
+package geoip
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// reloadInterval - период проверки изменения файла базы.
+const reloadInterval = 30 * time.Second
+
+// record - поля записи базы GeoIP2/GeoLite2 City или Country, которые нужны сервису.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Resolver - структура, которая хранит открытую базу и перечитывает ее при изменении файла.
+type Resolver struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// NewResolver открывает базу по пути path.
+func NewResolver(path string) (*Resolver, error) {
+	r := &Resolver{path: path}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Lookup возвращает местоположение IP адреса ip.
+// Для некорректных и неизвестных адресов возвращает пустое местоположение.
+func (r *Resolver) Lookup(ip string) settings.GeoLocation {
+	var loc settings.GeoLocation
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return loc
+	}
+	var rec record
+	r.mu.RLock()
+	err := r.reader.Lookup(parsed, &rec)
+	r.mu.RUnlock()
+	if err != nil {
+		return loc
+	}
+	loc.Country = rec.Country.ISOCode
+	if len(rec.Subdivisions) > 0 {
+		loc.Region = rec.Subdivisions[0].ISOCode
+	}
+	loc.City = rec.City.Names["en"]
+	return loc
+}
+
+// HandleReload периодически проверяет время изменения файла базы и перечитывает его.
+// При ошибке чтения продолжает работать с ранее загруженной базой.
+func (r *Resolver) HandleReload() {
+	ticker := time.NewTicker(reloadInterval)
+	for range ticker.C {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			logger.Log.Info("cannot stat geoip database", zap.Error(err))
+			continue
+		}
+		r.mu.RLock()
+		changed := !info.ModTime().Equal(r.modTime)
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := r.reload(); err != nil {
+			logger.Log.Info("cannot reload geoip database", zap.Error(err))
+			continue
+		}
+		logger.Log.Info("geoip database reloaded", zap.String("path", r.path))
+	}
+}
+
+// reload открывает файл базы и подменяет им текущую базу.
+func (r *Resolver) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	reader, err := maxminddb.Open(r.path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	old := r.reader
+	r.reader = reader
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+	// поиски держат RLock, поэтому после подмены старой базой уже никто не пользуется
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Close закрывает базу.
+func (r *Resolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reader.Close()
+}
@@ -2,19 +2,40 @@ package grpcapi
 
 import (
 	context "context"
+	"errors"
+	"net"
+	"strings"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
 	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+	"github.com/nasik90/url-shortener/internal/app/storage"
 )
 
 // Service - интерфейс, который описывает методы объектов с типом Service
 type Service interface {
-	GetShortURL(ctx context.Context, originalURL, userID string) (string, error)
-	GetOriginalURL(ctx context.Context, shortURL string) (string, error)
-	GetShortURLs(ctx context.Context, originalURLs map[string]string, userID string) (map[string]string, error)
-	GetUserURLs(ctx context.Context, userID string) (map[string]string, error)
+	GetShortURL(ctx context.Context, originalURL, userID string, opts settings.LinkOptions) (string, error)
+	GetOriginalURL(ctx context.Context, shortURL string, r settings.RedirectRequest) (settings.Redirect, error)
+	GetProtectedOriginalURL(ctx context.Context, shortURL, password string, r settings.RedirectRequest) (settings.Redirect, error)
+	GetShortURLs(ctx context.Context, originalURLs map[string]settings.BatchURL, userID string) (map[string]string, error)
+	GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) (settings.UserURLsPage, error)
 	MarkRecordsForDeletion(ctx context.Context, shortURLs []string, userID string)
 	Ping(ctx context.Context) error
-	GetURLsStats(ctx context.Context) (int, int, error)
+	GetURLsStats(ctx context.Context) (int, int, int64, error)
+	GetLinkStats(ctx context.Context, shortURL, userID string, q settings.StatsQuery) (settings.LinkStats, error)
+	GetQRCode(ctx context.Context, shortURL string, opts settings.QROptions) ([]byte, string, error)
+	UpdateShortURL(ctx context.Context, shortURL, userID string, update settings.LinkUpdate) (settings.LinkVersion, error)
+	GetLinkVersions(ctx context.Context, shortURL, userID string) ([]settings.LinkVersion, error)
+	RollbackShortURL(ctx context.Context, shortURL, userID string, version int) (settings.LinkVersion, error)
+	RestoreShortURL(ctx context.Context, shortURL, userID string) error
+	GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error)
+	MergeTags(ctx context.Context, userID string, from []string, to string) (int, error)
+	RefetchMetadata(ctx context.Context, shortURL, userID string) (settings.LinkMetadata, error)
 }
 
 // ShortenerServerStruct поддерживает все необходимые методы сервера.
@@ -24,10 +45,12 @@ type ShortenerServerStruct struct {
 	UnimplementedShortenerServer
 	// service Service
 	service Service
+	// trustedSubnet - подсеть прокси, которым доверяем адрес клиента в метаданных x-real-ip.
+	trustedSubnet string
 }
 
-func NewShortenerServer(service Service) *ShortenerServerStruct {
-	return &ShortenerServerStruct{service: service}
+func NewShortenerServer(service Service, trustedSubnet string) *ShortenerServerStruct {
+	return &ShortenerServerStruct{service: service, trustedSubnet: trustedSubnet}
 }
 
 // GetShortURL - метод для получения короткого URL по переданному оригинальному URL.
@@ -36,18 +59,49 @@ func (s *ShortenerServerStruct) GetShortURL(ctx context.Context, req *GetShortUR
 		response GetShortURLResponse
 		err      error
 	)
-	response.ShortURL, err = s.service.GetShortURL(ctx, req.OriginalURL, middleware.UserIDFromContext(ctx))
-	return &response, err
+	opts := settings.LinkOptions{
+		Alias:            req.Alias,
+		TTL:              time.Duration(req.TtlSeconds) * time.Second,
+		MaxClicks:        req.MaxClicks,
+		Password:         req.Password,
+		Interstitial:     req.Interstitial,
+		FallbackURL:      req.FallbackURL,
+		QueryPassthrough: req.QueryPassthrough,
+		RedirectStatus:   int(req.RedirectStatus),
+		Tags:             req.Tags,
+		Folder:           req.Folder,
+	}
+	opts.Routing = routingRules(req.Routing)
+	opts.Variants = variants(req.Variants)
+	if req.Utm != nil {
+		opts.UTM = utmParams(req.Utm)
+	}
+	if req.ExpiresAt != 0 {
+		opts.ExpiresAt = time.Unix(req.ExpiresAt, 0)
+	}
+	response.ShortURL, err = s.service.GetShortURL(ctx, req.OriginalURL, middleware.UserIDFromContext(ctx), opts)
+	return &response, statusFromError(err)
 }
 
 // GetOriginalURL - метод для получения оригинального URL по переданному короткому URL.
+// Для ссылок, защищенных паролем, пароль передается в поле password.
+// Промежуточная страница в gRPC не показывается, запрос считается подтвержденным переходом.
+// Правила маршрутизации получают User-Agent и Accept-Language из метаданных запроса и адрес клиента.
 func (s *ShortenerServerStruct) GetOriginalURL(ctx context.Context, req *GetOriginalURLRequest) (*GetOriginalURLResponse, error) {
 	var (
 		response GetOriginalURLResponse
+		redirect settings.Redirect
 		err      error
 	)
-	response.OriginalURL, err = s.service.GetOriginalURL(ctx, req.ShortURL)
-	return &response, err
+	r := s.redirectRequest(ctx)
+	if req.Password != "" {
+		redirect, err = s.service.GetProtectedOriginalURL(ctx, req.ShortURL, req.Password, r)
+	} else {
+		r.Confirmed = true
+		redirect, err = s.service.GetOriginalURL(ctx, req.ShortURL, r)
+	}
+	response.OriginalURL, response.RedirectStatus, response.Variant = redirect.URL, int32(redirect.Status), redirect.Variant
+	return &response, statusFromError(err)
 }
 
 // GetShortURLs - принимает на вход массив структур с указанием correlation_id и оригинального URL.
@@ -57,13 +111,13 @@ func (s *ShortenerServerStruct) GetShortURLs(ctx context.Context, req *GetShortU
 		response GetShortURLsResponse
 		err      error
 	)
-	originalURLs := make(map[string]string)
+	originalURLs := make(map[string]settings.BatchURL)
 	for _, in := range req.OriginalURLs {
-		originalURLs[in.CorrelationID] = in.OriginalURL
+		originalURLs[in.CorrelationID] = settings.BatchURL{OriginalURL: in.OriginalURL, Alias: in.Alias}
 	}
 	shortURLs, err := s.service.GetShortURLs(ctx, originalURLs, middleware.UserIDFromContext(ctx))
 	if err != nil {
-		return &response, err
+		return &response, statusFromError(err)
 	}
 	for corID, shortURL := range shortURLs {
 		var shortURLWithID ShortURLWithID
@@ -75,20 +129,46 @@ func (s *ShortenerServerStruct) GetShortURLs(ctx context.Context, req *GetShortU
 }
 
 // GetUserURLs - возвращает список URL`ов пользователя.
-// Список представляет собой массив структур с указанием короткого и оригинального URL.
+// Список представляет собой страницу структур с указанием короткого и оригинального URL и курсор следующей страницы.
 func (s *ShortenerServerStruct) GetUserURLs(ctx context.Context, req *GetUserURLsRequest) (*GetUserURLsResponse, error) {
-	var (
-		response GetUserURLsResponse
-		err      error
-	)
-	userURLs, err := s.service.GetUserURLs(ctx, middleware.UserIDFromContext(ctx))
+	var response GetUserURLsResponse
+	q := settings.UserURLsQuery{
+		Limit:   int(req.Limit),
+		Cursor:  req.Cursor,
+		Sort:    settings.UserURLsSort(req.Sort),
+		Order:   settings.SortOrder(req.Order),
+		Deleted: settings.LinkFilter(req.Deleted),
+		Expired: settings.LinkFilter(req.Expired),
+		Search:  req.Search,
+		Tag:     req.Tag,
+		Folder:  req.Folder,
+	}
+	page, err := s.service.GetUserURLs(ctx, middleware.UserIDFromContext(ctx), q)
 	if err != nil {
-		return &response, err
+		return &response, statusFromError(err)
 	}
-	for shortURL, originalURL := range userURLs {
+	response.NextCursor = page.NextCursor
+	for _, userURL := range page.URLs {
 		var shortOriginalURL ShortOriginalURL
-		shortOriginalURL.ShortURL = shortURL
-		shortOriginalURL.OriginalURL = originalURL
+		shortOriginalURL.ShortURL = userURL.ShortURL
+		shortOriginalURL.OriginalURL = userURL.OriginalURL
+		shortOriginalURL.LastStatus = int32(userURL.Health.Status)
+		if !userURL.Health.CheckedAt.IsZero() {
+			shortOriginalURL.LastCheckedAt = userURL.Health.CheckedAt.Unix()
+		}
+		shortOriginalURL.Failures = int32(userURL.Health.Failures)
+		shortOriginalURL.Dead = userURL.Health.Dead()
+		if !userURL.CreatedAt.IsZero() {
+			shortOriginalURL.CreatedAt = userURL.CreatedAt.Unix()
+		}
+		if !userURL.ExpiresAt.IsZero() {
+			shortOriginalURL.ExpiresAt = userURL.ExpiresAt.Unix()
+		}
+		shortOriginalURL.Clicks = userURL.Clicks
+		shortOriginalURL.Deleted = userURL.Deleted
+		shortOriginalURL.Tags = userURL.Tags
+		shortOriginalURL.Folder = userURL.Folder
+		shortOriginalURL.Metadata = linkMetadata(userURL.Metadata)
 		response.ShortOriginalURLs = append(response.ShortOriginalURLs, &shortOriginalURL)
 	}
 	return &response, nil
@@ -100,6 +180,15 @@ func (s *ShortenerServerStruct) MarkRecordsForDeletion(ctx context.Context, req
 	return nil, nil
 }
 
+// RestoreShortURL - снимает пометку на удаление с короткого URL пользователя, пока его не удалили безвозвратно.
+func (s *ShortenerServerStruct) RestoreShortURL(ctx context.Context, req *RestoreShortURLRequest) (*RestoreShortURLResponse, error) {
+	var response RestoreShortURLResponse
+	if err := s.service.RestoreShortURL(ctx, req.ShortURL, middleware.UserIDFromContext(ctx)); err != nil {
+		return &response, statusFromError(err)
+	}
+	return &response, nil
+}
+
 // Ping - проверяет работоспособность сервера и БД.
 func (s *ShortenerServerStruct) Ping(ctx context.Context, req *PingRequest) (*PingResponse, error) {
 	err := s.service.Ping(ctx)
@@ -109,18 +198,332 @@ func (s *ShortenerServerStruct) Ping(ctx context.Context, req *PingRequest) (*Pi
 	return nil, nil
 }
 
-// GetUserURLs - возвращает количество URL и пользователей.
+// GetURLsStats - возвращает количество URL, пользователей и уникальных посетителей.
 // В настройках сервиса обязательно должен быть указан CIDR и передан в метаданных X-Real-IP IP адрес.
 func (s *ShortenerServerStruct) GetURLsStats(ctx context.Context, req *GetURLsStatsRequest) (*GetURLsStatsResponse, error) {
 	var (
 		response GetURLsStatsResponse
 		err      error
 	)
-	urls, users, err := s.service.GetURLsStats(ctx)
+	urls, users, visitors, err := s.service.GetURLsStats(ctx)
 	if err != nil {
 		return &response, err
 	}
 	response.Urls = int64(urls)
 	response.Users = int64(users)
+	response.Visitors = visitors
+	return &response, nil
+}
+
+// GetLinkStats - возвращает статистику переходов по ссылке пользователя.
+func (s *ShortenerServerStruct) GetLinkStats(ctx context.Context, req *GetLinkStatsRequest) (*GetLinkStatsResponse, error) {
+	var response GetLinkStatsResponse
+	q := settings.StatsQuery{Bucket: settings.StatsBucket(req.Bucket), Top: int(req.Top)}
+	if req.From != 0 {
+		q.From = time.Unix(req.From, 0)
+	}
+	if req.To != 0 {
+		q.To = time.Unix(req.To, 0)
+	}
+	stats, err := s.service.GetLinkStats(ctx, req.ShortURL, middleware.UserIDFromContext(ctx), q)
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	response.TotalClicks = stats.TotalClicks
+	response.UniqueVisitors = stats.UniqueVisitors
+	for _, p := range stats.Series {
+		response.Series = append(response.Series, &StatsPoint{Time: p.Time.Unix(), Clicks: p.Clicks})
+	}
+	response.TopReferrers = statsCounts(stats.TopReferrers)
+	response.TopUserAgents = statsCounts(stats.TopUserAgents)
+	response.TopCountries = statsCounts(stats.TopCountries)
+	response.Variants = statsCounts(stats.Variants)
+	return &response, nil
+}
+
+// GetQRCode - возвращает изображение QR кода полного короткого URL.
+func (s *ShortenerServerStruct) GetQRCode(ctx context.Context, req *GetQRCodeRequest) (*GetQRCodeResponse, error) {
+	var (
+		response GetQRCodeResponse
+		err      error
+	)
+	opts := settings.QROptions{Format: req.Format, Size: int(req.Size), Level: req.Level, Margin: -1}
+	if req.Margin != nil {
+		if *req.Margin < 0 {
+			return &response, status.Error(codes.InvalidArgument, "margin must not be negative")
+		}
+		opts.Margin = int(*req.Margin)
+	}
+	response.Image, response.ContentType, err = s.service.GetQRCode(ctx, req.ShortURL, opts)
+	return &response, statusFromError(err)
+}
+
+func statsCounts(values []settings.StatsCount) []*StatsCount {
+	res := make([]*StatsCount, 0, len(values))
+	for _, v := range values {
+		res = append(res, &StatsCount{Value: v.Value, Count: v.Count})
+	}
+	return res
+}
+
+// GetUserTags - возвращает теги ссылок пользователя с числом ссылок, от самых частых.
+func (s *ShortenerServerStruct) GetUserTags(ctx context.Context, req *GetUserTagsRequest) (*GetUserTagsResponse, error) {
+	var response GetUserTagsResponse
+	tags, err := s.service.GetUserTags(ctx, middleware.UserIDFromContext(ctx))
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	for _, t := range tags {
+		response.Tags = append(response.Tags, &TagCount{Tag: t.Tag, Count: int32(t.Count)})
+	}
 	return &response, nil
 }
+
+// MergeTags - заменяет теги from у ссылок пользователя тегом to. Возвращает число измененных ссылок.
+func (s *ShortenerServerStruct) MergeTags(ctx context.Context, req *MergeTagsRequest) (*MergeTagsResponse, error) {
+	var response MergeTagsResponse
+	updated, err := s.service.MergeTags(ctx, middleware.UserIDFromContext(ctx), req.From, req.To)
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	response.Updated = int32(updated)
+	return &response, nil
+}
+
+// RefetchMetadata - заново загружает заголовок и метки OpenGraph страницы назначения ссылки пользователя.
+func (s *ShortenerServerStruct) RefetchMetadata(ctx context.Context, req *RefetchMetadataRequest) (*RefetchMetadataResponse, error) {
+	var response RefetchMetadataResponse
+	meta, err := s.service.RefetchMetadata(ctx, req.ShortURL, middleware.UserIDFromContext(ctx))
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	response.Metadata = linkMetadata(meta)
+	return &response, nil
+}
+
+// linkMetadata преобразует сведения о странице назначения в сообщение LinkMetadata.
+func linkMetadata(meta settings.LinkMetadata) *LinkMetadata {
+	result := &LinkMetadata{
+		Title:         meta.Title,
+		OgTitle:       meta.OpenGraph.Title,
+		OgDescription: meta.OpenGraph.Description,
+		OgImage:       meta.OpenGraph.Image,
+		OgSiteName:    meta.OpenGraph.SiteName,
+		OgType:        meta.OpenGraph.Type,
+		OgURL:         meta.OpenGraph.URL,
+		Error:         meta.Error,
+	}
+	if !meta.FetchedAt.IsZero() {
+		result.FetchedAt = meta.FetchedAt.Unix()
+	}
+	return result
+}
+
+// UpdateShortURL - изменяет адрес назначения и атрибуты ссылки пользователя. Не переданные поля не меняются.
+// Возвращает новую версию ссылки.
+func (s *ShortenerServerStruct) UpdateShortURL(ctx context.Context, req *UpdateShortURLRequest) (*UpdateShortURLResponse, error) {
+	var response UpdateShortURLResponse
+	update := settings.LinkUpdate{
+		OriginalURL:      req.OriginalURL,
+		MaxClicks:        req.MaxClicks,
+		Password:         req.Password,
+		Interstitial:     req.Interstitial,
+		FallbackURL:      req.FallbackURL,
+		QueryPassthrough: req.QueryPassthrough,
+		Folder:           req.Folder,
+	}
+	if req.ExpiresAt != nil {
+		var expiresAt time.Time
+		if *req.ExpiresAt != 0 {
+			expiresAt = time.Unix(*req.ExpiresAt, 0)
+		}
+		update.ExpiresAt = &expiresAt
+	}
+	if req.Utm != nil {
+		utm := utmParams(req.Utm)
+		update.UTM = &utm
+	}
+	if req.RedirectStatus != nil {
+		redirectStatus := int(*req.RedirectStatus)
+		update.RedirectStatus = &redirectStatus
+	}
+	if req.Routing != nil {
+		rules := routingRules(req.Routing.Rules)
+		update.Routing = &rules
+	}
+	if req.Variants != nil {
+		list := variants(req.Variants.Variants)
+		update.Variants = &list
+	}
+	if req.Tags != nil {
+		update.Tags = &req.Tags.Tags
+	}
+	version, err := s.service.UpdateShortURL(ctx, req.ShortURL, middleware.UserIDFromContext(ctx), update)
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	response.Version = linkVersion(version)
+	return &response, nil
+}
+
+// GetLinkVersions - возвращает версии ссылки пользователя от первой до текущей.
+func (s *ShortenerServerStruct) GetLinkVersions(ctx context.Context, req *GetLinkVersionsRequest) (*GetLinkVersionsResponse, error) {
+	var response GetLinkVersionsResponse
+	versions, err := s.service.GetLinkVersions(ctx, req.ShortURL, middleware.UserIDFromContext(ctx))
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	for _, v := range versions {
+		response.Versions = append(response.Versions, linkVersion(v))
+	}
+	return &response, nil
+}
+
+// RollbackShortURL - возвращает ссылке пользователя адрес назначения и атрибуты одной из версий.
+// Возвращает новую версию ссылки.
+func (s *ShortenerServerStruct) RollbackShortURL(ctx context.Context, req *RollbackShortURLRequest) (*RollbackShortURLResponse, error) {
+	var response RollbackShortURLResponse
+	version, err := s.service.RollbackShortURL(ctx, req.ShortURL, middleware.UserIDFromContext(ctx), int(req.Version))
+	if err != nil {
+		return &response, statusFromError(err)
+	}
+	response.Version = linkVersion(version)
+	return &response, nil
+}
+
+func linkVersion(v settings.LinkVersion) *LinkVersion {
+	res := &LinkVersion{
+		Version:          int32(v.Version),
+		OriginalURL:      v.OriginalURL,
+		MaxClicks:        v.Attrs.MaxClicks,
+		Protected:        v.Attrs.PasswordHash != "",
+		Interstitial:     v.Attrs.Interstitial,
+		FallbackURL:      v.Attrs.FallbackURL,
+		QueryPassthrough: string(v.Attrs.QueryPassthrough),
+		Utm: &UTMParams{
+			Source:   v.Attrs.UTM.Source,
+			Medium:   v.Attrs.UTM.Medium,
+			Campaign: v.Attrs.UTM.Campaign,
+			Term:     v.Attrs.UTM.Term,
+			Content:  v.Attrs.UTM.Content,
+		},
+		RedirectStatus: int32(v.Attrs.RedirectStatus),
+	}
+	if !v.CreatedAt.IsZero() {
+		res.CreatedAt = v.CreatedAt.Unix()
+	}
+	if !v.Attrs.ExpiresAt.IsZero() {
+		res.ExpiresAt = v.Attrs.ExpiresAt.Unix()
+	}
+	for _, rule := range v.Attrs.Routing {
+		res.Routing = append(res.Routing, &RoutingRule{Platform: rule.Platform, Language: rule.Language, Country: rule.Country, Url: rule.URL})
+	}
+	for _, variant := range v.Attrs.Variants {
+		res.Variants = append(res.Variants, &Variant{Name: variant.Name, Url: variant.URL, Weight: int32(variant.Weight)})
+	}
+	return res
+}
+
+func utmParams(utm *UTMParams) settings.UTMParams {
+	return settings.UTMParams{
+		Source:   utm.Source,
+		Medium:   utm.Medium,
+		Campaign: utm.Campaign,
+		Term:     utm.Term,
+		Content:  utm.Content,
+	}
+}
+
+func routingRules(rules []*RoutingRule) []settings.RoutingRule {
+	var res []settings.RoutingRule
+	for _, rule := range rules {
+		res = append(res, settings.RoutingRule{
+			Platform: rule.Platform,
+			Language: rule.Language,
+			Country:  rule.Country,
+			URL:      rule.Url,
+		})
+	}
+	return res
+}
+
+func variants(list []*Variant) []settings.Variant {
+	var res []settings.Variant
+	for _, v := range list {
+		res = append(res, settings.Variant{Name: v.Name, URL: v.Url, Weight: int(v.Weight)})
+	}
+	return res
+}
+
+// statusFromError преобразует ошибки сервиса в ошибки gRPC с соответствующим кодом.
+// Неизвестные ошибки возвращаются без изменений.
+func statusFromError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, settings.ErrInvalidURL), errors.Is(err, settings.ErrInvalidAlias), errors.Is(err, settings.ErrInvalidExpiration),
+		errors.Is(err, settings.ErrInvalidMaxClicks), errors.Is(err, settings.ErrInvalidPassword), errors.Is(err, settings.ErrInvalidQueryPassthrough),
+		errors.Is(err, settings.ErrInvalidRedirectStatus), errors.Is(err, settings.ErrInvalidRoutingRule), errors.Is(err, settings.ErrInvalidVariants),
+		errors.Is(err, settings.ErrInvalidStatsQuery), errors.Is(err, settings.ErrInvalidQROptions),
+		errors.Is(err, settings.ErrInvalidUserURLsQuery), errors.Is(err, settings.ErrInvalidTags):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, settings.ErrPasswordRequired):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, settings.ErrWrongPassword), errors.Is(err, settings.ErrNotOwner), errors.Is(err, settings.ErrURLBlocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, settings.ErrOriginalURLNotFound), errors.Is(err, settings.ErrVersionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, settings.ErrTooManyAttempts):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, storage.ErrRecordMarkedForDel), errors.Is(err, storage.ErrRecordExpired),
+		errors.Is(err, storage.ErrClickLimitReached):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, settings.ErrAliasNotUnique), errors.Is(err, settings.ErrOriginalURLNotUnique):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, settings.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, settings.ErrMetadataDisabled):
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	return err
+}
+
+// redirectRequest возвращает сведения о клиенте для выбора адреса назначения правилами маршрутизации
+// и вариантами A/B теста: User-Agent и Accept-Language из метаданных запроса и адрес клиента.
+// Метаданным x-real-ip доверяем, только если запрос пришел из доверенной подсети прокси.
+func (s *ShortenerServerStruct) redirectRequest(ctx context.Context) settings.RedirectRequest {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := settings.RedirectRequest{
+		UserAgent:      firstValue(md, "user-agent"),
+		AcceptLanguage: firstValue(md, "accept-language"),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(r.IP); err == nil {
+			r.IP = host
+		}
+	}
+	if s.inTrustedSubnet(net.ParseIP(r.IP)) {
+		if realIP := net.ParseIP(strings.TrimSpace(firstValue(md, "x-real-ip"))); realIP != nil {
+			r.IP = realIP.String()
+		}
+	}
+	return r
+}
+
+// inTrustedSubnet сообщает, входит ли адрес ip в доверенную подсеть.
+func (s *ShortenerServerStruct) inTrustedSubnet(ip net.IP) bool {
+	if s.trustedSubnet == "" || ip == nil {
+		return false
+	}
+	_, trustedNet, err := net.ParseCIDR(s.trustedSubnet)
+	return err == nil && trustedNet.Contains(ip)
+}
+
+// firstValue возвращает первое значение ключа key метаданных md или пустую строку.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -22,8 +22,34 @@ const (
 )
 
 type GetShortURLRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalURL   string                 `protobuf:"bytes,1,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalURL string                 `protobuf:"bytes,1,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
+	Alias       string                 `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	// срок действия ссылки в unix-секундах, 0 - бессрочно.
+	ExpiresAt int64 `protobuf:"varint,3,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	// время жизни ссылки в секундах, нельзя задавать вместе с expiresAt.
+	TtlSeconds int64 `protobuf:"varint,4,opt,name=ttlSeconds,proto3" json:"ttlSeconds,omitempty"`
+	// допустимое число переходов по ссылке, 0 - без ограничений.
+	MaxClicks int64 `protobuf:"varint,5,opt,name=maxClicks,proto3" json:"maxClicks,omitempty"`
+	// пароль для перехода по ссылке, пустой - без пароля.
+	Password string `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`
+	// всегда показывать промежуточную страницу перед переходом по ссылке.
+	Interstitial bool `protobuf:"varint,7,opt,name=interstitial,proto3" json:"interstitial,omitempty"`
+	// запасной адрес, на который ведет ссылка, если оригинальный URL перестал отвечать.
+	FallbackURL string `protobuf:"bytes,8,opt,name=fallbackURL,proto3" json:"fallbackURL,omitempty"`
+	// режим передачи параметров запроса в адрес назначения: off (по умолчанию), merge или preserve.
+	QueryPassthrough string `protobuf:"bytes,9,opt,name=queryPassthrough,proto3" json:"queryPassthrough,omitempty"`
+	// UTM метки, которые добавляются к адресу назначения при переходе, если их там нет.
+	Utm *UTMParams `protobuf:"bytes,10,opt,name=utm,proto3" json:"utm,omitempty"`
+	// http статус редиректа: 301, 302, 307 или 308, 0 - статус по умолчанию сервиса.
+	RedirectStatus int32 `protobuf:"varint,11,opt,name=redirectStatus,proto3" json:"redirectStatus,omitempty"`
+	// правила выбора адреса назначения по клиенту, проверяются по порядку до первого подходящего.
+	Routing []*RoutingRule `protobuf:"bytes,12,rep,name=routing,proto3" json:"routing,omitempty"`
+	// взвешенные варианты адреса назначения для A/B тестов, от 2 до 10.
+	Variants []*Variant `protobuf:"bytes,13,rep,name=variants,proto3" json:"variants,omitempty"`
+	// теги ссылки, не больше 20, и папка.
+	Tags          []string `protobuf:"bytes,14,rep,name=tags,proto3" json:"tags,omitempty"`
+	Folder        string   `protobuf:"bytes,15,opt,name=folder,proto3" json:"folder,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,6 +91,313 @@ func (x *GetShortURLRequest) GetOriginalURL() string {
 	return ""
 }
 
+func (x *GetShortURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *GetShortURLRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *GetShortURLRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *GetShortURLRequest) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *GetShortURLRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *GetShortURLRequest) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *GetShortURLRequest) GetFallbackURL() string {
+	if x != nil {
+		return x.FallbackURL
+	}
+	return ""
+}
+
+func (x *GetShortURLRequest) GetQueryPassthrough() string {
+	if x != nil {
+		return x.QueryPassthrough
+	}
+	return ""
+}
+
+func (x *GetShortURLRequest) GetUtm() *UTMParams {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
+func (x *GetShortURLRequest) GetRedirectStatus() int32 {
+	if x != nil {
+		return x.RedirectStatus
+	}
+	return 0
+}
+
+func (x *GetShortURLRequest) GetRouting() []*RoutingRule {
+	if x != nil {
+		return x.Routing
+	}
+	return nil
+}
+
+func (x *GetShortURLRequest) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *GetShortURLRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *GetShortURLRequest) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+type Variant struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// имя варианта, пустое - буква по порядку: a, b, ...
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Url  string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// вес варианта: доля переходов пропорциональна весу.
+	Weight        int32 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_proto_shortener_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Variant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variant) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Variant) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+type RoutingRule struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// платформа клиента по User-Agent: ios, android, windows, macos или linux, пустая - любая.
+	Platform string `protobuf:"bytes,1,opt,name=platform,proto3" json:"platform,omitempty"`
+	// язык клиента по Accept-Language, например "ru" или "en-us", пустой - любой.
+	Language string `protobuf:"bytes,2,opt,name=language,proto3" json:"language,omitempty"`
+	// ISO код страны клиента по GeoIP, пустой - любая.
+	Country       string `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	Url           string `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingRule) Reset() {
+	*x = RoutingRule{}
+	mi := &file_proto_shortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingRule) ProtoMessage() {}
+
+func (x *RoutingRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingRule.ProtoReflect.Descriptor instead.
+func (*RoutingRule) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RoutingRule) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *RoutingRule) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *RoutingRule) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *RoutingRule) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type UTMParams struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Medium        string                 `protobuf:"bytes,2,opt,name=medium,proto3" json:"medium,omitempty"`
+	Campaign      string                 `protobuf:"bytes,3,opt,name=campaign,proto3" json:"campaign,omitempty"`
+	Term          string                 `protobuf:"bytes,4,opt,name=term,proto3" json:"term,omitempty"`
+	Content       string                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UTMParams) Reset() {
+	*x = UTMParams{}
+	mi := &file_proto_shortener_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UTMParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTMParams) ProtoMessage() {}
+
+func (x *UTMParams) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTMParams.ProtoReflect.Descriptor instead.
+func (*UTMParams) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UTMParams) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *UTMParams) GetMedium() string {
+	if x != nil {
+		return x.Medium
+	}
+	return ""
+}
+
+func (x *UTMParams) GetCampaign() string {
+	if x != nil {
+		return x.Campaign
+	}
+	return ""
+}
+
+func (x *UTMParams) GetTerm() string {
+	if x != nil {
+		return x.Term
+	}
+	return ""
+}
+
+func (x *UTMParams) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
 type GetShortURLResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
@@ -74,7 +407,7 @@ type GetShortURLResponse struct {
 
 func (x *GetShortURLResponse) Reset() {
 	*x = GetShortURLResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[1]
+	mi := &file_proto_shortener_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -86,7 +419,7 @@ func (x *GetShortURLResponse) String() string {
 func (*GetShortURLResponse) ProtoMessage() {}
 
 func (x *GetShortURLResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[1]
+	mi := &file_proto_shortener_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -99,7 +432,7 @@ func (x *GetShortURLResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetShortURLResponse.ProtoReflect.Descriptor instead.
 func (*GetShortURLResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{1}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetShortURLResponse) GetShortURL() string {
@@ -110,15 +443,17 @@ func (x *GetShortURLResponse) GetShortURL() string {
 }
 
 type GetOriginalURLRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	// пароль для ссылок, защищенных паролем.
+	Password      string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetOriginalURLRequest) Reset() {
 	*x = GetOriginalURLRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[2]
+	mi := &file_proto_shortener_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -130,7 +465,7 @@ func (x *GetOriginalURLRequest) String() string {
 func (*GetOriginalURLRequest) ProtoMessage() {}
 
 func (x *GetOriginalURLRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[2]
+	mi := &file_proto_shortener_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -143,7 +478,7 @@ func (x *GetOriginalURLRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOriginalURLRequest.ProtoReflect.Descriptor instead.
 func (*GetOriginalURLRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{2}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetOriginalURLRequest) GetShortURL() string {
@@ -153,16 +488,27 @@ func (x *GetOriginalURLRequest) GetShortURL() string {
 	return ""
 }
 
+func (x *GetOriginalURLRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
 type GetOriginalURLResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalURL   string                 `protobuf:"bytes,1,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalURL string                 `protobuf:"bytes,1,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
+	// http статус, с которым выполняется редирект по ссылке.
+	RedirectStatus int32 `protobuf:"varint,2,opt,name=redirectStatus,proto3" json:"redirectStatus,omitempty"`
+	// имя выбранного варианта адреса назначения A/B теста.
+	Variant       string `protobuf:"bytes,3,opt,name=variant,proto3" json:"variant,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetOriginalURLResponse) Reset() {
 	*x = GetOriginalURLResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[3]
+	mi := &file_proto_shortener_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -174,7 +520,7 @@ func (x *GetOriginalURLResponse) String() string {
 func (*GetOriginalURLResponse) ProtoMessage() {}
 
 func (x *GetOriginalURLResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[3]
+	mi := &file_proto_shortener_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -187,7 +533,7 @@ func (x *GetOriginalURLResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOriginalURLResponse.ProtoReflect.Descriptor instead.
 func (*GetOriginalURLResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{3}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetOriginalURLResponse) GetOriginalURL() string {
@@ -197,17 +543,32 @@ func (x *GetOriginalURLResponse) GetOriginalURL() string {
 	return ""
 }
 
+func (x *GetOriginalURLResponse) GetRedirectStatus() int32 {
+	if x != nil {
+		return x.RedirectStatus
+	}
+	return 0
+}
+
+func (x *GetOriginalURLResponse) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
 type OriginalURLWithID struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	OriginalURL   string                 `protobuf:"bytes,1,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
 	CorrelationID string                 `protobuf:"bytes,2,opt,name=correlationID,proto3" json:"correlationID,omitempty"`
+	Alias         string                 `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *OriginalURLWithID) Reset() {
 	*x = OriginalURLWithID{}
-	mi := &file_proto_shortener_proto_msgTypes[4]
+	mi := &file_proto_shortener_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -219,7 +580,7 @@ func (x *OriginalURLWithID) String() string {
 func (*OriginalURLWithID) ProtoMessage() {}
 
 func (x *OriginalURLWithID) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[4]
+	mi := &file_proto_shortener_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -230,47 +591,1785 @@ func (x *OriginalURLWithID) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OriginalURLWithID.ProtoReflect.Descriptor instead.
-func (*OriginalURLWithID) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use OriginalURLWithID.ProtoReflect.Descriptor instead.
+func (*OriginalURLWithID) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *OriginalURLWithID) GetOriginalURL() string {
+	if x != nil {
+		return x.OriginalURL
+	}
+	return ""
+}
+
+func (x *OriginalURLWithID) GetCorrelationID() string {
+	if x != nil {
+		return x.CorrelationID
+	}
+	return ""
+}
+
+func (x *OriginalURLWithID) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type GetShortURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OriginalURLs  []*OriginalURLWithID   `protobuf:"bytes,1,rep,name=originalURLs,proto3" json:"originalURLs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShortURLsRequest) Reset() {
+	*x = GetShortURLsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShortURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShortURLsRequest) ProtoMessage() {}
+
+func (x *GetShortURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShortURLsRequest.ProtoReflect.Descriptor instead.
+func (*GetShortURLsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetShortURLsRequest) GetOriginalURLs() []*OriginalURLWithID {
+	if x != nil {
+		return x.OriginalURLs
+	}
+	return nil
+}
+
+type ShortURLWithID struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	CorrelationID string                 `protobuf:"bytes,2,opt,name=correlationID,proto3" json:"correlationID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortURLWithID) Reset() {
+	*x = ShortURLWithID{}
+	mi := &file_proto_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortURLWithID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortURLWithID) ProtoMessage() {}
+
+func (x *ShortURLWithID) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortURLWithID.ProtoReflect.Descriptor instead.
+func (*ShortURLWithID) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ShortURLWithID) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *ShortURLWithID) GetCorrelationID() string {
+	if x != nil {
+		return x.CorrelationID
+	}
+	return ""
+}
+
+type GetShortURLsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURLs     []*ShortURLWithID      `protobuf:"bytes,1,rep,name=shortURLs,proto3" json:"shortURLs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShortURLsResponse) Reset() {
+	*x = GetShortURLsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShortURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShortURLsResponse) ProtoMessage() {}
+
+func (x *GetShortURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShortURLsResponse.ProtoReflect.Descriptor instead.
+func (*GetShortURLsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetShortURLsResponse) GetShortURLs() []*ShortURLWithID {
+	if x != nil {
+		return x.ShortURLs
+	}
+	return nil
+}
+
+type GetUserURLsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// размер страницы. 0 - все ссылки, а с курсором - размер по умолчанию.
+	Limit int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	// курсор следующей страницы из предыдущего ответа, пустой - первая страница.
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// поле сортировки: created_at (по умолчанию) или clicks.
+	Sort string `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	// направление сортировки: asc или desc (по умолчанию).
+	Order string `protobuf:"bytes,4,opt,name=order,proto3" json:"order,omitempty"`
+	// отбор помеченных на удаление и истекших ссылок: exclude, include или only.
+	Deleted string `protobuf:"bytes,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Expired string `protobuf:"bytes,6,opt,name=expired,proto3" json:"expired,omitempty"`
+	// подстрока оригинального URL.
+	Search string `protobuf:"bytes,7,opt,name=search,proto3" json:"search,omitempty"`
+	// тег и папка ссылок.
+	Tag           string `protobuf:"bytes,8,opt,name=tag,proto3" json:"tag,omitempty"`
+	Folder        string `protobuf:"bytes,9,opt,name=folder,proto3" json:"folder,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserURLsRequest) Reset() {
+	*x = GetUserURLsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserURLsRequest) ProtoMessage() {}
+
+func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*GetUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetUserURLsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetUserURLsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetDeleted() string {
+	if x != nil {
+		return x.Deleted
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetExpired() string {
+	if x != nil {
+		return x.Expired
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *GetUserURLsRequest) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+type ShortOriginalURL struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL    string                 `protobuf:"bytes,1,opt,name=ShortURL,proto3" json:"ShortURL,omitempty"`
+	OriginalURL string                 `protobuf:"bytes,2,opt,name=OriginalURL,proto3" json:"OriginalURL,omitempty"`
+	// http статус последней проверки оригинального URL, 0 - не проверялся или сетевая ошибка.
+	LastStatus int32 `protobuf:"varint,3,opt,name=lastStatus,proto3" json:"lastStatus,omitempty"`
+	// момент последней проверки в unix-секундах, 0 - не проверялся.
+	LastCheckedAt int64 `protobuf:"varint,4,opt,name=lastCheckedAt,proto3" json:"lastCheckedAt,omitempty"`
+	// число неудачных проверок подряд.
+	Failures int32 `protobuf:"varint,5,opt,name=failures,proto3" json:"failures,omitempty"`
+	// ссылка считается мертвой.
+	Dead bool `protobuf:"varint,6,opt,name=dead,proto3" json:"dead,omitempty"`
+	// момент создания и истечения срока действия в unix-секундах, 0 - неизвестен или бессрочно.
+	CreatedAt int64    `protobuf:"varint,7,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	ExpiresAt int64    `protobuf:"varint,8,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	Clicks    int64    `protobuf:"varint,9,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	Deleted   bool     `protobuf:"varint,10,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Tags      []string `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	Folder    string   `protobuf:"bytes,12,opt,name=folder,proto3" json:"folder,omitempty"`
+	// сведения о странице назначения.
+	Metadata      *LinkMetadata `protobuf:"bytes,13,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortOriginalURL) Reset() {
+	*x = ShortOriginalURL{}
+	mi := &file_proto_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortOriginalURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortOriginalURL) ProtoMessage() {}
+
+func (x *ShortOriginalURL) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortOriginalURL.ProtoReflect.Descriptor instead.
+func (*ShortOriginalURL) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ShortOriginalURL) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *ShortOriginalURL) GetOriginalURL() string {
+	if x != nil {
+		return x.OriginalURL
+	}
+	return ""
+}
+
+func (x *ShortOriginalURL) GetLastStatus() int32 {
+	if x != nil {
+		return x.LastStatus
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetLastCheckedAt() int64 {
+	if x != nil {
+		return x.LastCheckedAt
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetFailures() int32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetDead() bool {
+	if x != nil {
+		return x.Dead
+	}
+	return false
+}
+
+func (x *ShortOriginalURL) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+func (x *ShortOriginalURL) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *ShortOriginalURL) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ShortOriginalURL) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+func (x *ShortOriginalURL) GetMetadata() *LinkMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type LinkMetadata struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// содержимое тега <title>.
+	Title string `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	// метки og:title, og:description, og:image, og:site_name, og:type и og:url.
+	OgTitle       string `protobuf:"bytes,2,opt,name=ogTitle,proto3" json:"ogTitle,omitempty"`
+	OgDescription string `protobuf:"bytes,3,opt,name=ogDescription,proto3" json:"ogDescription,omitempty"`
+	OgImage       string `protobuf:"bytes,4,opt,name=ogImage,proto3" json:"ogImage,omitempty"`
+	OgSiteName    string `protobuf:"bytes,5,opt,name=ogSiteName,proto3" json:"ogSiteName,omitempty"`
+	OgType        string `protobuf:"bytes,6,opt,name=ogType,proto3" json:"ogType,omitempty"`
+	OgURL         string `protobuf:"bytes,7,opt,name=ogURL,proto3" json:"ogURL,omitempty"`
+	// момент последней загрузки в unix-секундах, 0 - страница еще не загружалась.
+	FetchedAt int64 `protobuf:"varint,8,opt,name=fetchedAt,proto3" json:"fetchedAt,omitempty"`
+	// причина неудачи последней загрузки, пустая - загрузка успешна.
+	Error         string `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkMetadata) Reset() {
+	*x = LinkMetadata{}
+	mi := &file_proto_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkMetadata) ProtoMessage() {}
+
+func (x *LinkMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkMetadata.ProtoReflect.Descriptor instead.
+func (*LinkMetadata) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LinkMetadata) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgTitle() string {
+	if x != nil {
+		return x.OgTitle
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgDescription() string {
+	if x != nil {
+		return x.OgDescription
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgImage() string {
+	if x != nil {
+		return x.OgImage
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgSiteName() string {
+	if x != nil {
+		return x.OgSiteName
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgType() string {
+	if x != nil {
+		return x.OgType
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetOgURL() string {
+	if x != nil {
+		return x.OgURL
+	}
+	return ""
+}
+
+func (x *LinkMetadata) GetFetchedAt() int64 {
+	if x != nil {
+		return x.FetchedAt
+	}
+	return 0
+}
+
+func (x *LinkMetadata) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetUserURLsResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ShortOriginalURLs []*ShortOriginalURL    `protobuf:"bytes,1,rep,name=shortOriginalURLs,proto3" json:"shortOriginalURLs,omitempty"`
+	// курсор следующей страницы, пустой - страница последняя.
+	NextCursor    string `protobuf:"bytes,2,opt,name=nextCursor,proto3" json:"nextCursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserURLsResponse) Reset() {
+	*x = GetUserURLsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserURLsResponse) ProtoMessage() {}
+
+func (x *GetUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserURLsResponse.ProtoReflect.Descriptor instead.
+func (*GetUserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetUserURLsResponse) GetShortOriginalURLs() []*ShortOriginalURL {
+	if x != nil {
+		return x.ShortOriginalURLs
+	}
+	return nil
+}
+
+func (x *GetUserURLsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type MarkRecordsForDeletionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURLs     []string               `protobuf:"bytes,1,rep,name=shortURLs,proto3" json:"shortURLs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkRecordsForDeletionRequest) Reset() {
+	*x = MarkRecordsForDeletionRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkRecordsForDeletionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkRecordsForDeletionRequest) ProtoMessage() {}
+
+func (x *MarkRecordsForDeletionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkRecordsForDeletionRequest.ProtoReflect.Descriptor instead.
+func (*MarkRecordsForDeletionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *MarkRecordsForDeletionRequest) GetShortURLs() []string {
+	if x != nil {
+		return x.ShortURLs
+	}
+	return nil
+}
+
+type MarkRecordsForDeletionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkRecordsForDeletionResponse) Reset() {
+	*x = MarkRecordsForDeletionResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkRecordsForDeletionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkRecordsForDeletionResponse) ProtoMessage() {}
+
+func (x *MarkRecordsForDeletionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkRecordsForDeletionResponse.ProtoReflect.Descriptor instead.
+func (*MarkRecordsForDeletionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{16}
+}
+
+type RestoreShortURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreShortURLRequest) Reset() {
+	*x = RestoreShortURLRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreShortURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreShortURLRequest) ProtoMessage() {}
+
+func (x *RestoreShortURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreShortURLRequest.ProtoReflect.Descriptor instead.
+func (*RestoreShortURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RestoreShortURLRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+type RestoreShortURLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreShortURLResponse) Reset() {
+	*x = RestoreShortURLResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreShortURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreShortURLResponse) ProtoMessage() {}
+
+func (x *RestoreShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreShortURLResponse.ProtoReflect.Descriptor instead.
+func (*RestoreShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{19}
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+type GetURLsStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetURLsStatsRequest) Reset() {
+	*x = GetURLsStatsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLsStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLsStatsRequest) ProtoMessage() {}
+
+func (x *GetURLsStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLsStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetURLsStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+type GetURLsStatsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Urls  int64                  `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`
+	Users int64                  `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`
+	// оценка числа уникальных посетителей всех ссылок.
+	Visitors      int64 `protobuf:"varint,3,opt,name=visitors,proto3" json:"visitors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetURLsStatsResponse) Reset() {
+	*x = GetURLsStatsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLsStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLsStatsResponse) ProtoMessage() {}
+
+func (x *GetURLsStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLsStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetURLsStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetURLsStatsResponse) GetUrls() int64 {
+	if x != nil {
+		return x.Urls
+	}
+	return 0
+}
+
+func (x *GetURLsStatsResponse) GetUsers() int64 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+func (x *GetURLsStatsResponse) GetVisitors() int64 {
+	if x != nil {
+		return x.Visitors
+	}
+	return 0
+}
+
+type GetLinkStatsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	// начало и конец интервала в unix-секундах, 0 - значение по умолчанию.
+	From int64 `protobuf:"varint,2,opt,name=from,proto3" json:"from,omitempty"`
+	To   int64 `protobuf:"varint,3,opt,name=to,proto3" json:"to,omitempty"`
+	// шаг временного ряда: hour или day.
+	Bucket string `protobuf:"bytes,4,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// размер топов, 0 - значение по умолчанию.
+	Top           int32 `protobuf:"varint,5,opt,name=top,proto3" json:"top,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLinkStatsRequest) Reset() {
+	*x = GetLinkStatsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLinkStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkStatsRequest) ProtoMessage() {}
+
+func (x *GetLinkStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetLinkStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetLinkStatsRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *GetLinkStatsRequest) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *GetLinkStatsRequest) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *GetLinkStatsRequest) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *GetLinkStatsRequest) GetTop() int32 {
+	if x != nil {
+		return x.Top
+	}
+	return 0
+}
+
+type StatsPoint struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// начало шага в unix-секундах.
+	Time          int64 `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Clicks        int64 `protobuf:"varint,2,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsPoint) Reset() {
+	*x = StatsPoint{}
+	mi := &file_proto_shortener_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsPoint) ProtoMessage() {}
+
+func (x *StatsPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsPoint.ProtoReflect.Descriptor instead.
+func (*StatsPoint) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *StatsPoint) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+type StatsCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsCount) Reset() {
+	*x = StatsCount{}
+	mi := &file_proto_shortener_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsCount) ProtoMessage() {}
+
+func (x *StatsCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsCount.ProtoReflect.Descriptor instead.
+func (*StatsCount) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *StatsCount) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *StatsCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type GetLinkStatsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TotalClicks    int64                  `protobuf:"varint,1,opt,name=totalClicks,proto3" json:"totalClicks,omitempty"`
+	UniqueVisitors int64                  `protobuf:"varint,2,opt,name=uniqueVisitors,proto3" json:"uniqueVisitors,omitempty"`
+	Series         []*StatsPoint          `protobuf:"bytes,3,rep,name=series,proto3" json:"series,omitempty"`
+	TopReferrers   []*StatsCount          `protobuf:"bytes,4,rep,name=topReferrers,proto3" json:"topReferrers,omitempty"`
+	TopUserAgents  []*StatsCount          `protobuf:"bytes,5,rep,name=topUserAgents,proto3" json:"topUserAgents,omitempty"`
+	TopCountries   []*StatsCount          `protobuf:"bytes,6,rep,name=topCountries,proto3" json:"topCountries,omitempty"`
+	// число переходов по каждому варианту адреса назначения A/B теста.
+	Variants      []*StatsCount `protobuf:"bytes,7,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLinkStatsResponse) Reset() {
+	*x = GetLinkStatsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLinkStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkStatsResponse) ProtoMessage() {}
+
+func (x *GetLinkStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetLinkStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetLinkStatsResponse) GetTotalClicks() int64 {
+	if x != nil {
+		return x.TotalClicks
+	}
+	return 0
+}
+
+func (x *GetLinkStatsResponse) GetUniqueVisitors() int64 {
+	if x != nil {
+		return x.UniqueVisitors
+	}
+	return 0
+}
+
+func (x *GetLinkStatsResponse) GetSeries() []*StatsPoint {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetTopReferrers() []*StatsCount {
+	if x != nil {
+		return x.TopReferrers
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetTopUserAgents() []*StatsCount {
+	if x != nil {
+		return x.TopUserAgents
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetTopCountries() []*StatsCount {
+	if x != nil {
+		return x.TopCountries
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetVariants() []*StatsCount {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+type GetQRCodeRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	// формат изображения: png (по умолчанию) или svg.
+	Format string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	// ширина и высота изображения в пикселях, 0 - по умолчанию.
+	Size int32 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	// уровень коррекции ошибок: L, M (по умолчанию), Q или H.
+	Level string `protobuf:"bytes,4,opt,name=level,proto3" json:"level,omitempty"`
+	// ширина рамки в модулях, не задана - по умолчанию.
+	Margin        *int32 `protobuf:"varint,5,opt,name=margin,proto3,oneof" json:"margin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQRCodeRequest) Reset() {
+	*x = GetQRCodeRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQRCodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQRCodeRequest) ProtoMessage() {}
+
+func (x *GetQRCodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQRCodeRequest.ProtoReflect.Descriptor instead.
+func (*GetQRCodeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GetQRCodeRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *GetQRCodeRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *GetQRCodeRequest) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *GetQRCodeRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *GetQRCodeRequest) GetMargin() int32 {
+	if x != nil && x.Margin != nil {
+		return *x.Margin
+	}
+	return 0
+}
+
+type GetQRCodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Image         []byte                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQRCodeResponse) Reset() {
+	*x = GetQRCodeResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQRCodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQRCodeResponse) ProtoMessage() {}
+
+func (x *GetQRCodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQRCodeResponse.ProtoReflect.Descriptor instead.
+func (*GetQRCodeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetQRCodeResponse) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *GetQRCodeResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+type UpdateShortURLRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	// новые значения атрибутов, не переданные поля не меняются.
+	OriginalURL *string `protobuf:"bytes,2,opt,name=originalURL,proto3,oneof" json:"originalURL,omitempty"`
+	// срок действия ссылки в unix-секундах, 0 - снять ограничение.
+	ExpiresAt *int64 `protobuf:"varint,3,opt,name=expiresAt,proto3,oneof" json:"expiresAt,omitempty"`
+	MaxClicks *int64 `protobuf:"varint,4,opt,name=maxClicks,proto3,oneof" json:"maxClicks,omitempty"`
+	// пароль для перехода по ссылке, пустой - снять пароль.
+	Password         *string    `protobuf:"bytes,5,opt,name=password,proto3,oneof" json:"password,omitempty"`
+	Interstitial     *bool      `protobuf:"varint,6,opt,name=interstitial,proto3,oneof" json:"interstitial,omitempty"`
+	FallbackURL      *string    `protobuf:"bytes,7,opt,name=fallbackURL,proto3,oneof" json:"fallbackURL,omitempty"`
+	QueryPassthrough *string    `protobuf:"bytes,8,opt,name=queryPassthrough,proto3,oneof" json:"queryPassthrough,omitempty"`
+	Utm              *UTMParams `protobuf:"bytes,9,opt,name=utm,proto3" json:"utm,omitempty"`
+	RedirectStatus   *int32     `protobuf:"varint,10,opt,name=redirectStatus,proto3,oneof" json:"redirectStatus,omitempty"`
+	// правила маршрутизации и варианты заменяются целиком, пустой список удаляет их.
+	Routing  *RoutingRules `protobuf:"bytes,11,opt,name=routing,proto3" json:"routing,omitempty"`
+	Variants *Variants     `protobuf:"bytes,12,opt,name=variants,proto3" json:"variants,omitempty"`
+	// теги заменяются целиком, пустой список удаляет их. Теги и папка не входят в версии ссылки.
+	Tags          *Tags   `protobuf:"bytes,13,opt,name=tags,proto3" json:"tags,omitempty"`
+	Folder        *string `protobuf:"bytes,14,opt,name=folder,proto3,oneof" json:"folder,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateShortURLRequest) Reset() {
+	*x = UpdateShortURLRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateShortURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateShortURLRequest) ProtoMessage() {}
+
+func (x *UpdateShortURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateShortURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateShortURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *UpdateShortURLRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *UpdateShortURLRequest) GetOriginalURL() string {
+	if x != nil && x.OriginalURL != nil {
+		return *x.OriginalURL
+	}
+	return ""
+}
+
+func (x *UpdateShortURLRequest) GetExpiresAt() int64 {
+	if x != nil && x.ExpiresAt != nil {
+		return *x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *UpdateShortURLRequest) GetMaxClicks() int64 {
+	if x != nil && x.MaxClicks != nil {
+		return *x.MaxClicks
+	}
+	return 0
+}
+
+func (x *UpdateShortURLRequest) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *UpdateShortURLRequest) GetInterstitial() bool {
+	if x != nil && x.Interstitial != nil {
+		return *x.Interstitial
+	}
+	return false
+}
+
+func (x *UpdateShortURLRequest) GetFallbackURL() string {
+	if x != nil && x.FallbackURL != nil {
+		return *x.FallbackURL
+	}
+	return ""
+}
+
+func (x *UpdateShortURLRequest) GetQueryPassthrough() string {
+	if x != nil && x.QueryPassthrough != nil {
+		return *x.QueryPassthrough
+	}
+	return ""
+}
+
+func (x *UpdateShortURLRequest) GetUtm() *UTMParams {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
+func (x *UpdateShortURLRequest) GetRedirectStatus() int32 {
+	if x != nil && x.RedirectStatus != nil {
+		return *x.RedirectStatus
+	}
+	return 0
+}
+
+func (x *UpdateShortURLRequest) GetRouting() *RoutingRules {
+	if x != nil {
+		return x.Routing
+	}
+	return nil
+}
+
+func (x *UpdateShortURLRequest) GetVariants() *Variants {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *UpdateShortURLRequest) GetTags() *Tags {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *UpdateShortURLRequest) GetFolder() string {
+	if x != nil && x.Folder != nil {
+		return *x.Folder
+	}
+	return ""
+}
+
+type Tags struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tags) Reset() {
+	*x = Tags{}
+	mi := &file_proto_shortener_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tags) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tags) ProtoMessage() {}
+
+func (x *Tags) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tags.ProtoReflect.Descriptor instead.
+func (*Tags) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Tags) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type RoutingRules struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*RoutingRule         `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingRules) Reset() {
+	*x = RoutingRules{}
+	mi := &file_proto_shortener_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingRules) ProtoMessage() {}
+
+func (x *RoutingRules) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingRules.ProtoReflect.Descriptor instead.
+func (*RoutingRules) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RoutingRules) GetRules() []*RoutingRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type Variants struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variants      []*Variant             `protobuf:"bytes,1,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variants) Reset() {
+	*x = Variants{}
+	mi := &file_proto_shortener_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variants) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variants) ProtoMessage() {}
+
+func (x *Variants) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variants.ProtoReflect.Descriptor instead.
+func (*Variants) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *Variants) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+// LinkVersion - версия ссылки: адрес назначения и атрибуты, действующие с момента createdAt.
+type LinkVersion struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Version     int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	OriginalURL string                 `protobuf:"bytes,2,opt,name=originalURL,proto3" json:"originalURL,omitempty"`
+	CreatedAt   int64                  `protobuf:"varint,3,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	ExpiresAt   int64                  `protobuf:"varint,4,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	MaxClicks   int64                  `protobuf:"varint,5,opt,name=maxClicks,proto3" json:"maxClicks,omitempty"`
+	// у ссылки есть пароль.
+	Protected        bool           `protobuf:"varint,6,opt,name=protected,proto3" json:"protected,omitempty"`
+	Interstitial     bool           `protobuf:"varint,7,opt,name=interstitial,proto3" json:"interstitial,omitempty"`
+	FallbackURL      string         `protobuf:"bytes,8,opt,name=fallbackURL,proto3" json:"fallbackURL,omitempty"`
+	QueryPassthrough string         `protobuf:"bytes,9,opt,name=queryPassthrough,proto3" json:"queryPassthrough,omitempty"`
+	Utm              *UTMParams     `protobuf:"bytes,10,opt,name=utm,proto3" json:"utm,omitempty"`
+	RedirectStatus   int32          `protobuf:"varint,11,opt,name=redirectStatus,proto3" json:"redirectStatus,omitempty"`
+	Routing          []*RoutingRule `protobuf:"bytes,12,rep,name=routing,proto3" json:"routing,omitempty"`
+	Variants         []*Variant     `protobuf:"bytes,13,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LinkVersion) Reset() {
+	*x = LinkVersion{}
+	mi := &file_proto_shortener_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkVersion) ProtoMessage() {}
+
+func (x *LinkVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkVersion.ProtoReflect.Descriptor instead.
+func (*LinkVersion) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *LinkVersion) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *LinkVersion) GetOriginalURL() string {
+	if x != nil {
+		return x.OriginalURL
+	}
+	return ""
+}
+
+func (x *LinkVersion) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *LinkVersion) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *LinkVersion) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *LinkVersion) GetProtected() bool {
+	if x != nil {
+		return x.Protected
+	}
+	return false
+}
+
+func (x *LinkVersion) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *LinkVersion) GetFallbackURL() string {
+	if x != nil {
+		return x.FallbackURL
+	}
+	return ""
+}
+
+func (x *LinkVersion) GetQueryPassthrough() string {
+	if x != nil {
+		return x.QueryPassthrough
+	}
+	return ""
+}
+
+func (x *LinkVersion) GetUtm() *UTMParams {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
+func (x *LinkVersion) GetRedirectStatus() int32 {
+	if x != nil {
+		return x.RedirectStatus
+	}
+	return 0
 }
 
-func (x *OriginalURLWithID) GetOriginalURL() string {
+func (x *LinkVersion) GetRouting() []*RoutingRule {
 	if x != nil {
-		return x.OriginalURL
+		return x.Routing
 	}
-	return ""
+	return nil
 }
 
-func (x *OriginalURLWithID) GetCorrelationID() string {
+func (x *LinkVersion) GetVariants() []*Variant {
 	if x != nil {
-		return x.CorrelationID
+		return x.Variants
 	}
-	return ""
+	return nil
 }
 
-type GetShortURLsRequest struct {
+type UpdateShortURLResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalURLs  []*OriginalURLWithID   `protobuf:"bytes,1,rep,name=originalURLs,proto3" json:"originalURLs,omitempty"`
+	Version       *LinkVersion           `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetShortURLsRequest) Reset() {
-	*x = GetShortURLsRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[5]
+func (x *UpdateShortURLResponse) Reset() {
+	*x = UpdateShortURLResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetShortURLsRequest) String() string {
+func (x *UpdateShortURLResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetShortURLsRequest) ProtoMessage() {}
+func (*UpdateShortURLResponse) ProtoMessage() {}
 
-func (x *GetShortURLsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[5]
+func (x *UpdateShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -281,41 +2380,40 @@ func (x *GetShortURLsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetShortURLsRequest.ProtoReflect.Descriptor instead.
-func (*GetShortURLsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use UpdateShortURLResponse.ProtoReflect.Descriptor instead.
+func (*UpdateShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{34}
 }
 
-func (x *GetShortURLsRequest) GetOriginalURLs() []*OriginalURLWithID {
+func (x *UpdateShortURLResponse) GetVersion() *LinkVersion {
 	if x != nil {
-		return x.OriginalURLs
+		return x.Version
 	}
 	return nil
 }
 
-type ShortURLWithID struct {
+type GetLinkVersionsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
-	CorrelationID string                 `protobuf:"bytes,2,opt,name=correlationID,proto3" json:"correlationID,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ShortURLWithID) Reset() {
-	*x = ShortURLWithID{}
-	mi := &file_proto_shortener_proto_msgTypes[6]
+func (x *GetLinkVersionsRequest) Reset() {
+	*x = GetLinkVersionsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ShortURLWithID) String() string {
+func (x *GetLinkVersionsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ShortURLWithID) ProtoMessage() {}
+func (*GetLinkVersionsRequest) ProtoMessage() {}
 
-func (x *ShortURLWithID) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[6]
+func (x *GetLinkVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -326,47 +2424,40 @@ func (x *ShortURLWithID) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ShortURLWithID.ProtoReflect.Descriptor instead.
-func (*ShortURLWithID) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use GetLinkVersionsRequest.ProtoReflect.Descriptor instead.
+func (*GetLinkVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{35}
 }
 
-func (x *ShortURLWithID) GetShortURL() string {
+func (x *GetLinkVersionsRequest) GetShortURL() string {
 	if x != nil {
 		return x.ShortURL
 	}
 	return ""
 }
 
-func (x *ShortURLWithID) GetCorrelationID() string {
-	if x != nil {
-		return x.CorrelationID
-	}
-	return ""
-}
-
-type GetShortURLsResponse struct {
+type GetLinkVersionsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortURLs     []*ShortURLWithID      `protobuf:"bytes,1,rep,name=shortURLs,proto3" json:"shortURLs,omitempty"`
+	Versions      []*LinkVersion         `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetShortURLsResponse) Reset() {
-	*x = GetShortURLsResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[7]
+func (x *GetLinkVersionsResponse) Reset() {
+	*x = GetLinkVersionsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetShortURLsResponse) String() string {
+func (x *GetLinkVersionsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetShortURLsResponse) ProtoMessage() {}
+func (*GetLinkVersionsResponse) ProtoMessage() {}
 
-func (x *GetShortURLsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[7]
+func (x *GetLinkVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -377,39 +2468,41 @@ func (x *GetShortURLsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetShortURLsResponse.ProtoReflect.Descriptor instead.
-func (*GetShortURLsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use GetLinkVersionsResponse.ProtoReflect.Descriptor instead.
+func (*GetLinkVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *GetShortURLsResponse) GetShortURLs() []*ShortURLWithID {
+func (x *GetLinkVersionsResponse) GetVersions() []*LinkVersion {
 	if x != nil {
-		return x.ShortURLs
+		return x.Versions
 	}
 	return nil
 }
 
-type GetUserURLsRequest struct {
+type RollbackShortURLRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
+	Version       int32                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetUserURLsRequest) Reset() {
-	*x = GetUserURLsRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[8]
+func (x *RollbackShortURLRequest) Reset() {
+	*x = RollbackShortURLRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetUserURLsRequest) String() string {
+func (x *RollbackShortURLRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetUserURLsRequest) ProtoMessage() {}
+func (*RollbackShortURLRequest) ProtoMessage() {}
 
-func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[8]
+func (x *RollbackShortURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -420,34 +2513,47 @@ func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetUserURLsRequest.ProtoReflect.Descriptor instead.
-func (*GetUserURLsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use RollbackShortURLRequest.ProtoReflect.Descriptor instead.
+func (*RollbackShortURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{37}
 }
 
-type ShortOriginalURL struct {
+func (x *RollbackShortURLRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *RollbackShortURLRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type RollbackShortURLResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortURL      string                 `protobuf:"bytes,1,opt,name=ShortURL,proto3" json:"ShortURL,omitempty"`
-	OriginalURL   string                 `protobuf:"bytes,2,opt,name=OriginalURL,proto3" json:"OriginalURL,omitempty"`
+	Version       *LinkVersion           `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ShortOriginalURL) Reset() {
-	*x = ShortOriginalURL{}
-	mi := &file_proto_shortener_proto_msgTypes[9]
+func (x *RollbackShortURLResponse) Reset() {
+	*x = RollbackShortURLResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ShortOriginalURL) String() string {
+func (x *RollbackShortURLResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ShortOriginalURL) ProtoMessage() {}
+func (*RollbackShortURLResponse) ProtoMessage() {}
 
-func (x *ShortOriginalURL) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[9]
+func (x *RollbackShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -458,47 +2564,39 @@ func (x *ShortOriginalURL) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ShortOriginalURL.ProtoReflect.Descriptor instead.
-func (*ShortOriginalURL) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{9}
-}
-
-func (x *ShortOriginalURL) GetShortURL() string {
-	if x != nil {
-		return x.ShortURL
-	}
-	return ""
+// Deprecated: Use RollbackShortURLResponse.ProtoReflect.Descriptor instead.
+func (*RollbackShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{38}
 }
 
-func (x *ShortOriginalURL) GetOriginalURL() string {
+func (x *RollbackShortURLResponse) GetVersion() *LinkVersion {
 	if x != nil {
-		return x.OriginalURL
+		return x.Version
 	}
-	return ""
+	return nil
 }
 
-type GetUserURLsResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	ShortOriginalURLs []*ShortOriginalURL    `protobuf:"bytes,1,rep,name=shortOriginalURLs,proto3" json:"shortOriginalURLs,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type GetUserTagsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetUserURLsResponse) Reset() {
-	*x = GetUserURLsResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[10]
+func (x *GetUserTagsRequest) Reset() {
+	*x = GetUserTagsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetUserURLsResponse) String() string {
+func (x *GetUserTagsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetUserURLsResponse) ProtoMessage() {}
+func (*GetUserTagsRequest) ProtoMessage() {}
 
-func (x *GetUserURLsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[10]
+func (x *GetUserTagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -509,40 +2607,34 @@ func (x *GetUserURLsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetUserURLsResponse.ProtoReflect.Descriptor instead.
-func (*GetUserURLsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{10}
-}
-
-func (x *GetUserURLsResponse) GetShortOriginalURLs() []*ShortOriginalURL {
-	if x != nil {
-		return x.ShortOriginalURLs
-	}
-	return nil
+// Deprecated: Use GetUserTagsRequest.ProtoReflect.Descriptor instead.
+func (*GetUserTagsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{39}
 }
 
-type MarkRecordsForDeletionRequest struct {
+type TagCount struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortURLs     []string               `protobuf:"bytes,1,rep,name=shortURLs,proto3" json:"shortURLs,omitempty"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MarkRecordsForDeletionRequest) Reset() {
-	*x = MarkRecordsForDeletionRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[11]
+func (x *TagCount) Reset() {
+	*x = TagCount{}
+	mi := &file_proto_shortener_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MarkRecordsForDeletionRequest) String() string {
+func (x *TagCount) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MarkRecordsForDeletionRequest) ProtoMessage() {}
+func (*TagCount) ProtoMessage() {}
 
-func (x *MarkRecordsForDeletionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[11]
+func (x *TagCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -553,39 +2645,48 @@ func (x *MarkRecordsForDeletionRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MarkRecordsForDeletionRequest.ProtoReflect.Descriptor instead.
-func (*MarkRecordsForDeletionRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use TagCount.ProtoReflect.Descriptor instead.
+func (*TagCount) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *MarkRecordsForDeletionRequest) GetShortURLs() []string {
+func (x *TagCount) GetTag() string {
 	if x != nil {
-		return x.ShortURLs
+		return x.Tag
 	}
-	return nil
+	return ""
 }
 
-type MarkRecordsForDeletionResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *TagCount) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type GetUserTagsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// теги ссылок пользователя, от самых частых.
+	Tags          []*TagCount `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MarkRecordsForDeletionResponse) Reset() {
-	*x = MarkRecordsForDeletionResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[12]
+func (x *GetUserTagsResponse) Reset() {
+	*x = GetUserTagsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MarkRecordsForDeletionResponse) String() string {
+func (x *GetUserTagsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MarkRecordsForDeletionResponse) ProtoMessage() {}
+func (*GetUserTagsResponse) ProtoMessage() {}
 
-func (x *MarkRecordsForDeletionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[12]
+func (x *GetUserTagsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -596,32 +2697,41 @@ func (x *MarkRecordsForDeletionResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MarkRecordsForDeletionResponse.ProtoReflect.Descriptor instead.
-func (*MarkRecordsForDeletionResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use GetUserTagsResponse.ProtoReflect.Descriptor instead.
+func (*GetUserTagsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{41}
 }
 
-type PingRequest struct {
+func (x *GetUserTagsResponse) GetTags() []*TagCount {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type MergeTagsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          []string               `protobuf:"bytes,1,rep,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PingRequest) Reset() {
-	*x = PingRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[13]
+func (x *MergeTagsRequest) Reset() {
+	*x = MergeTagsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PingRequest) String() string {
+func (x *MergeTagsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PingRequest) ProtoMessage() {}
+func (*MergeTagsRequest) ProtoMessage() {}
 
-func (x *PingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[13]
+func (x *MergeTagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -632,32 +2742,48 @@ func (x *PingRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
-func (*PingRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use MergeTagsRequest.ProtoReflect.Descriptor instead.
+func (*MergeTagsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{42}
 }
 
-type PingResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *MergeTagsRequest) GetFrom() []string {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *MergeTagsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type MergeTagsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// число измененных ссылок.
+	Updated       int32 `protobuf:"varint,1,opt,name=updated,proto3" json:"updated,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PingResponse) Reset() {
-	*x = PingResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[14]
+func (x *MergeTagsResponse) Reset() {
+	*x = MergeTagsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PingResponse) String() string {
+func (x *MergeTagsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PingResponse) ProtoMessage() {}
+func (*MergeTagsResponse) ProtoMessage() {}
 
-func (x *PingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[14]
+func (x *MergeTagsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -668,32 +2794,40 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
-func (*PingResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use MergeTagsResponse.ProtoReflect.Descriptor instead.
+func (*MergeTagsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{43}
 }
 
-type GetURLsStatsRequest struct {
+func (x *MergeTagsResponse) GetUpdated() int32 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+type RefetchMetadataRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortURL      string                 `protobuf:"bytes,1,opt,name=shortURL,proto3" json:"shortURL,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetURLsStatsRequest) Reset() {
-	*x = GetURLsStatsRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[15]
+func (x *RefetchMetadataRequest) Reset() {
+	*x = RefetchMetadataRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetURLsStatsRequest) String() string {
+func (x *RefetchMetadataRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetURLsStatsRequest) ProtoMessage() {}
+func (*RefetchMetadataRequest) ProtoMessage() {}
 
-func (x *GetURLsStatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[15]
+func (x *RefetchMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -704,34 +2838,40 @@ func (x *GetURLsStatsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetURLsStatsRequest.ProtoReflect.Descriptor instead.
-func (*GetURLsStatsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use RefetchMetadataRequest.ProtoReflect.Descriptor instead.
+func (*RefetchMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{44}
 }
 
-type GetURLsStatsResponse struct {
+func (x *RefetchMetadataRequest) GetShortURL() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+type RefetchMetadataResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Urls          int64                  `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`
-	Users         int64                  `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`
+	Metadata      *LinkMetadata          `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetURLsStatsResponse) Reset() {
-	*x = GetURLsStatsResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[16]
+func (x *RefetchMetadataResponse) Reset() {
+	*x = RefetchMetadataResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetURLsStatsResponse) String() string {
+func (x *RefetchMetadataResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetURLsStatsResponse) ProtoMessage() {}
+func (*RefetchMetadataResponse) ProtoMessage() {}
 
-func (x *GetURLsStatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[16]
+func (x *RefetchMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -742,71 +2882,258 @@ func (x *GetURLsStatsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetURLsStatsResponse.ProtoReflect.Descriptor instead.
-func (*GetURLsStatsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{16}
-}
-
-func (x *GetURLsStatsResponse) GetUrls() int64 {
-	if x != nil {
-		return x.Urls
-	}
-	return 0
+// Deprecated: Use RefetchMetadataResponse.ProtoReflect.Descriptor instead.
+func (*RefetchMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *GetURLsStatsResponse) GetUsers() int64 {
+func (x *RefetchMetadataResponse) GetMetadata() *LinkMetadata {
 	if x != nil {
-		return x.Users
+		return x.Metadata
 	}
-	return 0
+	return nil
 }
 
 var File_proto_shortener_proto protoreflect.FileDescriptor
 
 const file_proto_shortener_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/shortener.proto\x12\tshortener\"6\n" +
+	"\x15proto/shortener.proto\x12\tshortener\"\x94\x04\n" +
 	"\x12GetShortURLRequest\x12 \n" +
-	"\voriginalURL\x18\x01 \x01(\tR\voriginalURL\"1\n" +
+	"\voriginalURL\x18\x01 \x01(\tR\voriginalURL\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x12\x1c\n" +
+	"\texpiresAt\x18\x03 \x01(\x03R\texpiresAt\x12\x1e\n" +
+	"\n" +
+	"ttlSeconds\x18\x04 \x01(\x03R\n" +
+	"ttlSeconds\x12\x1c\n" +
+	"\tmaxClicks\x18\x05 \x01(\x03R\tmaxClicks\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\x12\"\n" +
+	"\finterstitial\x18\a \x01(\bR\finterstitial\x12 \n" +
+	"\vfallbackURL\x18\b \x01(\tR\vfallbackURL\x12*\n" +
+	"\x10queryPassthrough\x18\t \x01(\tR\x10queryPassthrough\x12&\n" +
+	"\x03utm\x18\n" +
+	" \x01(\v2\x14.shortener.UTMParamsR\x03utm\x12&\n" +
+	"\x0eredirectStatus\x18\v \x01(\x05R\x0eredirectStatus\x120\n" +
+	"\arouting\x18\f \x03(\v2\x16.shortener.RoutingRuleR\arouting\x12.\n" +
+	"\bvariants\x18\r \x03(\v2\x12.shortener.VariantR\bvariants\x12\x12\n" +
+	"\x04tags\x18\x0e \x03(\tR\x04tags\x12\x16\n" +
+	"\x06folder\x18\x0f \x01(\tR\x06folder\"G\n" +
+	"\aVariant\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x16\n" +
+	"\x06weight\x18\x03 \x01(\x05R\x06weight\"q\n" +
+	"\vRoutingRule\x12\x1a\n" +
+	"\bplatform\x18\x01 \x01(\tR\bplatform\x12\x1a\n" +
+	"\blanguage\x18\x02 \x01(\tR\blanguage\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\"\x85\x01\n" +
+	"\tUTMParams\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x16\n" +
+	"\x06medium\x18\x02 \x01(\tR\x06medium\x12\x1a\n" +
+	"\bcampaign\x18\x03 \x01(\tR\bcampaign\x12\x12\n" +
+	"\x04term\x18\x04 \x01(\tR\x04term\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\tR\acontent\"1\n" +
 	"\x13GetShortURLResponse\x12\x1a\n" +
-	"\bshortURL\x18\x01 \x01(\tR\bshortURL\"3\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\"O\n" +
 	"\x15GetOriginalURLRequest\x12\x1a\n" +
-	"\bshortURL\x18\x01 \x01(\tR\bshortURL\":\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"|\n" +
 	"\x16GetOriginalURLResponse\x12 \n" +
-	"\voriginalURL\x18\x01 \x01(\tR\voriginalURL\"[\n" +
+	"\voriginalURL\x18\x01 \x01(\tR\voriginalURL\x12&\n" +
+	"\x0eredirectStatus\x18\x02 \x01(\x05R\x0eredirectStatus\x12\x18\n" +
+	"\avariant\x18\x03 \x01(\tR\avariant\"q\n" +
 	"\x11OriginalURLWithID\x12 \n" +
 	"\voriginalURL\x18\x01 \x01(\tR\voriginalURL\x12$\n" +
-	"\rcorrelationID\x18\x02 \x01(\tR\rcorrelationID\"W\n" +
+	"\rcorrelationID\x18\x02 \x01(\tR\rcorrelationID\x12\x14\n" +
+	"\x05alias\x18\x03 \x01(\tR\x05alias\"W\n" +
 	"\x13GetShortURLsRequest\x12@\n" +
 	"\foriginalURLs\x18\x01 \x03(\v2\x1c.shortener.OriginalURLWithIDR\foriginalURLs\"R\n" +
 	"\x0eShortURLWithID\x12\x1a\n" +
 	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12$\n" +
 	"\rcorrelationID\x18\x02 \x01(\tR\rcorrelationID\"O\n" +
 	"\x14GetShortURLsResponse\x127\n" +
-	"\tshortURLs\x18\x01 \x03(\v2\x19.shortener.ShortURLWithIDR\tshortURLs\"\x14\n" +
-	"\x12GetUserURLsRequest\"P\n" +
+	"\tshortURLs\x18\x01 \x03(\v2\x19.shortener.ShortURLWithIDR\tshortURLs\"\xe2\x01\n" +
+	"\x12GetUserURLsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05order\x18\x04 \x01(\tR\x05order\x12\x18\n" +
+	"\adeleted\x18\x05 \x01(\tR\adeleted\x12\x18\n" +
+	"\aexpired\x18\x06 \x01(\tR\aexpired\x12\x16\n" +
+	"\x06search\x18\a \x01(\tR\x06search\x12\x10\n" +
+	"\x03tag\x18\b \x01(\tR\x03tag\x12\x16\n" +
+	"\x06folder\x18\t \x01(\tR\x06folder\"\x95\x03\n" +
 	"\x10ShortOriginalURL\x12\x1a\n" +
 	"\bShortURL\x18\x01 \x01(\tR\bShortURL\x12 \n" +
-	"\vOriginalURL\x18\x02 \x01(\tR\vOriginalURL\"`\n" +
+	"\vOriginalURL\x18\x02 \x01(\tR\vOriginalURL\x12\x1e\n" +
+	"\n" +
+	"lastStatus\x18\x03 \x01(\x05R\n" +
+	"lastStatus\x12$\n" +
+	"\rlastCheckedAt\x18\x04 \x01(\x03R\rlastCheckedAt\x12\x1a\n" +
+	"\bfailures\x18\x05 \x01(\x05R\bfailures\x12\x12\n" +
+	"\x04dead\x18\x06 \x01(\bR\x04dead\x12\x1c\n" +
+	"\tcreatedAt\x18\a \x01(\x03R\tcreatedAt\x12\x1c\n" +
+	"\texpiresAt\x18\b \x01(\x03R\texpiresAt\x12\x16\n" +
+	"\x06clicks\x18\t \x01(\x03R\x06clicks\x12\x18\n" +
+	"\adeleted\x18\n" +
+	" \x01(\bR\adeleted\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x12\x16\n" +
+	"\x06folder\x18\f \x01(\tR\x06folder\x123\n" +
+	"\bmetadata\x18\r \x01(\v2\x17.shortener.LinkMetadataR\bmetadata\"\x80\x02\n" +
+	"\fLinkMetadata\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x18\n" +
+	"\aogTitle\x18\x02 \x01(\tR\aogTitle\x12$\n" +
+	"\rogDescription\x18\x03 \x01(\tR\rogDescription\x12\x18\n" +
+	"\aogImage\x18\x04 \x01(\tR\aogImage\x12\x1e\n" +
+	"\n" +
+	"ogSiteName\x18\x05 \x01(\tR\n" +
+	"ogSiteName\x12\x16\n" +
+	"\x06ogType\x18\x06 \x01(\tR\x06ogType\x12\x14\n" +
+	"\x05ogURL\x18\a \x01(\tR\x05ogURL\x12\x1c\n" +
+	"\tfetchedAt\x18\b \x01(\x03R\tfetchedAt\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\x80\x01\n" +
 	"\x13GetUserURLsResponse\x12I\n" +
-	"\x11shortOriginalURLs\x18\x01 \x03(\v2\x1b.shortener.ShortOriginalURLR\x11shortOriginalURLs\"=\n" +
+	"\x11shortOriginalURLs\x18\x01 \x03(\v2\x1b.shortener.ShortOriginalURLR\x11shortOriginalURLs\x12\x1e\n" +
+	"\n" +
+	"nextCursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"=\n" +
 	"\x1dMarkRecordsForDeletionRequest\x12\x1c\n" +
 	"\tshortURLs\x18\x01 \x03(\tR\tshortURLs\" \n" +
-	"\x1eMarkRecordsForDeletionResponse\"\r\n" +
+	"\x1eMarkRecordsForDeletionResponse\"4\n" +
+	"\x16RestoreShortURLRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\"\x19\n" +
+	"\x17RestoreShortURLResponse\"\r\n" +
 	"\vPingRequest\"\x0e\n" +
 	"\fPingResponse\"\x15\n" +
-	"\x13GetURLsStatsRequest\"@\n" +
+	"\x13GetURLsStatsRequest\"\\\n" +
 	"\x14GetURLsStatsResponse\x12\x12\n" +
 	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
-	"\x05users\x18\x02 \x01(\x03R\x05users2\xc8\x04\n" +
+	"\x05users\x18\x02 \x01(\x03R\x05users\x12\x1a\n" +
+	"\bvisitors\x18\x03 \x01(\x03R\bvisitors\"\x7f\n" +
+	"\x13GetLinkStatsRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\x03R\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\x03R\x02to\x12\x16\n" +
+	"\x06bucket\x18\x04 \x01(\tR\x06bucket\x12\x10\n" +
+	"\x03top\x18\x05 \x01(\x05R\x03top\"8\n" +
+	"\n" +
+	"StatsPoint\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\x03R\x04time\x12\x16\n" +
+	"\x06clicks\x18\x02 \x01(\x03R\x06clicks\"8\n" +
+	"\n" +
+	"StatsCount\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\xf5\x02\n" +
+	"\x14GetLinkStatsResponse\x12 \n" +
+	"\vtotalClicks\x18\x01 \x01(\x03R\vtotalClicks\x12&\n" +
+	"\x0euniqueVisitors\x18\x02 \x01(\x03R\x0euniqueVisitors\x12-\n" +
+	"\x06series\x18\x03 \x03(\v2\x15.shortener.StatsPointR\x06series\x129\n" +
+	"\ftopReferrers\x18\x04 \x03(\v2\x15.shortener.StatsCountR\ftopReferrers\x12;\n" +
+	"\rtopUserAgents\x18\x05 \x03(\v2\x15.shortener.StatsCountR\rtopUserAgents\x129\n" +
+	"\ftopCountries\x18\x06 \x03(\v2\x15.shortener.StatsCountR\ftopCountries\x121\n" +
+	"\bvariants\x18\a \x03(\v2\x15.shortener.StatsCountR\bvariants\"\x98\x01\n" +
+	"\x10GetQRCodeRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x05R\x04size\x12\x14\n" +
+	"\x05level\x18\x04 \x01(\tR\x05level\x12\x1b\n" +
+	"\x06margin\x18\x05 \x01(\x05H\x00R\x06margin\x88\x01\x01B\t\n" +
+	"\a_margin\"K\n" +
+	"\x11GetQRCodeResponse\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\fR\x05image\x12 \n" +
+	"\vcontentType\x18\x02 \x01(\tR\vcontentType\"\xca\x05\n" +
+	"\x15UpdateShortURLRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12%\n" +
+	"\voriginalURL\x18\x02 \x01(\tH\x00R\voriginalURL\x88\x01\x01\x12!\n" +
+	"\texpiresAt\x18\x03 \x01(\x03H\x01R\texpiresAt\x88\x01\x01\x12!\n" +
+	"\tmaxClicks\x18\x04 \x01(\x03H\x02R\tmaxClicks\x88\x01\x01\x12\x1f\n" +
+	"\bpassword\x18\x05 \x01(\tH\x03R\bpassword\x88\x01\x01\x12'\n" +
+	"\finterstitial\x18\x06 \x01(\bH\x04R\finterstitial\x88\x01\x01\x12%\n" +
+	"\vfallbackURL\x18\a \x01(\tH\x05R\vfallbackURL\x88\x01\x01\x12/\n" +
+	"\x10queryPassthrough\x18\b \x01(\tH\x06R\x10queryPassthrough\x88\x01\x01\x12&\n" +
+	"\x03utm\x18\t \x01(\v2\x14.shortener.UTMParamsR\x03utm\x12+\n" +
+	"\x0eredirectStatus\x18\n" +
+	" \x01(\x05H\aR\x0eredirectStatus\x88\x01\x01\x121\n" +
+	"\arouting\x18\v \x01(\v2\x17.shortener.RoutingRulesR\arouting\x12/\n" +
+	"\bvariants\x18\f \x01(\v2\x13.shortener.VariantsR\bvariants\x12#\n" +
+	"\x04tags\x18\r \x01(\v2\x0f.shortener.TagsR\x04tags\x12\x1b\n" +
+	"\x06folder\x18\x0e \x01(\tH\bR\x06folder\x88\x01\x01B\x0e\n" +
+	"\f_originalURLB\f\n" +
+	"\n" +
+	"_expiresAtB\f\n" +
+	"\n" +
+	"_maxClicksB\v\n" +
+	"\t_passwordB\x0f\n" +
+	"\r_interstitialB\x0e\n" +
+	"\f_fallbackURLB\x13\n" +
+	"\x11_queryPassthroughB\x11\n" +
+	"\x0f_redirectStatusB\t\n" +
+	"\a_folder\"\x1a\n" +
+	"\x04Tags\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"<\n" +
+	"\fRoutingRules\x12,\n" +
+	"\x05rules\x18\x01 \x03(\v2\x16.shortener.RoutingRuleR\x05rules\":\n" +
+	"\bVariants\x12.\n" +
+	"\bvariants\x18\x01 \x03(\v2\x12.shortener.VariantR\bvariants\"\xe5\x03\n" +
+	"\vLinkVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12 \n" +
+	"\voriginalURL\x18\x02 \x01(\tR\voriginalURL\x12\x1c\n" +
+	"\tcreatedAt\x18\x03 \x01(\x03R\tcreatedAt\x12\x1c\n" +
+	"\texpiresAt\x18\x04 \x01(\x03R\texpiresAt\x12\x1c\n" +
+	"\tmaxClicks\x18\x05 \x01(\x03R\tmaxClicks\x12\x1c\n" +
+	"\tprotected\x18\x06 \x01(\bR\tprotected\x12\"\n" +
+	"\finterstitial\x18\a \x01(\bR\finterstitial\x12 \n" +
+	"\vfallbackURL\x18\b \x01(\tR\vfallbackURL\x12*\n" +
+	"\x10queryPassthrough\x18\t \x01(\tR\x10queryPassthrough\x12&\n" +
+	"\x03utm\x18\n" +
+	" \x01(\v2\x14.shortener.UTMParamsR\x03utm\x12&\n" +
+	"\x0eredirectStatus\x18\v \x01(\x05R\x0eredirectStatus\x120\n" +
+	"\arouting\x18\f \x03(\v2\x16.shortener.RoutingRuleR\arouting\x12.\n" +
+	"\bvariants\x18\r \x03(\v2\x12.shortener.VariantR\bvariants\"J\n" +
+	"\x16UpdateShortURLResponse\x120\n" +
+	"\aversion\x18\x01 \x01(\v2\x16.shortener.LinkVersionR\aversion\"4\n" +
+	"\x16GetLinkVersionsRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\"M\n" +
+	"\x17GetLinkVersionsResponse\x122\n" +
+	"\bversions\x18\x01 \x03(\v2\x16.shortener.LinkVersionR\bversions\"O\n" +
+	"\x17RollbackShortURLRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\"L\n" +
+	"\x18RollbackShortURLResponse\x120\n" +
+	"\aversion\x18\x01 \x01(\v2\x16.shortener.LinkVersionR\aversion\"\x14\n" +
+	"\x12GetUserTagsRequest\"2\n" +
+	"\bTagCount\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\">\n" +
+	"\x13GetUserTagsResponse\x12'\n" +
+	"\x04tags\x18\x01 \x03(\v2\x13.shortener.TagCountR\x04tags\"6\n" +
+	"\x10MergeTagsRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x03(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\"-\n" +
+	"\x11MergeTagsResponse\x12\x18\n" +
+	"\aupdated\x18\x01 \x01(\x05R\aupdated\"4\n" +
+	"\x16RefetchMetadataRequest\x12\x1a\n" +
+	"\bshortURL\x18\x01 \x01(\tR\bshortURL\"N\n" +
+	"\x17RefetchMetadataResponse\x123\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x17.shortener.LinkMetadataR\bmetadata2\xb9\n" +
+	"\n" +
 	"\tShortener\x12L\n" +
 	"\vGetShortURL\x12\x1d.shortener.GetShortURLRequest\x1a\x1e.shortener.GetShortURLResponse\x12U\n" +
 	"\x0eGetOriginalURL\x12 .shortener.GetOriginalURLRequest\x1a!.shortener.GetOriginalURLResponse\x12O\n" +
 	"\fGetShortURLs\x12\x1e.shortener.GetShortURLsRequest\x1a\x1f.shortener.GetShortURLsResponse\x12L\n" +
 	"\vGetUserURLs\x12\x1d.shortener.GetUserURLsRequest\x1a\x1e.shortener.GetUserURLsResponse\x12m\n" +
-	"\x16MarkRecordsForDeletion\x12(.shortener.MarkRecordsForDeletionRequest\x1a).shortener.MarkRecordsForDeletionResponse\x127\n" +
+	"\x16MarkRecordsForDeletion\x12(.shortener.MarkRecordsForDeletionRequest\x1a).shortener.MarkRecordsForDeletionResponse\x12X\n" +
+	"\x0fRestoreShortURL\x12!.shortener.RestoreShortURLRequest\x1a\".shortener.RestoreShortURLResponse\x127\n" +
 	"\x04Ping\x12\x16.shortener.PingRequest\x1a\x17.shortener.PingResponse\x12O\n" +
-	"\fGetURLsStats\x12\x1e.shortener.GetURLsStatsRequest\x1a\x1f.shortener.GetURLsStatsResponseB\x16Z\x14internal/app/grpcapib\x06proto3"
+	"\fGetURLsStats\x12\x1e.shortener.GetURLsStatsRequest\x1a\x1f.shortener.GetURLsStatsResponse\x12O\n" +
+	"\fGetLinkStats\x12\x1e.shortener.GetLinkStatsRequest\x1a\x1f.shortener.GetLinkStatsResponse\x12F\n" +
+	"\tGetQRCode\x12\x1b.shortener.GetQRCodeRequest\x1a\x1c.shortener.GetQRCodeResponse\x12U\n" +
+	"\x0eUpdateShortURL\x12 .shortener.UpdateShortURLRequest\x1a!.shortener.UpdateShortURLResponse\x12X\n" +
+	"\x0fGetLinkVersions\x12!.shortener.GetLinkVersionsRequest\x1a\".shortener.GetLinkVersionsResponse\x12[\n" +
+	"\x10RollbackShortURL\x12\".shortener.RollbackShortURLRequest\x1a#.shortener.RollbackShortURLResponse\x12L\n" +
+	"\vGetUserTags\x12\x1d.shortener.GetUserTagsRequest\x1a\x1e.shortener.GetUserTagsResponse\x12F\n" +
+	"\tMergeTags\x12\x1b.shortener.MergeTagsRequest\x1a\x1c.shortener.MergeTagsResponse\x12X\n" +
+	"\x0fRefetchMetadata\x12!.shortener.RefetchMetadataRequest\x1a\".shortener.RefetchMetadataResponseB\x16Z\x14internal/app/grpcapib\x06proto3"
 
 var (
 	file_proto_shortener_proto_rawDescOnce sync.Once
@@ -820,49 +3147,119 @@ func file_proto_shortener_proto_rawDescGZIP() []byte {
 	return file_proto_shortener_proto_rawDescData
 }
 
-var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 46)
 var file_proto_shortener_proto_goTypes = []any{
 	(*GetShortURLRequest)(nil),             // 0: shortener.GetShortURLRequest
-	(*GetShortURLResponse)(nil),            // 1: shortener.GetShortURLResponse
-	(*GetOriginalURLRequest)(nil),          // 2: shortener.GetOriginalURLRequest
-	(*GetOriginalURLResponse)(nil),         // 3: shortener.GetOriginalURLResponse
-	(*OriginalURLWithID)(nil),              // 4: shortener.OriginalURLWithID
-	(*GetShortURLsRequest)(nil),            // 5: shortener.GetShortURLsRequest
-	(*ShortURLWithID)(nil),                 // 6: shortener.ShortURLWithID
-	(*GetShortURLsResponse)(nil),           // 7: shortener.GetShortURLsResponse
-	(*GetUserURLsRequest)(nil),             // 8: shortener.GetUserURLsRequest
-	(*ShortOriginalURL)(nil),               // 9: shortener.ShortOriginalURL
-	(*GetUserURLsResponse)(nil),            // 10: shortener.GetUserURLsResponse
-	(*MarkRecordsForDeletionRequest)(nil),  // 11: shortener.MarkRecordsForDeletionRequest
-	(*MarkRecordsForDeletionResponse)(nil), // 12: shortener.MarkRecordsForDeletionResponse
-	(*PingRequest)(nil),                    // 13: shortener.PingRequest
-	(*PingResponse)(nil),                   // 14: shortener.PingResponse
-	(*GetURLsStatsRequest)(nil),            // 15: shortener.GetURLsStatsRequest
-	(*GetURLsStatsResponse)(nil),           // 16: shortener.GetURLsStatsResponse
+	(*Variant)(nil),                        // 1: shortener.Variant
+	(*RoutingRule)(nil),                    // 2: shortener.RoutingRule
+	(*UTMParams)(nil),                      // 3: shortener.UTMParams
+	(*GetShortURLResponse)(nil),            // 4: shortener.GetShortURLResponse
+	(*GetOriginalURLRequest)(nil),          // 5: shortener.GetOriginalURLRequest
+	(*GetOriginalURLResponse)(nil),         // 6: shortener.GetOriginalURLResponse
+	(*OriginalURLWithID)(nil),              // 7: shortener.OriginalURLWithID
+	(*GetShortURLsRequest)(nil),            // 8: shortener.GetShortURLsRequest
+	(*ShortURLWithID)(nil),                 // 9: shortener.ShortURLWithID
+	(*GetShortURLsResponse)(nil),           // 10: shortener.GetShortURLsResponse
+	(*GetUserURLsRequest)(nil),             // 11: shortener.GetUserURLsRequest
+	(*ShortOriginalURL)(nil),               // 12: shortener.ShortOriginalURL
+	(*LinkMetadata)(nil),                   // 13: shortener.LinkMetadata
+	(*GetUserURLsResponse)(nil),            // 14: shortener.GetUserURLsResponse
+	(*MarkRecordsForDeletionRequest)(nil),  // 15: shortener.MarkRecordsForDeletionRequest
+	(*MarkRecordsForDeletionResponse)(nil), // 16: shortener.MarkRecordsForDeletionResponse
+	(*RestoreShortURLRequest)(nil),         // 17: shortener.RestoreShortURLRequest
+	(*RestoreShortURLResponse)(nil),        // 18: shortener.RestoreShortURLResponse
+	(*PingRequest)(nil),                    // 19: shortener.PingRequest
+	(*PingResponse)(nil),                   // 20: shortener.PingResponse
+	(*GetURLsStatsRequest)(nil),            // 21: shortener.GetURLsStatsRequest
+	(*GetURLsStatsResponse)(nil),           // 22: shortener.GetURLsStatsResponse
+	(*GetLinkStatsRequest)(nil),            // 23: shortener.GetLinkStatsRequest
+	(*StatsPoint)(nil),                     // 24: shortener.StatsPoint
+	(*StatsCount)(nil),                     // 25: shortener.StatsCount
+	(*GetLinkStatsResponse)(nil),           // 26: shortener.GetLinkStatsResponse
+	(*GetQRCodeRequest)(nil),               // 27: shortener.GetQRCodeRequest
+	(*GetQRCodeResponse)(nil),              // 28: shortener.GetQRCodeResponse
+	(*UpdateShortURLRequest)(nil),          // 29: shortener.UpdateShortURLRequest
+	(*Tags)(nil),                           // 30: shortener.Tags
+	(*RoutingRules)(nil),                   // 31: shortener.RoutingRules
+	(*Variants)(nil),                       // 32: shortener.Variants
+	(*LinkVersion)(nil),                    // 33: shortener.LinkVersion
+	(*UpdateShortURLResponse)(nil),         // 34: shortener.UpdateShortURLResponse
+	(*GetLinkVersionsRequest)(nil),         // 35: shortener.GetLinkVersionsRequest
+	(*GetLinkVersionsResponse)(nil),        // 36: shortener.GetLinkVersionsResponse
+	(*RollbackShortURLRequest)(nil),        // 37: shortener.RollbackShortURLRequest
+	(*RollbackShortURLResponse)(nil),       // 38: shortener.RollbackShortURLResponse
+	(*GetUserTagsRequest)(nil),             // 39: shortener.GetUserTagsRequest
+	(*TagCount)(nil),                       // 40: shortener.TagCount
+	(*GetUserTagsResponse)(nil),            // 41: shortener.GetUserTagsResponse
+	(*MergeTagsRequest)(nil),               // 42: shortener.MergeTagsRequest
+	(*MergeTagsResponse)(nil),              // 43: shortener.MergeTagsResponse
+	(*RefetchMetadataRequest)(nil),         // 44: shortener.RefetchMetadataRequest
+	(*RefetchMetadataResponse)(nil),        // 45: shortener.RefetchMetadataResponse
 }
 var file_proto_shortener_proto_depIdxs = []int32{
-	4,  // 0: shortener.GetShortURLsRequest.originalURLs:type_name -> shortener.OriginalURLWithID
-	6,  // 1: shortener.GetShortURLsResponse.shortURLs:type_name -> shortener.ShortURLWithID
-	9,  // 2: shortener.GetUserURLsResponse.shortOriginalURLs:type_name -> shortener.ShortOriginalURL
-	0,  // 3: shortener.Shortener.GetShortURL:input_type -> shortener.GetShortURLRequest
-	2,  // 4: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
-	5,  // 5: shortener.Shortener.GetShortURLs:input_type -> shortener.GetShortURLsRequest
-	8,  // 6: shortener.Shortener.GetUserURLs:input_type -> shortener.GetUserURLsRequest
-	11, // 7: shortener.Shortener.MarkRecordsForDeletion:input_type -> shortener.MarkRecordsForDeletionRequest
-	13, // 8: shortener.Shortener.Ping:input_type -> shortener.PingRequest
-	15, // 9: shortener.Shortener.GetURLsStats:input_type -> shortener.GetURLsStatsRequest
-	1,  // 10: shortener.Shortener.GetShortURL:output_type -> shortener.GetShortURLResponse
-	3,  // 11: shortener.Shortener.GetOriginalURL:output_type -> shortener.GetOriginalURLResponse
-	7,  // 12: shortener.Shortener.GetShortURLs:output_type -> shortener.GetShortURLsResponse
-	10, // 13: shortener.Shortener.GetUserURLs:output_type -> shortener.GetUserURLsResponse
-	12, // 14: shortener.Shortener.MarkRecordsForDeletion:output_type -> shortener.MarkRecordsForDeletionResponse
-	14, // 15: shortener.Shortener.Ping:output_type -> shortener.PingResponse
-	16, // 16: shortener.Shortener.GetURLsStats:output_type -> shortener.GetURLsStatsResponse
-	10, // [10:17] is the sub-list for method output_type
-	3,  // [3:10] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	3,  // 0: shortener.GetShortURLRequest.utm:type_name -> shortener.UTMParams
+	2,  // 1: shortener.GetShortURLRequest.routing:type_name -> shortener.RoutingRule
+	1,  // 2: shortener.GetShortURLRequest.variants:type_name -> shortener.Variant
+	7,  // 3: shortener.GetShortURLsRequest.originalURLs:type_name -> shortener.OriginalURLWithID
+	9,  // 4: shortener.GetShortURLsResponse.shortURLs:type_name -> shortener.ShortURLWithID
+	13, // 5: shortener.ShortOriginalURL.metadata:type_name -> shortener.LinkMetadata
+	12, // 6: shortener.GetUserURLsResponse.shortOriginalURLs:type_name -> shortener.ShortOriginalURL
+	24, // 7: shortener.GetLinkStatsResponse.series:type_name -> shortener.StatsPoint
+	25, // 8: shortener.GetLinkStatsResponse.topReferrers:type_name -> shortener.StatsCount
+	25, // 9: shortener.GetLinkStatsResponse.topUserAgents:type_name -> shortener.StatsCount
+	25, // 10: shortener.GetLinkStatsResponse.topCountries:type_name -> shortener.StatsCount
+	25, // 11: shortener.GetLinkStatsResponse.variants:type_name -> shortener.StatsCount
+	3,  // 12: shortener.UpdateShortURLRequest.utm:type_name -> shortener.UTMParams
+	31, // 13: shortener.UpdateShortURLRequest.routing:type_name -> shortener.RoutingRules
+	32, // 14: shortener.UpdateShortURLRequest.variants:type_name -> shortener.Variants
+	30, // 15: shortener.UpdateShortURLRequest.tags:type_name -> shortener.Tags
+	2,  // 16: shortener.RoutingRules.rules:type_name -> shortener.RoutingRule
+	1,  // 17: shortener.Variants.variants:type_name -> shortener.Variant
+	3,  // 18: shortener.LinkVersion.utm:type_name -> shortener.UTMParams
+	2,  // 19: shortener.LinkVersion.routing:type_name -> shortener.RoutingRule
+	1,  // 20: shortener.LinkVersion.variants:type_name -> shortener.Variant
+	33, // 21: shortener.UpdateShortURLResponse.version:type_name -> shortener.LinkVersion
+	33, // 22: shortener.GetLinkVersionsResponse.versions:type_name -> shortener.LinkVersion
+	33, // 23: shortener.RollbackShortURLResponse.version:type_name -> shortener.LinkVersion
+	40, // 24: shortener.GetUserTagsResponse.tags:type_name -> shortener.TagCount
+	13, // 25: shortener.RefetchMetadataResponse.metadata:type_name -> shortener.LinkMetadata
+	0,  // 26: shortener.Shortener.GetShortURL:input_type -> shortener.GetShortURLRequest
+	5,  // 27: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
+	8,  // 28: shortener.Shortener.GetShortURLs:input_type -> shortener.GetShortURLsRequest
+	11, // 29: shortener.Shortener.GetUserURLs:input_type -> shortener.GetUserURLsRequest
+	15, // 30: shortener.Shortener.MarkRecordsForDeletion:input_type -> shortener.MarkRecordsForDeletionRequest
+	17, // 31: shortener.Shortener.RestoreShortURL:input_type -> shortener.RestoreShortURLRequest
+	19, // 32: shortener.Shortener.Ping:input_type -> shortener.PingRequest
+	21, // 33: shortener.Shortener.GetURLsStats:input_type -> shortener.GetURLsStatsRequest
+	23, // 34: shortener.Shortener.GetLinkStats:input_type -> shortener.GetLinkStatsRequest
+	27, // 35: shortener.Shortener.GetQRCode:input_type -> shortener.GetQRCodeRequest
+	29, // 36: shortener.Shortener.UpdateShortURL:input_type -> shortener.UpdateShortURLRequest
+	35, // 37: shortener.Shortener.GetLinkVersions:input_type -> shortener.GetLinkVersionsRequest
+	37, // 38: shortener.Shortener.RollbackShortURL:input_type -> shortener.RollbackShortURLRequest
+	39, // 39: shortener.Shortener.GetUserTags:input_type -> shortener.GetUserTagsRequest
+	42, // 40: shortener.Shortener.MergeTags:input_type -> shortener.MergeTagsRequest
+	44, // 41: shortener.Shortener.RefetchMetadata:input_type -> shortener.RefetchMetadataRequest
+	4,  // 42: shortener.Shortener.GetShortURL:output_type -> shortener.GetShortURLResponse
+	6,  // 43: shortener.Shortener.GetOriginalURL:output_type -> shortener.GetOriginalURLResponse
+	10, // 44: shortener.Shortener.GetShortURLs:output_type -> shortener.GetShortURLsResponse
+	14, // 45: shortener.Shortener.GetUserURLs:output_type -> shortener.GetUserURLsResponse
+	16, // 46: shortener.Shortener.MarkRecordsForDeletion:output_type -> shortener.MarkRecordsForDeletionResponse
+	18, // 47: shortener.Shortener.RestoreShortURL:output_type -> shortener.RestoreShortURLResponse
+	20, // 48: shortener.Shortener.Ping:output_type -> shortener.PingResponse
+	22, // 49: shortener.Shortener.GetURLsStats:output_type -> shortener.GetURLsStatsResponse
+	26, // 50: shortener.Shortener.GetLinkStats:output_type -> shortener.GetLinkStatsResponse
+	28, // 51: shortener.Shortener.GetQRCode:output_type -> shortener.GetQRCodeResponse
+	34, // 52: shortener.Shortener.UpdateShortURL:output_type -> shortener.UpdateShortURLResponse
+	36, // 53: shortener.Shortener.GetLinkVersions:output_type -> shortener.GetLinkVersionsResponse
+	38, // 54: shortener.Shortener.RollbackShortURL:output_type -> shortener.RollbackShortURLResponse
+	41, // 55: shortener.Shortener.GetUserTags:output_type -> shortener.GetUserTagsResponse
+	43, // 56: shortener.Shortener.MergeTags:output_type -> shortener.MergeTagsResponse
+	45, // 57: shortener.Shortener.RefetchMetadata:output_type -> shortener.RefetchMetadataResponse
+	42, // [42:58] is the sub-list for method output_type
+	26, // [26:42] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_proto_shortener_proto_init() }
@@ -870,13 +3267,15 @@ func file_proto_shortener_proto_init() {
 	if File_proto_shortener_proto != nil {
 		return
 	}
+	file_proto_shortener_proto_msgTypes[27].OneofWrappers = []any{}
+	file_proto_shortener_proto_msgTypes[29].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_shortener_proto_rawDesc), len(file_proto_shortener_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   17,
+			NumMessages:   46,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
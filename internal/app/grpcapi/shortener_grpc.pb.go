@@ -24,8 +24,17 @@ const (
 	Shortener_GetShortURLs_FullMethodName           = "/shortener.Shortener/GetShortURLs"
 	Shortener_GetUserURLs_FullMethodName            = "/shortener.Shortener/GetUserURLs"
 	Shortener_MarkRecordsForDeletion_FullMethodName = "/shortener.Shortener/MarkRecordsForDeletion"
+	Shortener_RestoreShortURL_FullMethodName        = "/shortener.Shortener/RestoreShortURL"
 	Shortener_Ping_FullMethodName                   = "/shortener.Shortener/Ping"
 	Shortener_GetURLsStats_FullMethodName           = "/shortener.Shortener/GetURLsStats"
+	Shortener_GetLinkStats_FullMethodName           = "/shortener.Shortener/GetLinkStats"
+	Shortener_GetQRCode_FullMethodName              = "/shortener.Shortener/GetQRCode"
+	Shortener_UpdateShortURL_FullMethodName         = "/shortener.Shortener/UpdateShortURL"
+	Shortener_GetLinkVersions_FullMethodName        = "/shortener.Shortener/GetLinkVersions"
+	Shortener_RollbackShortURL_FullMethodName       = "/shortener.Shortener/RollbackShortURL"
+	Shortener_GetUserTags_FullMethodName            = "/shortener.Shortener/GetUserTags"
+	Shortener_MergeTags_FullMethodName              = "/shortener.Shortener/MergeTags"
+	Shortener_RefetchMetadata_FullMethodName        = "/shortener.Shortener/RefetchMetadata"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -37,8 +46,17 @@ type ShortenerClient interface {
 	GetShortURLs(ctx context.Context, in *GetShortURLsRequest, opts ...grpc.CallOption) (*GetShortURLsResponse, error)
 	GetUserURLs(ctx context.Context, in *GetUserURLsRequest, opts ...grpc.CallOption) (*GetUserURLsResponse, error)
 	MarkRecordsForDeletion(ctx context.Context, in *MarkRecordsForDeletionRequest, opts ...grpc.CallOption) (*MarkRecordsForDeletionResponse, error)
+	RestoreShortURL(ctx context.Context, in *RestoreShortURLRequest, opts ...grpc.CallOption) (*RestoreShortURLResponse, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	GetURLsStats(ctx context.Context, in *GetURLsStatsRequest, opts ...grpc.CallOption) (*GetURLsStatsResponse, error)
+	GetLinkStats(ctx context.Context, in *GetLinkStatsRequest, opts ...grpc.CallOption) (*GetLinkStatsResponse, error)
+	GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error)
+	UpdateShortURL(ctx context.Context, in *UpdateShortURLRequest, opts ...grpc.CallOption) (*UpdateShortURLResponse, error)
+	GetLinkVersions(ctx context.Context, in *GetLinkVersionsRequest, opts ...grpc.CallOption) (*GetLinkVersionsResponse, error)
+	RollbackShortURL(ctx context.Context, in *RollbackShortURLRequest, opts ...grpc.CallOption) (*RollbackShortURLResponse, error)
+	GetUserTags(ctx context.Context, in *GetUserTagsRequest, opts ...grpc.CallOption) (*GetUserTagsResponse, error)
+	MergeTags(ctx context.Context, in *MergeTagsRequest, opts ...grpc.CallOption) (*MergeTagsResponse, error)
+	RefetchMetadata(ctx context.Context, in *RefetchMetadataRequest, opts ...grpc.CallOption) (*RefetchMetadataResponse, error)
 }
 
 type shortenerClient struct {
@@ -99,6 +117,16 @@ func (c *shortenerClient) MarkRecordsForDeletion(ctx context.Context, in *MarkRe
 	return out, nil
 }
 
+func (c *shortenerClient) RestoreShortURL(ctx context.Context, in *RestoreShortURLRequest, opts ...grpc.CallOption) (*RestoreShortURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreShortURLResponse)
+	err := c.cc.Invoke(ctx, Shortener_RestoreShortURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(PingResponse)
@@ -119,6 +147,86 @@ func (c *shortenerClient) GetURLsStats(ctx context.Context, in *GetURLsStatsRequ
 	return out, nil
 }
 
+func (c *shortenerClient) GetLinkStats(ctx context.Context, in *GetLinkStatsRequest, opts ...grpc.CallOption) (*GetLinkStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLinkStatsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetQRCodeResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetQRCode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) UpdateShortURL(ctx context.Context, in *UpdateShortURLRequest, opts ...grpc.CallOption) (*UpdateShortURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateShortURLResponse)
+	err := c.cc.Invoke(ctx, Shortener_UpdateShortURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetLinkVersions(ctx context.Context, in *GetLinkVersionsRequest, opts ...grpc.CallOption) (*GetLinkVersionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLinkVersionsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RollbackShortURL(ctx context.Context, in *RollbackShortURLRequest, opts ...grpc.CallOption) (*RollbackShortURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RollbackShortURLResponse)
+	err := c.cc.Invoke(ctx, Shortener_RollbackShortURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetUserTags(ctx context.Context, in *GetUserTagsRequest, opts ...grpc.CallOption) (*GetUserTagsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserTagsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetUserTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) MergeTags(ctx context.Context, in *MergeTagsRequest, opts ...grpc.CallOption) (*MergeTagsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeTagsResponse)
+	err := c.cc.Invoke(ctx, Shortener_MergeTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RefetchMetadata(ctx context.Context, in *RefetchMetadataRequest, opts ...grpc.CallOption) (*RefetchMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefetchMetadataResponse)
+	err := c.cc.Invoke(ctx, Shortener_RefetchMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility.
@@ -128,8 +236,17 @@ type ShortenerServer interface {
 	GetShortURLs(context.Context, *GetShortURLsRequest) (*GetShortURLsResponse, error)
 	GetUserURLs(context.Context, *GetUserURLsRequest) (*GetUserURLsResponse, error)
 	MarkRecordsForDeletion(context.Context, *MarkRecordsForDeletionRequest) (*MarkRecordsForDeletionResponse, error)
+	RestoreShortURL(context.Context, *RestoreShortURLRequest) (*RestoreShortURLResponse, error)
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	GetURLsStats(context.Context, *GetURLsStatsRequest) (*GetURLsStatsResponse, error)
+	GetLinkStats(context.Context, *GetLinkStatsRequest) (*GetLinkStatsResponse, error)
+	GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error)
+	UpdateShortURL(context.Context, *UpdateShortURLRequest) (*UpdateShortURLResponse, error)
+	GetLinkVersions(context.Context, *GetLinkVersionsRequest) (*GetLinkVersionsResponse, error)
+	RollbackShortURL(context.Context, *RollbackShortURLRequest) (*RollbackShortURLResponse, error)
+	GetUserTags(context.Context, *GetUserTagsRequest) (*GetUserTagsResponse, error)
+	MergeTags(context.Context, *MergeTagsRequest) (*MergeTagsResponse, error)
+	RefetchMetadata(context.Context, *RefetchMetadataRequest) (*RefetchMetadataResponse, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -155,12 +272,39 @@ func (UnimplementedShortenerServer) GetUserURLs(context.Context, *GetUserURLsReq
 func (UnimplementedShortenerServer) MarkRecordsForDeletion(context.Context, *MarkRecordsForDeletionRequest) (*MarkRecordsForDeletionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MarkRecordsForDeletion not implemented")
 }
+func (UnimplementedShortenerServer) RestoreShortURL(context.Context, *RestoreShortURLRequest) (*RestoreShortURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreShortURL not implemented")
+}
 func (UnimplementedShortenerServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
 func (UnimplementedShortenerServer) GetURLsStats(context.Context, *GetURLsStatsRequest) (*GetURLsStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetURLsStats not implemented")
 }
+func (UnimplementedShortenerServer) GetLinkStats(context.Context, *GetLinkStatsRequest) (*GetLinkStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkStats not implemented")
+}
+func (UnimplementedShortenerServer) GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQRCode not implemented")
+}
+func (UnimplementedShortenerServer) UpdateShortURL(context.Context, *UpdateShortURLRequest) (*UpdateShortURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateShortURL not implemented")
+}
+func (UnimplementedShortenerServer) GetLinkVersions(context.Context, *GetLinkVersionsRequest) (*GetLinkVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkVersions not implemented")
+}
+func (UnimplementedShortenerServer) RollbackShortURL(context.Context, *RollbackShortURLRequest) (*RollbackShortURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackShortURL not implemented")
+}
+func (UnimplementedShortenerServer) GetUserTags(context.Context, *GetUserTagsRequest) (*GetUserTagsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserTags not implemented")
+}
+func (UnimplementedShortenerServer) MergeTags(context.Context, *MergeTagsRequest) (*MergeTagsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeTags not implemented")
+}
+func (UnimplementedShortenerServer) RefetchMetadata(context.Context, *RefetchMetadataRequest) (*RefetchMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefetchMetadata not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 func (UnimplementedShortenerServer) testEmbeddedByValue()                   {}
 
@@ -272,6 +416,24 @@ func _Shortener_MarkRecordsForDeletion_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_RestoreShortURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreShortURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RestoreShortURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RestoreShortURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RestoreShortURL(ctx, req.(*RestoreShortURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PingRequest)
 	if err := dec(in); err != nil {
@@ -308,6 +470,150 @@ func _Shortener_GetURLsStats_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_GetLinkStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinkStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkStats(ctx, req.(*GetLinkStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetQRCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQRCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetQRCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetQRCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetQRCode(ctx, req.(*GetQRCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_UpdateShortURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateShortURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateShortURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_UpdateShortURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateShortURL(ctx, req.(*UpdateShortURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetLinkVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinkVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkVersions(ctx, req.(*GetLinkVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RollbackShortURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackShortURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RollbackShortURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RollbackShortURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RollbackShortURL(ctx, req.(*RollbackShortURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetUserTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetUserTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetUserTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetUserTags(ctx, req.(*GetUserTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_MergeTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).MergeTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_MergeTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).MergeTags(ctx, req.(*MergeTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RefetchMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefetchMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RefetchMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RefetchMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RefetchMetadata(ctx, req.(*RefetchMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -335,6 +641,10 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MarkRecordsForDeletion",
 			Handler:    _Shortener_MarkRecordsForDeletion_Handler,
 		},
+		{
+			MethodName: "RestoreShortURL",
+			Handler:    _Shortener_RestoreShortURL_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _Shortener_Ping_Handler,
@@ -343,6 +653,38 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetURLsStats",
 			Handler:    _Shortener_GetURLsStats_Handler,
 		},
+		{
+			MethodName: "GetLinkStats",
+			Handler:    _Shortener_GetLinkStats_Handler,
+		},
+		{
+			MethodName: "GetQRCode",
+			Handler:    _Shortener_GetQRCode_Handler,
+		},
+		{
+			MethodName: "UpdateShortURL",
+			Handler:    _Shortener_UpdateShortURL_Handler,
+		},
+		{
+			MethodName: "GetLinkVersions",
+			Handler:    _Shortener_GetLinkVersions_Handler,
+		},
+		{
+			MethodName: "RollbackShortURL",
+			Handler:    _Shortener_RollbackShortURL_Handler,
+		},
+		{
+			MethodName: "GetUserTags",
+			Handler:    _Shortener_GetUserTags_Handler,
+		},
+		{
+			MethodName: "MergeTags",
+			Handler:    _Shortener_MergeTags_Handler,
+		},
+		{
+			MethodName: "RefetchMetadata",
+			Handler:    _Shortener_RefetchMetadata_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortener.proto",
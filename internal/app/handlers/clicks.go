@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// variantCookie - cookie с именем варианта адреса назначения, который получил клиент.
+// Путь cookie - короткий урл, поэтому у каждой ссылки свой вариант.
+const (
+	variantCookie       = "variant"
+	variantCookieMaxAge = 30 * 24 * time.Hour
+)
+
+// recordClick передает сервису событие перехода по короткому урлу id на вариант адреса назначения variant.
+func (h *Handler) recordClick(req *http.Request, id, variant string) {
+	h.service.RecordClick(settings.ClickEvent{
+		Time:           time.Now().UTC(),
+		ShortURL:       id,
+		Referer:        req.Referer(),
+		UserAgent:      req.UserAgent(),
+		IP:             h.clientIP(req),
+		AcceptLanguage: req.Header.Get("Accept-Language"),
+		Variant:        variant,
+	})
+}
+
+// redirectRequest возвращает сведения о клиенте для выбора адреса назначения короткого урла id
+// правилами маршрутизации и вариантами A/B теста и параметры запроса для передачи в адрес назначения.
+func (h *Handler) redirectRequest(req *http.Request, id string) settings.RedirectRequest {
+	r := settings.RedirectRequest{
+		UserAgent:      req.UserAgent(),
+		AcceptLanguage: req.Header.Get("Accept-Language"),
+		IP:             h.clientIP(req),
+		Query:          req.URL.Query(),
+	}
+	// служебные параметры не передаются в адрес назначения
+	r.Query.Del("confirm")
+	r.Query.Del("preview")
+	if cookie, err := req.Cookie(variantCookie); err == nil {
+		r.Variant = cookie.Value
+	}
+	return r
+}
+
+// setVariantCookie запоминает у клиента вариант адреса назначения короткого урла id,
+// чтобы при следующих переходах он попадал на тот же вариант.
+func setVariantCookie(res http.ResponseWriter, id, variant string) {
+	if variant == "" {
+		return
+	}
+	http.SetCookie(res, &http.Cookie{
+		Name:     variantCookie,
+		Value:    variant,
+		Path:     "/" + id,
+		MaxAge:   int(variantCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clientIP возвращает IP адрес клиента.
+// Заголовкам X-Real-IP и X-Forwarded-For доверяем, только если запрос пришел из доверенной подсети прокси.
+// Начало X-Forwarded-For задает сам клиент, поэтому адрес ищется справа налево: клиентом считается
+// первый адрес не из доверенной подсети, то есть тот, от которого запрос принял доверенный прокси.
+func (h *Handler) clientIP(req *http.Request) string {
+	remoteIP := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !h.inTrustedSubnet(net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+	if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	clientIP := remoteIP
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		clientIP = ip.String()
+		if !h.inTrustedSubnet(ip) {
+			break
+		}
+	}
+	return clientIP
+}
+
+// inTrustedSubnet сообщает, входит ли адрес ip в доверенную подсеть.
+// Та же подсеть используется для доступа к внутренней статистике в checkForTrustedNet.
+func (h *Handler) inTrustedSubnet(ip net.IP) bool {
+	if h.trustedSubnet == "" || ip == nil {
+		return false
+	}
+	_, trustedNet, err := net.ParseCIDR(h.trustedSubnet)
+	return err == nil && trustedNet.Contains(ip)
+}
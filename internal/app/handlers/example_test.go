@@ -9,7 +9,7 @@ import (
 
 func ExampleHandler_GetShortURL() {
 
-	shortURL := "ya.ru"
+	shortURL := "https://ya.ru"
 	endpoint := "http://localhost:8080/"
 	client := &http.Client{}
 	request, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(shortURL))
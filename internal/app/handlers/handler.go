@@ -6,9 +6,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nasik90/url-shortener/cmd/shortener/settings"
 	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
@@ -17,13 +20,25 @@ import (
 
 // Service - интерфейс, который описывает методы объектов с типом Service
 type Service interface {
-	GetShortURL(ctx context.Context, originalURL, userID string) (string, error)
-	GetOriginalURL(ctx context.Context, shortURL string) (string, error)
-	GetShortURLs(ctx context.Context, originalURLs map[string]string, userID string) (map[string]string, error)
-	GetUserURLs(ctx context.Context, userID string) (map[string]string, error)
+	GetShortURL(ctx context.Context, originalURL, userID string, opts settings.LinkOptions) (string, error)
+	GetOriginalURL(ctx context.Context, shortURL string, r settings.RedirectRequest) (settings.Redirect, error)
+	GetProtectedOriginalURL(ctx context.Context, shortURL, password string, r settings.RedirectRequest) (settings.Redirect, error)
+	GetLinkPreview(ctx context.Context, shortURL string) (settings.LinkPreview, error)
+	GetShortURLs(ctx context.Context, originalURLs map[string]settings.BatchURL, userID string) (map[string]string, error)
+	GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) (settings.UserURLsPage, error)
 	MarkRecordsForDeletion(ctx context.Context, shortURLs []string, userID string)
 	Ping(ctx context.Context) error
-	GetURLsStats(ctx context.Context) (int, int, error)
+	GetURLsStats(ctx context.Context) (int, int, int64, error)
+	RecordClick(event settings.ClickEvent)
+	GetLinkStats(ctx context.Context, shortURL, userID string, q settings.StatsQuery) (settings.LinkStats, error)
+	GetQRCode(ctx context.Context, shortURL string, opts settings.QROptions) ([]byte, string, error)
+	UpdateShortURL(ctx context.Context, shortURL, userID string, update settings.LinkUpdate) (settings.LinkVersion, error)
+	GetLinkVersions(ctx context.Context, shortURL, userID string) ([]settings.LinkVersion, error)
+	RollbackShortURL(ctx context.Context, shortURL, userID string, version int) (settings.LinkVersion, error)
+	RestoreShortURL(ctx context.Context, shortURL, userID string) error
+	GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error)
+	MergeTags(ctx context.Context, userID string, from []string, to string) (int, error)
+	RefetchMetadata(ctx context.Context, shortURL, userID string) (settings.LinkMetadata, error)
 }
 
 // Handler - структура, хранящая объект типа Service.
@@ -55,12 +70,12 @@ func (h *Handler) GetShortURL() http.HandlerFunc {
 			return
 		}
 		status := http.StatusCreated
-		shortURL, err := h.service.GetShortURL(ctx, originalURL, userID)
+		shortURL, err := h.service.GetShortURL(ctx, originalURL, userID, settings.LinkOptions{})
 		if err != nil {
 			if errors.Is(err, settings.ErrOriginalURLNotUnique) {
 				status = http.StatusConflict
 			} else {
-				http.Error(res, err.Error(), http.StatusInternalServerError)
+				http.Error(res, err.Error(), errorStatus(err))
 				return
 			}
 		}
@@ -71,21 +86,97 @@ func (h *Handler) GetShortURL() http.HandlerFunc {
 }
 
 // GetOriginalURL - метод для получения оригинального URL по переданному короткому URL.
+// Если к короткому URL добавлен "+" или передан параметр preview=1, отдает страницу предпросмотра без редиректа.
+// Ту же страницу отдает, если перед переходом нужна промежуточная страница; переход с нее подтверждается
+// формой, которую принимает GetProtectedOriginalURL. Параметр запроса переход не подтверждает,
+// иначе промежуточную страницу можно было бы обойти, распространяя ссылку с ним.
 func (h *Handler) GetOriginalURL() http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		id := strings.Trim(req.URL.Path, "/")
-		originalURL, err := h.service.GetOriginalURL(ctx, id)
+		query := req.URL.Query()
+		if previewID, ok := strings.CutSuffix(id, "+"); ok || query.Get("preview") == "1" {
+			h.writePreview(res, req, previewID)
+			return
+		}
+		redirect, err := h.service.GetOriginalURL(ctx, id, h.redirectRequest(req, id))
+		if errors.Is(err, settings.ErrPasswordRequired) {
+			writePasswordForm(res, http.StatusOK, "")
+			return
+		}
+		if errors.Is(err, settings.ErrInterstitialRequired) {
+			h.writePreview(res, req, id)
+			return
+		}
 		if err != nil {
-			if err == storage.ErrRecordMarkedForDel {
-				http.Error(res, err.Error(), http.StatusGone)
-				return
-			}
-			http.Error(res, err.Error(), http.StatusNotFound)
+			http.Error(res, err.Error(), redirectErrorStatus(err))
+			return
+		}
+		setVariantCookie(res, id, redirect.Variant)
+		h.recordClick(req, id, redirect.Variant)
+		res.Header().Set("Location", redirect.URL)
+		res.Header().Set("Cache-Control", cacheControl(redirect.CacheMaxAge))
+		res.WriteHeader(redirect.Status)
+	}
+}
+
+// cacheControl возвращает значение заголовка Cache-Control для редиректа, который можно кэшировать maxAge.
+// Редиректы, которые кэшировать нельзя, помечаются no-store, чтобы каждый переход доходил до сервиса.
+// Остальные могут хранить и браузеры, и общие кэши CDN.
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "no-store"
+	}
+	return "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+}
+
+// GetProtectedOriginalURL - метод для перехода по ссылке, защищенной паролем, или с промежуточной страницы.
+// Пароль передается в поле password формы, которую отдает GetOriginalURL.
+// Форма промежуточной страницы передает поле confirm=1 без пароля.
+func (h *Handler) GetProtectedOriginalURL() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		id := strings.Trim(req.URL.Path, "/")
+		if err := req.ParseForm(); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r := h.redirectRequest(req, id)
+		var (
+			redirect settings.Redirect
+			err      error
+		)
+		if req.PostForm.Has("password") {
+			redirect, err = h.service.GetProtectedOriginalURL(ctx, id, req.PostForm.Get("password"), r)
+		} else {
+			r.Confirmed = req.PostForm.Get("confirm") == "1"
+			redirect, err = h.service.GetOriginalURL(ctx, id, r)
+		}
+		if errors.Is(err, settings.ErrPasswordRequired) {
+			writePasswordForm(res, http.StatusOK, "")
+			return
+		}
+		if errors.Is(err, settings.ErrInterstitialRequired) {
+			h.writePreview(res, req, id)
+			return
+		}
+		if errors.Is(err, settings.ErrWrongPassword) {
+			writePasswordForm(res, http.StatusForbidden, "Неверный пароль")
+			return
+		}
+		if errors.Is(err, settings.ErrTooManyAttempts) {
+			http.Error(res, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), redirectErrorStatus(err))
 			return
 		}
-		res.Header().Set("Location", originalURL)
-		res.WriteHeader(http.StatusTemporaryRedirect)
+		setVariantCookie(res, id, redirect.Variant)
+		h.recordClick(req, id, redirect.Variant)
+		res.Header().Set("Location", redirect.URL)
+		res.Header().Set("Cache-Control", "no-store")
+		res.WriteHeader(http.StatusSeeOther)
 	}
 }
 
@@ -96,7 +187,21 @@ func (h *Handler) GetShortURLJSON() http.HandlerFunc {
 		ctx := req.Context()
 		userID := middleware.UserIDFromContext(ctx)
 		var input struct {
-			URL string `json:"url"`
+			URL              string                 `json:"url"`
+			Alias            string                 `json:"alias"`
+			ExpiresAt        *time.Time             `json:"expires_at"`
+			TTLSeconds       int64                  `json:"ttl_seconds"`
+			MaxClicks        int64                  `json:"max_clicks"`
+			Password         string                 `json:"password"`
+			Interstitial     bool                   `json:"interstitial"`
+			FallbackURL      string                 `json:"fallback_url"`
+			QueryPassthrough string                 `json:"query_passthrough"`
+			UTM              settings.UTMParams     `json:"utm"`
+			RedirectStatus   int                    `json:"redirect_status"`
+			Routing          []settings.RoutingRule `json:"routing"`
+			Variants         []settings.Variant     `json:"variants"`
+			Tags             []string               `json:"tags"`
+			Folder           string                 `json:"folder"`
 		}
 		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
 			http.Error(res, err.Error(), http.StatusBadRequest)
@@ -109,12 +214,30 @@ func (h *Handler) GetShortURLJSON() http.HandlerFunc {
 		}
 
 		status := http.StatusCreated
-		shortURL, err := h.service.GetShortURL(ctx, input.URL, userID)
+		opts := settings.LinkOptions{
+			Alias:            input.Alias,
+			TTL:              time.Duration(input.TTLSeconds) * time.Second,
+			MaxClicks:        input.MaxClicks,
+			Password:         input.Password,
+			Interstitial:     input.Interstitial,
+			FallbackURL:      input.FallbackURL,
+			QueryPassthrough: input.QueryPassthrough,
+			UTM:              input.UTM,
+			RedirectStatus:   input.RedirectStatus,
+			Routing:          input.Routing,
+			Variants:         input.Variants,
+			Tags:             input.Tags,
+			Folder:           input.Folder,
+		}
+		if input.ExpiresAt != nil {
+			opts.ExpiresAt = *input.ExpiresAt
+		}
+		shortURL, err := h.service.GetShortURL(ctx, input.URL, userID, opts)
 		if err != nil {
 			if errors.Is(err, settings.ErrOriginalURLNotUnique) {
 				status = http.StatusConflict
 			} else {
-				http.Error(res, err.Error(), http.StatusInternalServerError)
+				http.Error(res, err.Error(), errorStatus(err))
 				return
 			}
 		}
@@ -155,19 +278,20 @@ func (h *Handler) GetShortURLs() http.HandlerFunc {
 		type input struct {
 			СorrelationID string `json:"correlation_id"`
 			OriginalURL   string `json:"original_url"`
+			Alias         string `json:"alias"`
 		}
 		var s []input
 		if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
 			http.Error(res, err.Error(), http.StatusBadRequest)
 			return
 		}
-		originalURLs := make(map[string]string)
+		originalURLs := make(map[string]settings.BatchURL)
 		for _, in := range s {
-			originalURLs[in.СorrelationID] = in.OriginalURL
+			originalURLs[in.СorrelationID] = settings.BatchURL{OriginalURL: in.OriginalURL, Alias: in.Alias}
 		}
 		shortURLs, err := h.service.GetShortURLs(ctx, originalURLs, userID)
 		if err != nil {
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			http.Error(res, err.Error(), errorStatus(err))
 			return
 		}
 		type output struct {
@@ -192,23 +316,57 @@ func (h *Handler) GetShortURLs() http.HandlerFunc {
 }
 
 // GetUserURLs - возвращает список URL`ов пользователя.
-// Список представляет собой массив структур с указанием короткого и оригинального URL.
+// Список представляет собой массив структур с указанием короткого и оригинального URL
+// и результата последней проверки доступности оригинального URL.
 func (h *Handler) GetUserURLs() http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		userID := middleware.UserIDFromContext(ctx)
-		UserURLs, err := h.service.GetUserURLs(ctx, userID)
+		q, err := parseUserURLsQuery(req)
 		if err != nil {
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := h.service.GetUserURLs(ctx, userID, q)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, settings.ErrInvalidUserURLsQuery) {
+				status = http.StatusBadRequest
+			}
+			http.Error(res, err.Error(), status)
 			return
 		}
+		UserURLs := page.URLs
+		type health struct {
+			settings.LinkHealth
+			Dead bool `json:"dead"`
+		}
 		type output struct {
-			ShortURL    string `json:"short_url"`
-			OriginalURL string `json:"original_url"`
+			ShortURL    string                `json:"short_url"`
+			OriginalURL string                `json:"original_url"`
+			Health      health                `json:"health"`
+			CreatedAt   time.Time             `json:"created_at,omitzero"`
+			Clicks      int64                 `json:"clicks"`
+			ExpiresAt   time.Time             `json:"expires_at,omitzero"`
+			Deleted     bool                  `json:"deleted,omitzero"`
+			Tags        []string              `json:"tags,omitempty"`
+			Folder      string                `json:"folder,omitzero"`
+			Metadata    settings.LinkMetadata `json:"metadata,omitzero"`
 		}
 		var o []output
-		for shortURL, originalURL := range UserURLs {
-			o = append(o, output{ShortURL: shortURL, OriginalURL: originalURL})
+		for _, userURL := range UserURLs {
+			o = append(o, output{
+				ShortURL:    userURL.ShortURL,
+				OriginalURL: userURL.OriginalURL,
+				Health:      health{LinkHealth: userURL.Health, Dead: userURL.Health.Dead()},
+				CreatedAt:   userURL.CreatedAt,
+				Clicks:      userURL.Clicks,
+				ExpiresAt:   userURL.ExpiresAt,
+				Deleted:     userURL.Deleted,
+				Tags:        userURL.Tags,
+				Folder:      userURL.Folder,
+				Metadata:    userURL.Metadata,
+			})
 		}
 
 		result, err := json.Marshal(o)
@@ -222,12 +380,38 @@ func (h *Handler) GetUserURLs() http.HandlerFunc {
 			status = http.StatusNoContent
 		}
 
+		if page.NextCursor != "" {
+			res.Header().Set("X-Next-Cursor", page.NextCursor)
+		}
 		res.Header().Set("content-type", "application/json")
 		res.WriteHeader(status)
 		res.Write(result)
 	}
 }
 
+// parseUserURLsQuery читает параметры выборки урлов пользователя: limit, cursor, sort (created_at или clicks),
+// order (asc или desc), deleted и expired (exclude, include или only), q - подстроку оригинального урла,
+// tag и folder - тег и папку ссылок.
+func parseUserURLsQuery(req *http.Request) (settings.UserURLsQuery, error) {
+	var q settings.UserURLsQuery
+	values := req.URL.Query()
+	if limit := values.Get("limit"); limit != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return q, fmt.Errorf("%w: limit: %w", settings.ErrInvalidUserURLsQuery, err)
+		}
+	}
+	q.Cursor = values.Get("cursor")
+	q.Sort = settings.UserURLsSort(values.Get("sort"))
+	q.Order = settings.SortOrder(values.Get("order"))
+	q.Deleted = settings.LinkFilter(values.Get("deleted"))
+	q.Expired = settings.LinkFilter(values.Get("expired"))
+	q.Search = values.Get("q")
+	q.Tag = values.Get("tag")
+	q.Folder = values.Get("folder")
+	return q, nil
+}
+
 // MarkRecordsForDeletion помечает на удаление переданные в массиве короткие URL
 func (h *Handler) MarkRecordsForDeletion() http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
@@ -244,7 +428,22 @@ func (h *Handler) MarkRecordsForDeletion() http.HandlerFunc {
 	}
 }
 
-// GetUserURLs - возвращает количество URL и пользователей.
+// RestoreShortURL снимает пометку на удаление с короткого URL пользователя, пока его не удалили безвозвратно.
+// Короткий урл передается в пути /api/user/urls/{id}/restore.
+func (h *Handler) RestoreShortURL() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/user/urls/"), "/restore")
+		if err := h.service.RestoreShortURL(ctx, id, userID); err != nil {
+			http.Error(res, err.Error(), ownLinkErrorStatus(err))
+			return
+		}
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetURLsStats - возвращает количество URL, пользователей и уникальных посетителей.
 // В настройках сервиса обязательно должен быть указан CIDR и передан в заголовке X-Real-IP IP адрес.
 func (h *Handler) GetURLsStats() http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
@@ -254,16 +453,17 @@ func (h *Handler) GetURLsStats() http.HandlerFunc {
 			return
 		}
 		ctx := req.Context()
-		urls, users, err := h.service.GetURLsStats(ctx)
+		urls, users, visitors, err := h.service.GetURLsStats(ctx)
 		if err != nil {
 			http.Error(res, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		type outputType struct {
-			Urls  int `json:"urls"`
-			Users int `json:"users"`
+			Urls     int   `json:"urls"`
+			Users    int   `json:"users"`
+			Visitors int64 `json:"visitors"`
 		}
-		output := outputType{Urls: urls, Users: users}
+		output := outputType{Urls: urls, Users: users, Visitors: visitors}
 		result, err := json.Marshal(output)
 		if err != nil {
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -276,14 +476,41 @@ func (h *Handler) GetURLsStats() http.HandlerFunc {
 	}
 }
 
+// redirectErrorStatus возвращает http статус ответа для ошибки получения оригинального URL.
+func redirectErrorStatus(err error) int {
+	if errors.Is(err, settings.ErrURLBlocked) {
+		return http.StatusForbidden
+	}
+	if err == storage.ErrRecordMarkedForDel || errors.Is(err, storage.ErrRecordExpired) || errors.Is(err, storage.ErrClickLimitReached) {
+		return http.StatusGone
+	}
+	return http.StatusNotFound
+}
+
+// errorStatus возвращает http статус ответа для ошибки, полученной от сервиса.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, settings.ErrInvalidURL), errors.Is(err, settings.ErrInvalidAlias), errors.Is(err, settings.ErrInvalidExpiration),
+		errors.Is(err, settings.ErrInvalidMaxClicks), errors.Is(err, settings.ErrInvalidPassword),
+		errors.Is(err, settings.ErrInvalidQueryPassthrough), errors.Is(err, settings.ErrInvalidRedirectStatus),
+		errors.Is(err, settings.ErrInvalidRoutingRule), errors.Is(err, settings.ErrInvalidVariants),
+		errors.Is(err, settings.ErrInvalidTags):
+		return http.StatusBadRequest
+	case errors.Is(err, settings.ErrAliasNotUnique), errors.Is(err, settings.ErrOriginalURLNotUnique):
+		return http.StatusConflict
+	case errors.Is(err, settings.ErrURLBlocked):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *Handler) checkForTrustedNet(req *http.Request) error {
 
 	if h.trustedSubnet == "" {
 		return errors.New("trusted subnet is empty, access forbidden")
 	}
 
-	_, trustedNet, err := net.ParseCIDR(h.trustedSubnet)
-	if err != nil {
+	if _, _, err := net.ParseCIDR(h.trustedSubnet); err != nil {
 		return err
 	}
 
@@ -297,7 +524,7 @@ func (h *Handler) checkForTrustedNet(req *http.Request) error {
 		return errors.New("forbidden - invalid IP")
 	}
 
-	if !trustedNet.Contains(ip) {
+	if !h.inTrustedSubnet(ip) {
 		return errors.New("forbidden - IP not in trusted subnet")
 	}
 
@@ -1,28 +1,45 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"image/png"
 	"io"
 	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 
 	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/grpcapi"
+	"github.com/nasik90/url-shortener/internal/app/health"
+	"github.com/nasik90/url-shortener/internal/app/hll"
+	"github.com/nasik90/url-shortener/internal/app/metadata"
 	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+	"github.com/nasik90/url-shortener/internal/app/netguard"
+	"github.com/nasik90/url-shortener/internal/app/policy"
 	"github.com/nasik90/url-shortener/internal/app/service"
 	"github.com/nasik90/url-shortener/internal/app/storage"
 )
 
 func TestGetShortURL(t *testing.T) {
 	ctx := context.Background()
-	repo := storage.NewLocalCahce()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
 	type want struct {
 		code              int
 		originalURLFromDB string
@@ -42,6 +59,31 @@ func TestGetShortURL(t *testing.T) {
 				originalURLFromDB: "https://practicum.yandex.ru/",
 			},
 		},
+		{
+			name:        "positive test #2 - normalization",
+			originalURL: "HTTPS://Practicum.Yandex.RU:443/Learn?b=2&a=1",
+			userID:      "123",
+			want: want{
+				code:              http.StatusCreated,
+				originalURLFromDB: "https://practicum.yandex.ru/Learn?b=2&a=1",
+			},
+		},
+		{
+			name:        "negative test #1 - scheme is missing",
+			originalURL: "ya.ru",
+			userID:      "123",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "negative test #2 - scheme is not allowed",
+			originalURL: "javascript:alert(1)",
+			userID:      "123",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -60,12 +102,15 @@ func TestGetShortURL(t *testing.T) {
 			assert.Equal(t, tt.want.code, res.StatusCode)
 
 			defer res.Body.Close()
+			if tt.want.code != http.StatusCreated {
+				return
+			}
 			resBody, err := io.ReadAll(res.Body)
 
 			require.NoError(t, err)
 			resBodyString := string(resBody)
 			shortURL := string(resBody)[len(resBodyString)-settings.ShortURLlen:]
-			originalURLFromDB, err := repo.GetOriginalURL(ctx, shortURL)
+			originalURLFromDB, _, err := repo.GetOriginalURL(ctx, shortURL, nil)
 			require.NoError(t, err)
 			assert.Equal(t, tt.want.originalURLFromDB, originalURLFromDB)
 		})
@@ -73,10 +118,10 @@ func TestGetShortURL(t *testing.T) {
 }
 
 func BenchmarkGetShortURL(b *testing.B) {
-	repo := storage.NewLocalCahce()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
 	userID := "123"
 	for i := 0; i < b.N; i++ {
-		originalURL := "testBench" + strconv.Itoa(rand.Int()) + ".ru"
+		originalURL := "https://testBench" + strconv.Itoa(rand.Int()) + ".ru"
 		body := httptest.NewRecorder().Body
 		body.Write([]byte(originalURL))
 		request := httptest.NewRequest(http.MethodPost, "/", body).
@@ -90,9 +135,249 @@ func BenchmarkGetShortURL(b *testing.B) {
 	}
 }
 
+func TestGetShortURLDedup(t *testing.T) {
+	const originalURL = "https://practicum.yandex.ru/"
+	type request struct {
+		userID   string
+		code     int
+		sameAsID int
+	}
+	tests := []struct {
+		name      string
+		dedupMode settings.DedupMode
+		requests  []request
+	}{
+		{
+			name:      "global",
+			dedupMode: settings.DedupGlobal,
+			requests: []request{
+				{userID: "A", code: http.StatusCreated, sameAsID: -1},
+				{userID: "B", code: http.StatusConflict, sameAsID: 0},
+			},
+		},
+		{
+			name:      "per user",
+			dedupMode: settings.DedupPerUser,
+			requests: []request{
+				{userID: "A", code: http.StatusCreated, sameAsID: -1},
+				{userID: "B", code: http.StatusCreated, sameAsID: -1},
+				{userID: "B", code: http.StatusConflict, sameAsID: 1},
+			},
+		},
+		{
+			name:      "off",
+			dedupMode: settings.DedupOff,
+			requests: []request{
+				{userID: "A", code: http.StatusCreated, sameAsID: -1},
+				{userID: "A", code: http.StatusCreated, sameAsID: -1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewLocalCahce(tt.dedupMode)
+			handler := NewHandler(service.NewService(repo, ""), "")
+			var shortURLs []string
+			for _, r := range tt.requests {
+				request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL)).
+					WithContext(context.WithValue(context.Background(), middleware.UserIDContextKey{}, r.userID))
+				w := httptest.NewRecorder()
+				handler.GetShortURL()(w, request)
+
+				res := w.Result()
+				resBody, err := io.ReadAll(res.Body)
+				res.Body.Close()
+				require.NoError(t, err)
+				assert.Equal(t, r.code, res.StatusCode)
+				if r.sameAsID >= 0 {
+					assert.Equal(t, shortURLs[r.sameAsID], string(resBody))
+				} else {
+					assert.NotContains(t, shortURLs, string(resBody))
+				}
+				shortURLs = append(shortURLs, string(resBody))
+			}
+		})
+	}
+}
+
+func TestGetShortURLCodeStrategies(t *testing.T) {
+	const codeLength = 6
+	tests := []struct {
+		name     string
+		strategy string
+		want     []string
+	}{
+		{name: "random", strategy: service.CodeStrategyRandom},
+		{name: "counter", strategy: service.CodeStrategyCounter, want: []string{"AAAAAB", "AAAAAC"}},
+		{name: "hashids", strategy: service.CodeStrategyHashids},
+		{name: "hash", strategy: service.CodeStrategyHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewLocalCahce(settings.DedupGlobal)
+			codeGen, err := service.NewCodeGenerator(tt.strategy, settings.TemplateUnambiguous, "salt", repo)
+			require.NoError(t, err)
+			handler := NewHandler(service.NewService(repo, "", service.WithCodeGenerator(codeGen, codeLength)), "")
+			var codes []string
+			for i := 0; i < 2; i++ {
+				originalURL := "https://practicum.yandex.ru/" + strconv.Itoa(i)
+				request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL)).
+					WithContext(context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123"))
+				w := httptest.NewRecorder()
+				handler.GetShortURL()(w, request)
+
+				res := w.Result()
+				resBody, err := io.ReadAll(res.Body)
+				res.Body.Close()
+				require.NoError(t, err)
+				require.Equal(t, http.StatusCreated, res.StatusCode)
+				code := strings.TrimPrefix(string(resBody), "/")
+				assert.Len(t, code, codeLength)
+				for _, r := range code {
+					assert.Contains(t, settings.TemplateUnambiguous, string(r))
+				}
+				codes = append(codes, code)
+			}
+			assert.NotEqual(t, codes[0], codes[1])
+			if tt.want != nil {
+				assert.Equal(t, tt.want, codes)
+			}
+		})
+	}
+}
+
+func TestCodeCounterPersistence(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt")
+	shorten := func(repo service.Repository, originalURL string) string {
+		codeGen, err := service.NewCodeGenerator(service.CodeStrategyCounter, settings.TemplateForRand, "", repo)
+		require.NoError(t, err)
+		service := service.NewService(repo, "", service.WithCodeGenerator(codeGen, 6), service.WithDeletedRetention(time.Nanosecond))
+		shortURL, err := service.GetShortURL(ctx, originalURL, "123", settings.LinkOptions{})
+		require.NoError(t, err)
+		require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: strings.TrimPrefix(shortURL, "/"), UserID: "123"}))
+		_, err = service.PurgeDeletedRecords(ctx)
+		require.NoError(t, err)
+		return shortURL
+	}
+
+	repo, err := storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	first := shorten(repo, "https://example.com/1")
+	assert.Equal(t, "/AAAAAB", first)
+	require.NoError(t, repo.Close())
+
+	// после безвозвратного удаления и перезапуска выданный код не выдается повторно
+	repo, err = storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	defer repo.Close()
+	second := shorten(repo, "https://example.com/2")
+	assert.NotEqual(t, first, second)
+}
+
+func TestGetShortURLCollisions(t *testing.T) {
+	const codeLength = 6
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	newHandler := func(t *testing.T) *Handler {
+		repo := storage.NewLocalCahce(settings.DedupGlobal)
+		codeGen, err := service.NewCodeGenerator(service.CodeStrategyCounter, settings.TemplateForRand, "", repo)
+		require.NoError(t, err)
+		// коды, которые счетчик выдаст первыми, уже заняты
+		for _, code := range []string{"AAAAAB", "AAAAAC"} {
+			require.NoError(t, repo.SaveShortURL(ctx, code, "https://ya.ru/"+code, "1", settings.LinkAttributes{}))
+		}
+		return NewHandler(service.NewService(repo, "", service.WithCodeGenerator(codeGen, codeLength)), "")
+	}
+
+	t.Run("single", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://practicum.yandex.ru/")).WithContext(ctx)
+		w := httptest.NewRecorder()
+		newHandler(t).GetShortURL()(w, request)
+
+		res := w.Result()
+		resBody, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "/AAAAAD", string(resBody))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		body := `[{"correlation_id":"1","original_url":"https://practicum.yandex.ru/1"},` +
+			`{"correlation_id":"2","original_url":"https://practicum.yandex.ru/2"},` +
+			`{"correlation_id":"3","original_url":"https://ya.ru/AAAAAB"}]`
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		newHandler(t).GetShortURLs()(w, request)
+
+		res := w.Result()
+		defer res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		var output []struct {
+			CorrelationID string `json:"correlation_id"`
+			ShortURL      string `json:"short_url"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+		shortURLs := make(map[string]string)
+		for _, o := range output {
+			shortURLs[o.CorrelationID] = o.ShortURL
+		}
+		require.Len(t, shortURLs, 3)
+		assert.NotEqual(t, shortURLs["1"], shortURLs["2"])
+		for _, id := range []string{"1", "2"} {
+			assert.NotContains(t, []string{"/AAAAAB", "/AAAAAC"}, shortURLs[id])
+		}
+		// повтор оригинального урла получает существующую ссылку, а не ошибку для всего пакета
+		assert.Equal(t, "/AAAAAB", shortURLs["3"])
+	})
+	t.Run("batch with taken alias", func(t *testing.T) {
+		fileRepo, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "urls.json"), settings.DedupGlobal)
+		require.NoError(t, err)
+		defer fileRepo.Close()
+		repos := map[string]service.Repository{
+			"memory": storage.NewLocalCahce(settings.DedupGlobal),
+			"file":   fileRepo,
+		}
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				require.NoError(t, repo.SaveShortURL(ctx, "taken", "https://ya.ru/taken", "1", settings.LinkAttributes{}))
+				body := `[{"correlation_id":"1","original_url":"https://practicum.yandex.ru/1"},` +
+					`{"correlation_id":"2","original_url":"https://practicum.yandex.ru/2","alias":"free"},` +
+					`{"correlation_id":"3","original_url":"https://practicum.yandex.ru/3","alias":"taken"}]`
+				request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body)).WithContext(ctx)
+				w := httptest.NewRecorder()
+				NewHandler(service.NewService(repo, ""), "").GetShortURLs()(w, request)
+
+				res := w.Result()
+				res.Body.Close()
+				assert.Equal(t, http.StatusConflict, res.StatusCode)
+				// пакет не сохраняется частично
+				_, _, err := repo.GetOriginalURL(ctx, "free", nil)
+				assert.ErrorIs(t, err, settings.ErrOriginalURLNotFound)
+				_, err = repo.GetShortURL(ctx, "https://practicum.yandex.ru/1", "123")
+				assert.ErrorIs(t, err, settings.ErrOriginalURLNotFound)
+
+				// алиас на уже сокращенный адрес не заменяется существующей ссылкой
+				body = `[{"correlation_id":"1","original_url":"https://practicum.yandex.ru/1"},` +
+					`{"correlation_id":"2","original_url":"https://ya.ru/taken","alias":"fresh"}]`
+				request = httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body)).WithContext(ctx)
+				w = httptest.NewRecorder()
+				NewHandler(service.NewService(repo, ""), "").GetShortURLs()(w, request)
+				res = w.Result()
+				res.Body.Close()
+				assert.Equal(t, http.StatusConflict, res.StatusCode)
+				_, _, err = repo.GetOriginalURL(ctx, "fresh", nil)
+				assert.ErrorIs(t, err, settings.ErrOriginalURLNotFound)
+				_, err = repo.GetShortURL(ctx, "https://practicum.yandex.ru/1", "123")
+				assert.ErrorIs(t, err, settings.ErrOriginalURLNotFound)
+			})
+		}
+	})
+}
+
 func TestGetOriginalURL(t *testing.T) {
 	ctx := context.Background()
-	repo := storage.NewLocalCahce()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
 	type want struct {
 		code         int
 		responseBody string
@@ -103,6 +388,7 @@ func TestGetOriginalURL(t *testing.T) {
 		shortURL    string
 		originalURL string
 		userID      string
+		attrs       settings.LinkAttributes
 		want        want
 	}{
 		{
@@ -123,11 +409,33 @@ func TestGetOriginalURL(t *testing.T) {
 				responseBody: settings.ErrOriginalURLNotFound.Error(),
 			},
 		},
+		{
+			name:        "negative test #2 - expired",
+			shortURL:    "expired1",
+			originalURL: "https://practicum.yandex.ru/expired",
+			userID:      "123",
+			attrs:       settings.LinkAttributes{ExpiresAt: time.Now().Add(-time.Minute)},
+			want: want{
+				code:         http.StatusGone,
+				responseBody: storage.ErrRecordExpired.Error(),
+			},
+		},
+		{
+			name:        "negative test #3 - click limit reached",
+			shortURL:    "oneTime1",
+			originalURL: "https://practicum.yandex.ru/invite",
+			userID:      "123",
+			attrs:       settings.LinkAttributes{MaxClicks: 1, Clicks: 1},
+			want: want{
+				code:         http.StatusGone,
+				responseBody: storage.ErrClickLimitReached.Error(),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if len(tt.shortURL) > 0 {
-				repo.SaveShortURL(ctx, tt.shortURL, tt.originalURL, tt.userID)
+				repo.SaveShortURL(ctx, tt.shortURL, tt.originalURL, tt.userID, tt.attrs)
 			}
 
 			request := httptest.NewRequest(http.MethodGet, "/"+tt.shortURL, nil).
@@ -150,9 +458,963 @@ func TestGetOriginalURL(t *testing.T) {
 	}
 }
 
+// geoResolverFunc - функция, реализующая service.GeoResolver.
+type geoResolverFunc func(ip string) settings.GeoLocation
+
+func (f geoResolverFunc) Lookup(ip string) settings.GeoLocation {
+	return f(ip)
+}
+
+func TestGetOriginalURLRecordsClick(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "clicked1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{}))
+	clickRepo := storage.NewClickCache()
+	var lookedUp []string
+	geo := geoResolverFunc(func(ip string) settings.GeoLocation {
+		lookedUp = append(lookedUp, ip)
+		if ip == "198.51.100.7" {
+			return settings.GeoLocation{Country: "RU", Region: "MOW", City: "Moscow"}
+		}
+		return settings.GeoLocation{}
+	})
+	service := service.NewService(repo, "", service.WithClickRepository(clickRepo), service.WithGeoResolver(geo))
+	go service.HandleClicks()
+	handler := NewHandler(service, "10.0.0.0/8")
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		realIP       string
+		forwardedFor string
+		wantIP       string
+		wantGeo      settings.GeoLocation
+	}{
+		{name: "direct client", remoteAddr: "192.0.2.1:1234", realIP: "198.51.100.7", wantIP: "192.0.2.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.5:1234", realIP: "198.51.100.7", wantIP: "198.51.100.7",
+			wantGeo: settings.GeoLocation{Country: "RU", Region: "MOW", City: "Moscow"}},
+		// первый адрес цепочки подставлен клиентом, клиентом считается адрес, от которого запрос принял прокси
+		{name: "forwarded chain", remoteAddr: "10.0.0.5:1234", forwardedFor: "203.0.113.9, 198.51.100.7, 10.0.0.6", wantIP: "198.51.100.7",
+			wantGeo: settings.GeoLocation{Country: "RU", Region: "MOW", City: "Moscow"}},
+		{name: "forwarded direct client", remoteAddr: "192.0.2.1:1234", forwardedFor: "198.51.100.7", wantIP: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/clicked1", nil)
+		request.RemoteAddr = tt.remoteAddr
+		request.Header.Set("X-Real-IP", tt.realIP)
+		request.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		request.Header.Set("Referer", "https://ya.ru/")
+		request.Header.Set("User-Agent", "test-agent")
+		request.Header.Set("Accept-Language", "ru-RU")
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, request)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Result().StatusCode)
+	}
+	service.CloseClicks()
+
+	events := clickRepo.Clicks["clicked1"]
+	require.Len(t, events, len(tests))
+	require.Len(t, lookedUp, len(tests))
+	for i, tt := range tests {
+		assert.Equal(t, tt.wantIP, lookedUp[i], tt.name)
+		assert.Empty(t, events[i].IP, tt.name)
+		assert.Equal(t, tt.wantGeo, settings.GeoLocation{Country: events[i].Country, Region: events[i].Region, City: events[i].City}, tt.name)
+		assert.Equal(t, "https://ya.ru/", events[i].Referer)
+		assert.Equal(t, "test-agent", events[i].UserAgent)
+		assert.Equal(t, "ru-RU", events[i].AcceptLanguage)
+		assert.False(t, events[i].Time.IsZero())
+	}
+}
+
+func TestClickLimit(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt")
+	repo, err := storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	const limitedURL = "https://example.com/limited"
+	require.NoError(t, repo.SaveShortURL(ctx, "limited1", limitedURL, "123", settings.LinkAttributes{MaxClicks: 1}))
+	require.NoError(t, repo.SaveShortURL(ctx, "unlimited1", "https://example.com/unlimited", "123", settings.LinkAttributes{}))
+	service := service.NewService(repo, "")
+	handler := NewHandler(service, "")
+	redirect := func(shortURL string) int {
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, httptest.NewRequest(http.MethodGet, "/"+shortURL, nil))
+		res := w.Result()
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect("unlimited1"))
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect("unlimited1"))
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect("limited1"))
+	assert.Equal(t, http.StatusGone, redirect("limited1"))
+	// в файл пишутся все удачные переходы, отказ по исчерпанному лимиту не пишется
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), `"click":true`))
+
+	// исчерпанная ссылка не удерживает адрес: новая ссылка на него создается, а не возвращается старая с 409
+	shortURL, err := service.GetShortURL(ctx, limitedURL, "123", settings.LinkOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, "/limited1", shortURL)
+	require.NoError(t, repo.Close())
+
+	repo, err = storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	defer repo.Close()
+	_, _, err = repo.GetOriginalURL(ctx, "limited1", nil)
+	assert.ErrorIs(t, err, storage.ErrClickLimitReached)
+	_, attrs, err := repo.GetLink(ctx, "unlimited1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), attrs.Clicks)
+	existing, err := repo.GetShortURL(ctx, limitedURL, "123")
+	require.NoError(t, err)
+	assert.Equal(t, shortURL, "/"+existing)
+}
+
+func TestGetLinkStats(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "stats1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{}))
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	service := service.NewService(repo, "", service.WithClickRepository(storage.NewClickCache()))
+	go service.HandleClicks()
+	for _, event := range []settings.ClickEvent{
+		{Time: start.Add(5 * time.Minute), ShortURL: "stats1", IP: "192.0.2.1", UserAgent: "ua1", Referer: "https://ya.ru/"},
+		{Time: start.Add(10 * time.Minute), ShortURL: "stats1", IP: "192.0.2.1", UserAgent: "ua1", Referer: "https://ya.ru/"},
+		{Time: start.Add(70 * time.Minute), ShortURL: "stats1", IP: "192.0.2.2", UserAgent: "ua2"},
+		{Time: start.Add(-time.Hour), ShortURL: "stats1", IP: "192.0.2.3", UserAgent: "ua3"},
+	} {
+		service.RecordClick(event)
+	}
+	service.CloseClicks()
+	handler := NewHandler(service, "")
+
+	query := "?bucket=hour&from=" + start.Format(time.RFC3339) + "&to=" + start.Add(3*time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name   string
+		path   string
+		userID string
+		code   int
+	}{
+		{name: "owner", path: "/api/user/urls/stats1/stats" + query, userID: "123", code: http.StatusOK},
+		{name: "another user", path: "/api/user/urls/stats1/stats" + query, userID: "456", code: http.StatusForbidden},
+		{name: "unknown link", path: "/api/user/urls/unknown/stats" + query, userID: "123", code: http.StatusNotFound},
+		{name: "bad bucket", path: "/api/user/urls/stats1/stats?bucket=week", userID: "123", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil).
+				WithContext(context.WithValue(context.Background(), middleware.UserIDContextKey{}, tt.userID))
+			w := httptest.NewRecorder()
+			handler.GetLinkStats()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, tt.code, res.StatusCode)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var output struct {
+				TotalClicks    int64 `json:"total_clicks"`
+				UniqueVisitors int64 `json:"unique_visitors"`
+				Series         []struct {
+					Time   time.Time `json:"time"`
+					Clicks int64     `json:"clicks"`
+				} `json:"series"`
+				TopReferrers []struct {
+					Value string `json:"value"`
+					Count int64  `json:"count"`
+				} `json:"top_referrers"`
+			}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+			assert.Equal(t, int64(3), output.TotalClicks)
+			// уникальные посетители считаются по дневным скетчам, поэтому учитывается и переход до начала интервала
+			assert.Equal(t, int64(3), output.UniqueVisitors)
+			require.Len(t, output.Series, 3)
+			assert.Equal(t, []int64{2, 1, 0}, []int64{output.Series[0].Clicks, output.Series[1].Clicks, output.Series[2].Clicks})
+			require.Len(t, output.TopReferrers, 1)
+			assert.Equal(t, "https://ya.ru/", output.TopReferrers[0].Value)
+			assert.Equal(t, int64(2), output.TopReferrers[0].Count)
+		})
+	}
+}
+
+func TestClickRetention(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt.clicks")
+	clickRepo, err := storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "retained1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{}))
+	service := service.NewService(repo, "", service.WithClickRepository(clickRepo), service.WithClickRetention(30*24*time.Hour))
+
+	now := time.Now()
+	service.RecordClick(settings.ClickEvent{Time: now.Add(-40 * 24 * time.Hour), ShortURL: "retained1", IP: "192.0.2.1"})
+	service.RecordClick(settings.ClickEvent{Time: now, ShortURL: "retained1", IP: "192.0.2.2"})
+	// HandleClicks не запускался: накопленные события сохраняются при закрытии
+	service.CloseClicks()
+	service.CloseClicks()
+	// после закрытия события отбрасываются без паники
+	service.RecordClick(settings.ClickEvent{Time: now, ShortURL: "retained1"})
+
+	deleted, err := service.DeleteOldClicks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	require.NoError(t, clickRepo.Close())
+
+	// файл переписан без удаленных событий и скетчей
+	clickRepo, err = storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+	defer clickRepo.Close()
+	stats, err := clickRepo.GetClickStats(ctx, "retained1", settings.StatsQuery{From: now.Add(-365 * 24 * time.Hour), To: now.Add(time.Hour), Bucket: settings.StatsBucketDay, Top: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks)
+	visitors, err := clickRepo.GetVisitorSketch(ctx, "retained1", now.Add(-365*24*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), visitors.Estimate())
+}
+
+func TestClickFileSketches(t *testing.T) {
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "urls.txt.clicks")
+	clickRepo, err := storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-24 * time.Hour)
+	sketchLines := func() int {
+		data, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		return strings.Count(string(data), `"sketch"`)
+	}
+	merge := func(day time.Time, visitor string) {
+		sketch := hll.New()
+		sketch.AddString(visitor)
+		key := settings.VisitorSketchKey{ShortURL: "sketched1", Day: day}
+		require.NoError(t, clickRepo.MergeVisitorSketches(ctx, map[settings.VisitorSketchKey]*hll.Sketch{key: sketch}))
+	}
+	for i := range 3 {
+		merge(yesterday, "visitor"+strconv.Itoa(i))
+	}
+	// скетчи текущего дня копятся в памяти
+	assert.Equal(t, 0, sketchLines())
+	// наступление следующего дня записывает скетч прошедшего один раз
+	merge(today, "visitor3")
+	assert.Equal(t, 1, sketchLines())
+	merge(today, "visitor4")
+	assert.Equal(t, 1, sketchLines())
+	require.NoError(t, clickRepo.Close())
+	assert.Equal(t, 2, sketchLines())
+
+	clickRepo, err = storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+	defer clickRepo.Close()
+	visitors, err := clickRepo.GetVisitorSketch(ctx, "sketched1", yesterday, today.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), visitors.Estimate())
+}
+
+func TestGetQRCode(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "qrcode1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{}))
+	require.NoError(t, repo.SaveShortURL(ctx, "deleted1", "https://practicum.yandex.ru/old", "123", settings.LinkAttributes{}))
+	require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: "deleted1", UserID: "123"}))
+	handler := NewHandler(service.NewService(repo, "http://localhost:8080"), "")
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		code        int
+		contentType string
+	}{
+		{name: "png", path: "/qrcode1/qr?size=128&level=H&margin=0", code: http.StatusOK, contentType: "image/png"},
+		{name: "svg by accept", path: "/qrcode1/qr", accept: "image/svg+xml", code: http.StatusOK, contentType: "image/svg+xml"},
+		{name: "bad level", path: "/qrcode1/qr?level=X", code: http.StatusBadRequest},
+		{name: "unknown link", path: "/unknown1/qr", code: http.StatusNotFound},
+		{name: "deleted link", path: "/deleted1/qr", code: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			request.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			handler.GetQRCode()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, tt.code, res.StatusCode)
+			if tt.code != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.contentType, res.Header.Get("content-type"))
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			if tt.contentType == "image/png" {
+				img, err := png.Decode(bytes.NewReader(resBody))
+				require.NoError(t, err)
+				assert.Equal(t, 128, img.Bounds().Dx())
+			} else {
+				assert.True(t, strings.HasPrefix(string(resBody), "<svg"))
+			}
+		})
+	}
+}
+
+func TestGetLinkPreview(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.SaveShortURL(ctx, "internal1", "http://localhost:8080/docs", "123", settings.LinkAttributes{CreatedAt: createdAt}))
+	require.NoError(t, repo.SaveShortURL(ctx, "external1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{CreatedAt: createdAt}))
+	require.NoError(t, repo.SaveShortURL(ctx, "confirm1", "http://localhost:8080/news", "123", settings.LinkAttributes{Interstitial: true}))
+	handler := NewHandler(service.NewService(repo, "http://localhost:8080", service.WithInterstitial(settings.InterstitialExternal)), "")
+
+	tests := []struct {
+		name     string
+		path     string
+		code     int
+		location string
+		contains string
+	}{
+		{name: "preview suffix", path: "/internal1+", code: http.StatusOK, contains: "http://localhost:8080/docs"},
+		{name: "preview param", path: "/internal1?preview=1", code: http.StatusOK, contains: "2024-05-01 12:00 UTC"},
+		{name: "internal redirect", path: "/internal1", code: http.StatusTemporaryRedirect, location: "http://localhost:8080/docs"},
+		{name: "external interstitial", path: "/external1", code: http.StatusOK, contains: `action="/external1"`},
+		// параметр запроса не подтверждает переход
+		{name: "external confirm param", path: "/external1?confirm=1", code: http.StatusOK, contains: `name="confirm"`},
+		{name: "link interstitial", path: "/confirm1", code: http.StatusOK, contains: "http://localhost:8080/news"},
+		{name: "unknown link", path: "/unknown1+", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.GetOriginalURL()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, tt.code, res.StatusCode)
+			assert.Equal(t, tt.location, res.Header.Get("Location"))
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(resBody), tt.contains)
+		})
+	}
+	// переход подтверждается формой промежуточной страницы
+	request := httptest.NewRequest(http.MethodPost, "/external1", strings.NewReader("confirm=1"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.GetProtectedOriginalURL()(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "https://practicum.yandex.ru/", res.Header.Get("Location"))
+
+	// предпросмотр и промежуточная страница не засчитывают переходы
+	_, attrs, err := repo.GetLink(ctx, "external1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), attrs.Clicks)
+
+	// предпросмотр показывает переходы по ссылке без лимита
+	w = httptest.NewRecorder()
+	handler.GetOriginalURL()(w, httptest.NewRequest(http.MethodGet, "/internal1+", nil))
+	assert.Contains(t, w.Body.String(), "<dt>Переходов</dt><dd>1</dd>")
+}
+
+func TestGetUserURLsPagination(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	now := time.Now()
+	for i, originalURL := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c", "https://docs.example.com/d", "https://example.com/e"} {
+		attrs := settings.LinkAttributes{CreatedAt: now.Add(time.Duration(i-10) * time.Minute), Clicks: int64(10 - i)}
+		if i == 4 {
+			attrs.ExpiresAt = now.Add(-time.Minute)
+		}
+		require.NoError(t, repo.SaveShortURL(ctx, "page"+strconv.Itoa(i), originalURL, "123", attrs))
+	}
+	require.NoError(t, repo.SaveShortURL(ctx, "other", "https://example.com/other", "456", settings.LinkAttributes{}))
+	require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: "page2", UserID: "123"}))
+	handler := NewHandler(service.NewService(repo, ""), "")
+
+	list := func(query string) (int, []string, string) {
+		request := httptest.NewRequest(http.MethodGet, "/api/user/urls?"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler.GetUserURLs()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return res.StatusCode, nil, ""
+		}
+		var output []struct {
+			ShortURL string `json:"short_url"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+		var shortURLs []string
+		for _, o := range output {
+			shortURLs = append(shortURLs, strings.TrimPrefix(o.ShortURL, "/"))
+		}
+		return res.StatusCode, shortURLs, res.Header.Get("X-Next-Cursor")
+	}
+
+	// по умолчанию от новых к старым без помеченных на удаление
+	code, page, cursor := list("limit=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"page4", "page3"}, page)
+	require.NotEmpty(t, cursor)
+	_, page, cursor = list("limit=2&cursor=" + cursor)
+	assert.Equal(t, []string{"page1", "page0"}, page)
+	assert.Empty(t, cursor)
+
+	_, page, _ = list("sort=clicks&order=asc&expired=exclude")
+	assert.Equal(t, []string{"page3", "page1", "page0"}, page)
+	// переходы по ссылкам без лимита меняют порядок сортировки по переходам
+	for range 4 {
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, httptest.NewRequest(http.MethodGet, "/page3", nil))
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	}
+	_, page, _ = list("sort=clicks&order=asc&expired=exclude")
+	assert.Equal(t, []string{"page1", "page0", "page3"}, page)
+	_, page, _ = list("deleted=only")
+	assert.Equal(t, []string{"page2"}, page)
+	_, page, _ = list("expired=only")
+	assert.Equal(t, []string{"page4"}, page)
+	_, page, _ = list("q=DOCS.example")
+	assert.Equal(t, []string{"page3"}, page)
+
+	_, _, cursor = list("limit=1")
+	code, _, _ = list("limit=1&sort=clicks&cursor=" + cursor)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = list("sort=name")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = list("limit=5000")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// без limit выдаются все ссылки, как до постраничной выдачи
+	for i := range 150 {
+		require.NoError(t, repo.SaveShortURL(ctx, "bulk"+strconv.Itoa(i), "https://example.com/bulk"+strconv.Itoa(i), "123", settings.LinkAttributes{}))
+	}
+	_, page, cursor = list("")
+	assert.Len(t, page, 154)
+	assert.Empty(t, cursor)
+}
+
+func TestURLPolicy(t *testing.T) {
+	dir := t.TempDir()
+	blocklist := filepath.Join(dir, "blocklist.txt")
+	allowlist := filepath.Join(dir, "allowlist.txt")
+	require.NoError(t, os.WriteFile(blocklist, []byte("# phishing\nevil.example.com\n.malware.test\nre:/login\\.php$\n"), 0666))
+	require.NoError(t, os.WriteFile(allowlist, []byte("*.example.com\n.malware.test\npracticum.yandex.ru\n"), 0666))
+	urlPolicy, err := policy.NewEngine(blocklist, allowlist)
+	require.NoError(t, err)
+
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	handler := NewHandler(service.NewService(repo, "", service.WithURLPolicy(urlPolicy)), "")
+	shorten := func(originalURL string) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL)).
+			WithContext(context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123"))
+		w := httptest.NewRecorder()
+		handler.GetShortURL()(w, request)
+		return w.Result()
+	}
+
+	tests := []struct {
+		name        string
+		originalURL string
+		code        int
+	}{
+		{name: "allowed", originalURL: "https://practicum.yandex.ru/", code: http.StatusCreated},
+		{name: "allowed wildcard", originalURL: "https://docs.example.com/", code: http.StatusCreated},
+		{name: "blocked exact", originalURL: "https://evil.example.com/", code: http.StatusForbidden},
+		{name: "blocked suffix", originalURL: "https://cdn.malware.test/", code: http.StatusForbidden},
+		{name: "blocked regexp", originalURL: "https://docs.example.com/login.php", code: http.StatusForbidden},
+		{name: "not in allowlist", originalURL: "https://google.com/", code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := shorten(tt.originalURL)
+			defer res.Body.Close()
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+
+	res := shorten("https://docs.example.com/")
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	shortURL, err := url.Parse(string(resBody))
+	require.NoError(t, err)
+
+	// после блокировки домена существующая ссылка перестает работать
+	require.NoError(t, os.WriteFile(blocklist, []byte("docs.example.com\n"), 0666))
+	require.NoError(t, urlPolicy.Reload())
+	request := httptest.NewRequest(http.MethodGet, shortURL.Path, nil)
+	w := httptest.NewRecorder()
+	handler.GetOriginalURL()(w, request)
+	redirect := w.Result()
+	defer redirect.Body.Close()
+	assert.Equal(t, http.StatusForbidden, redirect.StatusCode)
+}
+
+type healthCheckerFunc func(originalURL string) (int, error)
+
+func (f healthCheckerFunc) Check(ctx context.Context, originalURL string) (int, error) {
+	return f(originalURL)
+}
+
+func TestLinkHealth(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "alive1", "https://practicum.yandex.ru/", "123", settings.LinkAttributes{}))
+	require.NoError(t, repo.SaveShortURL(ctx, "dead1", "https://practicum.yandex.ru/gone", "123",
+		settings.LinkAttributes{FallbackURL: "https://practicum.yandex.ru/"}))
+	require.NoError(t, repo.SaveShortURL(ctx, "private1", "https://practicum.yandex.ru/private", "123",
+		settings.LinkAttributes{FallbackURL: "https://practicum.yandex.ru/"}))
+	checker := healthCheckerFunc(func(originalURL string) (int, error) {
+		switch {
+		case strings.HasSuffix(originalURL, "/gone"):
+			return http.StatusServiceUnavailable, nil
+		case strings.HasSuffix(originalURL, "/private"):
+			// 4xx не считается недоступностью
+			return http.StatusForbidden, nil
+		}
+		return http.StatusOK, nil
+	})
+	service := service.NewService(repo, "", service.WithHealthChecker(checker, time.Nanosecond))
+	handler := NewHandler(service, "")
+	redirect := func() string {
+		request := httptest.NewRequest(http.MethodGet, "/dead1", nil)
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		return res.Header.Get("Location")
+	}
+
+	for i := 1; i <= settings.DeadLinkFailures; i++ {
+		assert.Equal(t, "https://practicum.yandex.ru/gone", redirect(), "check %d", i)
+		checked, err := service.CheckLinksHealth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, checked)
+	}
+	// после DeadLinkFailures неудачных проверок подряд ссылка ведет на запасной адрес
+	assert.Equal(t, "https://practicum.yandex.ru/", redirect())
+
+	request := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.GetUserURLs()(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var output []struct {
+		ShortURL string `json:"short_url"`
+		Health   struct {
+			Status    int       `json:"status"`
+			CheckedAt time.Time `json:"checked_at"`
+			Failures  int       `json:"failures"`
+			Dead      bool      `json:"dead"`
+		} `json:"health"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+	require.Len(t, output, 3)
+	for _, link := range output {
+		assert.False(t, link.Health.CheckedAt.IsZero())
+		switch link.ShortURL {
+		case "/dead1":
+			assert.Equal(t, http.StatusServiceUnavailable, link.Health.Status)
+			assert.Equal(t, settings.DeadLinkFailures, link.Health.Failures)
+			assert.True(t, link.Health.Dead)
+		case "/private1":
+			assert.Equal(t, http.StatusForbidden, link.Health.Status)
+			assert.Zero(t, link.Health.Failures)
+			assert.False(t, link.Health.Dead)
+		default:
+			assert.Equal(t, http.StatusOK, link.Health.Status)
+			assert.Zero(t, link.Health.Failures)
+			assert.False(t, link.Health.Dead)
+		}
+	}
+
+	// проверка не обращается к адресам внутренней сети, в том числе к метаданным облака
+	for _, internal := range []string{"http://127.0.0.1:1/", "http://169.254.169.254/latest/meta-data/", "http://[::1]:1/"} {
+		_, err := health.NewChecker().Check(ctx, internal)
+		assert.ErrorIs(t, err, netguard.ErrForbiddenAddress, internal)
+	}
+}
+
+func TestQueryPassthrough(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	utm := settings.UTMParams{Source: "shortener", Medium: "link"}
+	links := map[string]settings.LinkAttributes{
+		"off1":      {},
+		"merge1":    {QueryPassthrough: settings.QueryPassthroughMerge},
+		"preserve1": {QueryPassthrough: settings.QueryPassthroughPreserve},
+		"utm1":      {QueryPassthrough: settings.QueryPassthroughMerge, UTM: utm},
+	}
+	for shortURL, attrs := range links {
+		require.NoError(t, repo.SaveShortURL(ctx, shortURL, "https://practicum.yandex.ru/?lang=ru&ref=site", "123", attrs))
+	}
+	require.NoError(t, repo.SaveShortURL(ctx, "raw1", "https://practicum.yandex.ru/?z=1&path=%7Euser&a=b%20c", "123",
+		settings.LinkAttributes{QueryPassthrough: settings.QueryPassthroughMerge}))
+	service := service.NewService(repo, "")
+	handler := NewHandler(service, "")
+
+	tests := []struct {
+		name     string
+		path     string
+		location string
+	}{
+		{name: "off", path: "/off1?utm_source=mail", location: "https://practicum.yandex.ru/?lang=ru&ref=site"},
+		{name: "merge", path: "/merge1?utm_source=mail&ref=mail", location: "https://practicum.yandex.ru/?lang=ru&ref=mail&utm_source=mail"},
+		{name: "preserve", path: "/preserve1?utm_source=mail&ref=mail", location: "https://practicum.yandex.ru/?lang=ru&ref=site&utm_source=mail"},
+		{name: "utm defaults", path: "/utm1?utm_source=mail&confirm=1", location: "https://practicum.yandex.ru/?lang=ru&ref=site&utm_medium=link&utm_source=mail"},
+		// исходные параметры адреса назначения не пересортировываются и не перекодируются
+		{name: "raw destination query", path: "/raw1?utm_source=mail&z=2", location: "https://practicum.yandex.ru/?path=%7Euser&a=b%20c&utm_source=mail&z=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.GetOriginalURL()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+			assert.Equal(t, tt.location, res.Header.Get("Location"))
+		})
+	}
+
+	// параметры передаются и после ввода пароля: форма отправляется на адрес короткой ссылки с параметрами
+	_, err := service.GetShortURL(ctx, "https://practicum.yandex.ru/?lang=ru", "123",
+		settings.LinkOptions{Alias: "protected1", Password: "p@ss", QueryPassthrough: string(settings.QueryPassthroughMerge)})
+	require.NoError(t, err)
+	protected := httptest.NewRequest(http.MethodPost, "/protected1?utm_source=mail", strings.NewReader("password=p%40ss"))
+	protected.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.GetProtectedOriginalURL()(w, protected)
+	protectedRes := w.Result()
+	defer protectedRes.Body.Close()
+	require.Equal(t, http.StatusSeeOther, protectedRes.StatusCode)
+	assert.Equal(t, "https://practicum.yandex.ru/?lang=ru&utm_source=mail", protectedRes.Header.Get("Location"))
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://practicum.yandex.ru/","query_passthrough":"append"}`)).
+		WithContext(context.WithValue(ctx, middleware.UserIDContextKey{}, "123"))
+	w = httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRedirectStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	links := map[string]settings.LinkAttributes{
+		"default1":   {},
+		"permanent1": {RedirectStatus: http.StatusMovedPermanently},
+		"found1":     {RedirectStatus: http.StatusFound},
+		"limited1":   {RedirectStatus: http.StatusPermanentRedirect, MaxClicks: 10},
+		"expiring1":  {RedirectStatus: http.StatusMovedPermanently, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for shortURL, attrs := range links {
+		require.NoError(t, repo.SaveShortURL(ctx, shortURL, "https://practicum.yandex.ru/", "123", attrs))
+	}
+	handler := NewHandler(service.NewService(repo, "", service.WithRedirectStatus(http.StatusFound),
+		service.WithRedirectCacheMaxAge(24*time.Hour)), "")
+
+	tests := []struct {
+		name         string
+		path         string
+		code         int
+		cacheControl string
+	}{
+		{name: "default", path: "/default1", code: http.StatusFound, cacheControl: "no-store"},
+		{name: "permanent", path: "/permanent1", code: http.StatusMovedPermanently, cacheControl: "public, max-age=86400"},
+		{name: "temporary", path: "/found1", code: http.StatusFound, cacheControl: "no-store"},
+		{name: "permanent with click limit", path: "/limited1", code: http.StatusPermanentRedirect, cacheControl: "no-store"},
+		{name: "permanent with expiration", path: "/expiring1", code: http.StatusMovedPermanently, cacheControl: "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.GetOriginalURL()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.code, res.StatusCode)
+			assert.Equal(t, tt.cacheControl, res.Header.Get("Cache-Control"))
+		})
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://practicum.yandex.ru/","redirect_status":303}`)).
+		WithContext(context.WithValue(ctx, middleware.UserIDContextKey{}, "123"))
+	w := httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRoutingRules(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	geo := geoResolverFunc(func(ip string) settings.GeoLocation {
+		if ip == "198.51.100.7" {
+			return settings.GeoLocation{Country: "DE"}
+		}
+		return settings.GeoLocation{}
+	})
+	handler := NewHandler(service.NewService(repo, "", service.WithGeoResolver(geo)), "")
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{
+		"url": "https://example.com/",
+		"alias": "app",
+		"routing": [
+			{"platform": "iOS", "url": "https://apps.apple.com/app/id1"},
+			{"platform": "android", "url": "https://play.google.com/store/apps/details?id=app"},
+			{"country": "de", "url": "https://example.de/"},
+			{"language": "ru", "url": "https://example.com/ru/"}
+		]
+	}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res := w.Result()
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	tests := []struct {
+		name           string
+		userAgent      string
+		acceptLanguage string
+		remoteAddr     string
+		location       string
+	}{
+		{name: "ios", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", location: "https://apps.apple.com/app/id1"},
+		{name: "android", userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8)", location: "https://play.google.com/store/apps/details?id=app"},
+		{name: "country", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", remoteAddr: "198.51.100.7:1234", location: "https://example.de/"},
+		{name: "language", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)", acceptLanguage: "en;q=0.5, ru-RU", location: "https://example.com/ru/"},
+		{name: "default", userAgent: "Mozilla/5.0 (X11; Linux x86_64)", acceptLanguage: "ru;q=0.5, en", location: "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/app", nil)
+			request.Header.Set("User-Agent", tt.userAgent)
+			request.Header.Set("Accept-Language", tt.acceptLanguage)
+			if tt.remoteAddr != "" {
+				request.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			handler.GetOriginalURL()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+			assert.Equal(t, tt.location, res.Header.Get("Location"))
+		})
+	}
+
+	// gRPC берет User-Agent и Accept-Language из метаданных, а адрес клиента - из соединения
+	// или из x-real-ip, если соединение пришло из доверенной подсети
+	grpcServer := grpcapi.NewShortenerServer(service.NewService(repo, "", service.WithGeoResolver(geo)), "10.0.0.0/8")
+	grpcTests := []struct {
+		name     string
+		md       grpcmetadata.MD
+		peerAddr string
+		location string
+	}{
+		{name: "grpc ios", md: grpcmetadata.Pairs("user-agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"), peerAddr: "192.0.2.1:5000", location: "https://apps.apple.com/app/id1"},
+		{name: "grpc language", md: grpcmetadata.Pairs("accept-language", "ru-RU"), peerAddr: "192.0.2.1:5000", location: "https://example.com/ru/"},
+		{name: "grpc peer country", md: grpcmetadata.MD{}, peerAddr: "198.51.100.7:5000", location: "https://example.de/"},
+		{name: "grpc trusted real ip", md: grpcmetadata.Pairs("x-real-ip", "198.51.100.7"), peerAddr: "10.0.0.2:5000", location: "https://example.de/"},
+		{name: "grpc untrusted real ip", md: grpcmetadata.Pairs("x-real-ip", "198.51.100.7"), peerAddr: "192.0.2.1:5000", location: "https://example.com/"},
+	}
+	for _, tt := range grpcTests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tt.peerAddr)
+			require.NoError(t, err)
+			ctx := peer.NewContext(grpcmetadata.NewIncomingContext(context.Background(), tt.md), &peer.Peer{Addr: addr})
+			resp, err := grpcServer.GetOriginalURL(ctx, &grpcapi.GetOriginalURLRequest{ShortURL: "app"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.location, resp.OriginalURL)
+		})
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://example.com/","routing":[{"platform":"symbian","url":"https://example.com/s"}]}`)).
+		WithContext(ctx)
+	w = httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res = w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestVariants(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	clickRepo := storage.NewClickCache()
+	service := service.NewService(repo, "", service.WithClickRepository(clickRepo))
+	go service.HandleClicks()
+	handler := NewHandler(service, "")
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{
+		"url": "https://example.com/",
+		"alias": "split",
+		"variants": [
+			{"url": "https://example.com/a", "weight": 1},
+			{"name": "green", "url": "https://example.com/green", "weight": 3}
+		]
+	}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res := w.Result()
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	locations := map[string]string{"a": "https://example.com/a", "green": "https://example.com/green"}
+	redirect := func(cookie *http.Cookie) (string, *http.Cookie) {
+		request := httptest.NewRequest(http.MethodGet, "/split", nil)
+		request.Header.Set("User-Agent", "test-agent")
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+		require.Len(t, res.Cookies(), 1)
+		return res.Header.Get("Location"), res.Cookies()[0]
+	}
+
+	// без cookie вариант определяется хешем IP и User-Agent и не меняется между переходами
+	location, cookie := redirect(nil)
+	assert.Equal(t, "/split", cookie.Path)
+	assert.Equal(t, locations[cookie.Value], location)
+	again, _ := redirect(nil)
+	assert.Equal(t, location, again)
+
+	// cookie закрепляет вариант за клиентом
+	other := "a"
+	if cookie.Value == "a" {
+		other = "green"
+	}
+	location, _ = redirect(&http.Cookie{Name: cookie.Name, Value: other})
+	assert.Equal(t, locations[other], location)
+	service.CloseClicks()
+
+	events := clickRepo.Clicks["split"]
+	require.Len(t, events, 3)
+	assert.Equal(t, other, events[2].Variant)
+
+	request = httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://example.com/","variants":[{"url":"https://example.com/a","weight":1}]}`)).
+		WithContext(ctx)
+	w = httptest.NewRecorder()
+	handler.GetShortURLJSON()(w, request)
+	res = w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestUpdateShortURL(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	require.NoError(t, repo.SaveShortURL(ctx, "edit1", "https://example.com/old", "123", settings.LinkAttributes{}))
+	handler := NewHandler(service.NewService(repo, ""), "")
+
+	call := func(h http.HandlerFunc, method, path, body string, ctx context.Context) (int, []byte) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		result, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, result
+	}
+
+	code, body := call(handler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/edit1",
+		`{"url":"https://example.com/new","max_clicks":5}`, ctx)
+	require.Equal(t, http.StatusOK, code)
+	var version linkVersion
+	require.NoError(t, json.Unmarshal(body, &version))
+	assert.Equal(t, 2, version.Version)
+	assert.Equal(t, "https://example.com/new", version.OriginalURL)
+	assert.Equal(t, int64(5), version.MaxClicks)
+
+	location, _, err := repo.GetOriginalURL(ctx, "edit1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", location)
+
+	code, body = call(handler.GetLinkVersions(), http.MethodGet, "/api/user/urls/edit1/versions", "", ctx)
+	require.Equal(t, http.StatusOK, code)
+	var versions []linkVersion
+	require.NoError(t, json.Unmarshal(body, &versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, "https://example.com/old", versions[0].OriginalURL)
+
+	// откат добавляет новую версию с адресом и атрибутами первой
+	code, body = call(handler.RollbackShortURL(), http.MethodPost, "/api/user/urls/edit1/rollback", `{"version":1}`, ctx)
+	require.Equal(t, http.StatusOK, code)
+	version = linkVersion{}
+	require.NoError(t, json.Unmarshal(body, &version))
+	assert.Equal(t, 3, version.Version)
+	assert.Equal(t, "https://example.com/old", version.OriginalURL)
+	assert.Zero(t, version.MaxClicks)
+
+	code, _ = call(handler.RollbackShortURL(), http.MethodPost, "/api/user/urls/edit1/rollback", `{"version":7}`, ctx)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// версию, основанную на устаревшей, хранилище не применяет
+	err = repo.UpdateLink(ctx, "edit1", settings.LinkVersion{Version: 3, OriginalURL: "https://example.com/stale", CreatedAt: time.Now()}, nil, nil)
+	assert.ErrorIs(t, err, settings.ErrVersionConflict)
+
+	// откат проверяет атрибуты версии: срок действия первой версии уже истек
+	require.NoError(t, repo.SaveShortURL(ctx, "expired1", "https://example.com/expired", "123",
+		settings.LinkAttributes{ExpiresAt: time.Now().Add(-time.Hour)}))
+	code, _ = call(handler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/expired1", `{"expires_at":null}`, ctx)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = call(handler.RollbackShortURL(), http.MethodPost, "/api/user/urls/expired1/rollback", `{"version":1}`, ctx)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	other := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "456")
+	code, _ = call(handler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/edit1", `{"url":"https://example.com/evil"}`, other)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// при дедупликации нельзя перевести ссылку на адрес, который уже сокращен
+	dedupRepo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, dedupRepo.SaveShortURL(ctx, "taken1", "https://example.com/taken", "456", settings.LinkAttributes{}))
+	require.NoError(t, dedupRepo.SaveShortURL(ctx, "edit2", "https://example.com/mine", "123", settings.LinkAttributes{}))
+	dedupHandler := NewHandler(service.NewService(dedupRepo, ""), "")
+	code, _ = call(dedupHandler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/edit2", `{"url":"https://example.com/taken","tags":["new"]}`, ctx)
+	assert.Equal(t, http.StatusConflict, code)
+	location, _, err = dedupRepo.GetOriginalURL(ctx, "edit2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/mine", location)
+	// изменение не применяется частично: теги не поменялись вместе с отклоненной версией
+	_, attrs, err := dedupRepo.GetLink(ctx, "edit2")
+	require.NoError(t, err)
+	assert.Empty(t, attrs.Tags)
+
+	code, _ = call(dedupHandler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/edit2", `{"url":"https://example.com/free","tags":["new"]}`, ctx)
+	require.Equal(t, http.StatusOK, code)
+	_, attrs, err = dedupRepo.GetLink(ctx, "edit2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, attrs.Tags)
+}
+
 func TestGetShortURLJSON(t *testing.T) {
 	ctx := context.Background()
-	repo := storage.NewLocalCahce()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
 	type input struct {
 		URL string `json:"url"`
 	}
@@ -203,16 +1465,187 @@ func TestGetShortURLJSON(t *testing.T) {
 			require.NoError(t, err)
 
 			shortURL := output.Result[len(output.Result)-settings.ShortURLlen:]
-			originalURLFromDB, err := repo.GetOriginalURL(ctx, shortURL)
+			originalURLFromDB, _, err := repo.GetOriginalURL(ctx, shortURL, nil)
 			require.NoError(t, err)
 			assert.Equal(t, tt.want.originalURLFromDB, originalURLFromDB)
 		})
 	}
 }
 
+func TestGetShortURLJSONAlias(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	type input struct {
+		URL   string `json:"url"`
+		Alias string `json:"alias"`
+	}
+	type want struct {
+		code     int
+		shortURL string
+	}
+	tests := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			name:  "positive test #1",
+			input: input{URL: "https://practicum.yandex.ru/", Alias: "q3-report"},
+			want: want{
+				code:     http.StatusCreated,
+				shortURL: "/q3-report",
+			},
+		},
+		{
+			name:  "negative test #1 - alias taken",
+			input: input{URL: "https://practicum.yandex.ru/other", Alias: "q3-report"},
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+		{
+			name:  "negative test #2 - invalid characters",
+			input: input{URL: "https://practicum.yandex.ru/", Alias: "q3 report!"},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:  "negative test #3 - reserved word",
+			input: input{URL: "https://practicum.yandex.ru/", Alias: "api"},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := httptest.NewRecorder().Body
+			data, _ := json.Marshal(&tt.input)
+			body.Write(data)
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten", body).
+				WithContext(context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123"))
+
+			w := httptest.NewRecorder()
+			service := service.NewService(repo, "")
+			handler := NewHandler(service, "")
+			handler.GetShortURLJSON()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.want.code, res.StatusCode)
+			if tt.want.shortURL == "" {
+				return
+			}
+
+			var output struct {
+				Result string `json:"result"`
+			}
+			err := json.NewDecoder(res.Body).Decode(&output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.shortURL, output.Result)
+			originalURLFromDB, _, err := repo.GetOriginalURL(ctx, tt.input.Alias, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.input.URL, originalURLFromDB)
+		})
+	}
+}
+
+func TestGetProtectedOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	service := service.NewService(repo, "")
+	handler := NewHandler(service, "")
+	const originalURL = "https://practicum.yandex.ru/internal"
+	_, err := service.GetShortURL(ctx, originalURL, "123", settings.LinkOptions{Alias: "secret", Password: "p@ss"})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodGet, "/secret", nil)
+	w := httptest.NewRecorder()
+	handler.GetOriginalURL()(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Location"))
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(resBody), `name="password"`)
+
+	type want struct {
+		code     int
+		location string
+	}
+	tests := []struct {
+		name     string
+		password string
+		want     want
+	}{
+		{
+			name:     "negative test #1 - wrong password",
+			password: "wrong",
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:     "positive test #1",
+			password: "p@ss",
+			want: want{
+				code:     http.StatusSeeOther,
+				location: originalURL,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"password": {tt.password}}
+			request := httptest.NewRequest(http.MethodPost, "/secret", strings.NewReader(form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.GetProtectedOriginalURL()(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.want.code, res.StatusCode)
+			assert.Equal(t, tt.want.location, res.Header.Get("Location"))
+		})
+	}
+	// форма пароля и неверный пароль переход не засчитывают
+	_, attrs, err := repo.GetLink(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), attrs.Clicks)
+
+	t.Run("concurrent attempts", func(t *testing.T) {
+		// одновременные запросы не проверяют пароль больше допустимого числа раз,
+		// неудачная попытка до верного ввода пароля по-прежнему учитывается
+		const requests = 20
+		codes := make(chan int, requests)
+		var wg sync.WaitGroup
+		for range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				form := url.Values{"password": {"wrong"}}
+				request := httptest.NewRequest(http.MethodPost, "/secret", strings.NewReader(form.Encode()))
+				request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				w := httptest.NewRecorder()
+				handler.GetProtectedOriginalURL()(w, request)
+				codes <- w.Result().StatusCode
+			}()
+		}
+		wg.Wait()
+		close(codes)
+		counts := make(map[int]int)
+		for code := range codes {
+			counts[code]++
+		}
+		assert.Equal(t, map[int]int{http.StatusForbidden: 4, http.StatusTooManyRequests: requests - 4}, counts)
+	})
+}
+
 func TestMarkRecordsForDeletion(t *testing.T) {
 	ctx := context.Background()
-	repo := storage.NewLocalCahce()
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
 	type want struct {
 		code         int
 		responseBody string
@@ -222,6 +1655,7 @@ func TestMarkRecordsForDeletion(t *testing.T) {
 		shortURL    string
 		originalURL string
 		userID      string
+		attrs       settings.LinkAttributes
 		want        want
 	}{
 		{
@@ -238,7 +1672,7 @@ func TestMarkRecordsForDeletion(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if len(tt.shortURL) > 0 {
-				repo.SaveShortURL(ctx, tt.shortURL, tt.originalURL, tt.userID)
+				repo.SaveShortURL(ctx, tt.shortURL, tt.originalURL, tt.userID, tt.attrs)
 			}
 			var s []string
 			s = append(s, tt.shortURL)
@@ -264,6 +1698,91 @@ func TestMarkRecordsForDeletion(t *testing.T) {
 	}
 }
 
+func TestRestoreShortURL(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt")
+	repo, err := storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveShortURL(ctx, "restore1", "https://example.com/", "123", settings.LinkAttributes{}))
+	service := service.NewService(repo, "", service.WithDeletedRetention(time.Nanosecond), service.WithReservedCodes(true))
+	handler := NewHandler(service, "")
+
+	redirect := func() int {
+		w := httptest.NewRecorder()
+		handler.GetOriginalURL()(w, httptest.NewRequest(http.MethodGet, "/restore1", nil))
+		res := w.Result()
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+	restore := func(ctx context.Context) int {
+		request := httptest.NewRequest(http.MethodPost, "/api/user/urls/restore1/restore", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler.RestoreShortURL()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: "restore1", UserID: "123"}))
+	assert.Equal(t, http.StatusGone, redirect())
+	other := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "456")
+	assert.Equal(t, http.StatusForbidden, restore(other))
+	assert.Equal(t, http.StatusNoContent, restore(ctx))
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect())
+
+	// помеченная повторно ссылка удаляется безвозвратно, а ее код остается занятым и после перезапуска
+	require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: "restore1", UserID: "123"}))
+	deleted, err := service.PurgeDeletedRecords(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, http.StatusNotFound, restore(ctx))
+	require.NoError(t, repo.Close())
+
+	repo, err = storage.NewFileStorage(fileName, settings.DedupGlobal)
+	require.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.GetURLOwner(ctx, "restore1")
+	assert.ErrorIs(t, err, settings.ErrOriginalURLNotFound)
+	assert.ErrorIs(t, repo.SaveShortURL(ctx, "restore1", "https://example.com/", "123", settings.LinkAttributes{}), settings.ErrShortURLNotUnique)
+	// оригинальный урл освобожден для дедупликации
+	require.NoError(t, repo.SaveShortURL(ctx, "restore2", "https://example.com/", "123", settings.LinkAttributes{}))
+}
+
+func TestPurgeDeletesClicks(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt.clicks")
+	clickRepo, err := storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+	repo := storage.NewLocalCahce(settings.DedupGlobal)
+	require.NoError(t, repo.SaveShortURL(ctx, "purged1", "https://example.com/old", "123", settings.LinkAttributes{}))
+	service := service.NewService(repo, "", service.WithClickRepository(clickRepo), service.WithDeletedRetention(time.Nanosecond))
+	now := time.Now()
+	service.RecordClick(settings.ClickEvent{Time: now, ShortURL: "purged1", IP: "192.0.2.1"})
+	service.CloseClicks()
+
+	require.NoError(t, repo.MarkRecordsForDeletion(ctx, settings.Record{ShortURL: "purged1", UserID: "123"}))
+	deleted, err := service.PurgeDeletedRecords(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// освободившийся код занимает новая ссылка другого пользователя без статистики прежней
+	_, err = service.GetShortURL(ctx, "https://example.com/new", "456", settings.LinkOptions{Alias: "purged1"})
+	require.NoError(t, err)
+	q := settings.StatsQuery{From: now.Add(-time.Hour), To: now.Add(time.Hour), Bucket: settings.StatsBucketHour, Top: 10}
+	stats, err := service.GetLinkStats(ctx, "purged1", "456", q)
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Zero(t, stats.UniqueVisitors)
+	require.NoError(t, clickRepo.Close())
+
+	clickRepo, err = storage.NewClickFileStorage(fileName)
+	require.NoError(t, err)
+	defer clickRepo.Close()
+	fileStats, err := clickRepo.GetClickStats(ctx, "purged1", q)
+	require.NoError(t, err)
+	assert.Zero(t, fileStats.TotalClicks)
+}
+
 // func TestHandler_MarkRecordsForDeletion(t *testing.T) {
 // 	tests := []struct {
 // 		name string
@@ -280,3 +1799,173 @@ func TestMarkRecordsForDeletion(t *testing.T) {
 // 		})
 // 	}
 // }
+
+func TestTags(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	fileName := filepath.Join(t.TempDir(), "urls.txt")
+	repo, err := storage.NewFileStorage(fileName, settings.DedupOff)
+	require.NoError(t, err)
+	handler := NewHandler(service.NewService(repo, ""), "")
+
+	call := func(h http.HandlerFunc, method, path, body string) (int, []byte) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		result, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, result
+	}
+	type userURL struct {
+		ShortURL string   `json:"short_url"`
+		Tags     []string `json:"tags"`
+		Folder   string   `json:"folder"`
+	}
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Count int    `json:"count"`
+	}
+
+	code, _ := call(handler.GetShortURLJSON(), http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/a","alias":"tag-a","tags":[" news ","go","news"],"folder":"work"}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = call(handler.GetShortURLJSON(), http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","alias":"tag-b","tags":["golang"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = call(handler.GetShortURLJSON(), http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/c","tags":["`+strings.Repeat("x", 65)+`"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// изменение тегов не добавляет версию ссылки
+	code, body := call(handler.UpdateShortURL(), http.MethodPatch, "/api/user/urls/tag-b", `{"tags":["golang","news"],"folder":"home"}`)
+	require.Equal(t, http.StatusOK, code)
+	var version linkVersion
+	require.NoError(t, json.Unmarshal(body, &version))
+	assert.Equal(t, 1, version.Version)
+
+	code, body = call(handler.GetUserURLs(), http.MethodGet, "/api/user/urls?tag=news&folder=work", "")
+	require.Equal(t, http.StatusOK, code)
+	var urls []userURL
+	require.NoError(t, json.Unmarshal(body, &urls))
+	assert.Equal(t, []userURL{{ShortURL: "/tag-a", Tags: []string{"news", "go"}, Folder: "work"}}, urls)
+
+	code, body = call(handler.GetUserTags(), http.MethodGet, "/api/user/tags", "")
+	require.Equal(t, http.StatusOK, code)
+	var tags []tagCount
+	require.NoError(t, json.Unmarshal(body, &tags))
+	assert.Equal(t, []tagCount{{"news", 2}, {"go", 1}, {"golang", 1}}, tags)
+
+	// слияние go и golang в один тег переживает перезапуск
+	code, body = call(handler.MergeTags(), http.MethodPost, "/api/user/tags/merge", `{"from":["golang","go"],"to":"go"}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"updated":2}`, string(body))
+	code, _ = call(handler.MergeTags(), http.MethodPost, "/api/user/tags/merge", `{"from":["go"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	require.NoError(t, repo.Close())
+	repo, err = storage.NewFileStorage(fileName, settings.DedupOff)
+	require.NoError(t, err)
+	handler = NewHandler(service.NewService(repo, ""), "")
+	code, body = call(handler.GetUserTags(), http.MethodGet, "/api/user/tags", "")
+	require.Equal(t, http.StatusOK, code)
+	tags = nil
+	require.NoError(t, json.Unmarshal(body, &tags))
+	assert.Equal(t, []tagCount{{"go", 2}, {"news", 2}}, tags)
+
+	code, body = call(handler.GetUserURLs(), http.MethodGet, "/api/user/urls?folder=home", "")
+	require.Equal(t, http.StatusOK, code)
+	urls = nil
+	require.NoError(t, json.Unmarshal(body, &urls))
+	assert.Equal(t, []userURL{{ShortURL: "/tag-b", Tags: []string{"go", "news"}, Folder: "home"}}, urls)
+}
+
+func TestLinkMetadata(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "123")
+	var page atomic.Value
+	page.Store(`<html><head><title> Example
+		page </title><meta property="og:title" content="Example &amp; Co"><meta property="og:image" content="https://example.com/a.png">
+		</head><body><title>ignored</title></body></html>`)
+	var broken atomic.Bool
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page.Load().(string))
+	}))
+	defer destination.Close()
+
+	repo := storage.NewLocalCahce(settings.DedupOff)
+	svc := service.NewService(repo, "", service.WithMetadataFetcher(metadata.NewFetcherWithClient(destination.Client())))
+	go svc.HandleMetadataFetches()
+	handler := NewHandler(svc, "")
+
+	call := func(h http.HandlerFunc, method, path, body string) (int, []byte) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		result, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, result
+	}
+	type userURL struct {
+		Metadata settings.LinkMetadata `json:"metadata"`
+	}
+
+	code, _ := call(handler.GetShortURLJSON(), http.MethodPost, "/api/shorten", `{"url":"`+destination.URL+`/page","alias":"meta1"}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	// сведения загружаются в фоне
+	var urls []userURL
+	require.Eventually(t, func() bool {
+		code, body := call(handler.GetUserURLs(), http.MethodGet, "/api/user/urls", "")
+		urls = nil
+		return code == http.StatusOK && json.Unmarshal(body, &urls) == nil && !urls[0].Metadata.FetchedAt.IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+	meta := urls[0].Metadata
+	assert.Equal(t, "Example page", meta.Title)
+	assert.Equal(t, settings.OpenGraph{Title: "Example & Co", Image: "https://example.com/a.png"}, meta.OpenGraph)
+	assert.Empty(t, meta.Error)
+
+	code, body := call(handler.GetOriginalURL(), http.MethodGet, "/meta1+", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(body), "<h1>Example &amp; Co</h1>")
+	assert.Contains(t, string(body), `<img src="https://example.com/a.png"`)
+
+	page.Store(`<title>Updated</title>`)
+	code, body = call(handler.RefetchMetadata(), http.MethodPost, "/api/user/urls/meta1/metadata", "")
+	require.Equal(t, http.StatusOK, code)
+	meta = settings.LinkMetadata{}
+	require.NoError(t, json.Unmarshal(body, &meta))
+	assert.Equal(t, "Updated", meta.Title)
+	assert.Zero(t, meta.OpenGraph)
+
+	// недоступность страницы не стирает загруженные ранее сведения
+	broken.Store(true)
+	code, body = call(handler.RefetchMetadata(), http.MethodPost, "/api/user/urls/meta1/metadata", "")
+	require.Equal(t, http.StatusOK, code)
+	meta = settings.LinkMetadata{}
+	require.NoError(t, json.Unmarshal(body, &meta))
+	assert.Equal(t, "Updated", meta.Title)
+	assert.Contains(t, meta.Error, "500")
+
+	// сведения о прежнем адресе назначения не сохраняются после его изменения
+	err := repo.UpdateLinkMetadata(ctx, "meta1", "https://example.com/old", settings.LinkMetadata{Title: "Old"})
+	assert.ErrorIs(t, err, settings.ErrVersionConflict)
+	_, attrs, err := repo.GetLink(ctx, "meta1")
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", attrs.Metadata.Title)
+
+	other := context.WithValue(context.Background(), middleware.UserIDContextKey{}, "456")
+	request := httptest.NewRequest(http.MethodPost, "/api/user/urls/meta1/metadata", nil).WithContext(other)
+	w := httptest.NewRecorder()
+	handler.RefetchMetadata()(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// загрузчик по умолчанию не обращается к адресам внутренней сети
+	_, err = metadata.NewFetcher().Fetch(context.Background(), destination.URL+"/page")
+	assert.ErrorIs(t, err, netguard.ErrForbiddenAddress)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+)
+
+// RefetchMetadata - заново загружает заголовок и метки OpenGraph страницы назначения ссылки пользователя.
+// Короткий урл передается в пути /api/user/urls/{id}/metadata. Возвращает загруженные сведения;
+// если страница недоступна, в поле error указывается причина, а загруженные ранее сведения сохраняются.
+func (h *Handler) RefetchMetadata() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/user/urls/"), "/metadata")
+		meta, err := h.service.RefetchMetadata(ctx, id, userID)
+		if err != nil {
+			http.Error(res, err.Error(), ownLinkErrorStatus(err))
+			return
+		}
+		writeJSON(res, http.StatusOK, meta)
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// GetQRCode - возвращает QR код полного короткого URL.
+// Короткий урл передается в пути /{id}/qr. Формат выбирается параметром format (png или svg),
+// а без него - по заголовку Accept. Параметры size, level и margin задают размер в пикселях,
+// уровень коррекции ошибок и ширину рамки в модулях.
+func (h *Handler) GetQRCode() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		id := strings.TrimSuffix(strings.Trim(req.URL.Path, "/"), "/qr")
+		opts, err := parseQROptions(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		image, contentType, err := h.service.GetQRCode(ctx, id, opts)
+		if errors.Is(err, settings.ErrInvalidQROptions) {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), redirectErrorStatus(err))
+			return
+		}
+		res.Header().Set("content-type", contentType)
+		res.WriteHeader(http.StatusOK)
+		res.Write(image)
+	}
+}
+
+// parseQROptions читает параметры QR кода из запроса.
+func parseQROptions(req *http.Request) (settings.QROptions, error) {
+	values := req.URL.Query()
+	opts := settings.QROptions{Format: values.Get("format"), Level: values.Get("level"), Margin: -1}
+	if opts.Format == "" && strings.Contains(req.Header.Get("Accept"), "image/svg+xml") {
+		opts.Format = settings.QRFormatSVG
+	}
+	var err error
+	if size := values.Get("size"); size != "" {
+		if opts.Size, err = strconv.Atoi(size); err != nil {
+			return opts, fmt.Errorf("%w: size: %w", settings.ErrInvalidQROptions, err)
+		}
+	}
+	if margin := values.Get("margin"); margin != "" {
+		if opts.Margin, err = strconv.Atoi(margin); err != nil || opts.Margin < 0 {
+			return opts, fmt.Errorf("%w: margin must be a non-negative integer", settings.ErrInvalidQROptions)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+)
+
+// GetLinkStats - возвращает статистику переходов по ссылке пользователя.
+// Короткий урл передается в пути /api/user/urls/{id}/stats, интервал и шаг - в параметрах
+// from, to (RFC 3339), bucket (hour или day) и top (размер топов).
+func (h *Handler) GetLinkStats() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/user/urls/"), "/stats")
+		q, err := parseStatsQuery(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stats, err := h.service.GetLinkStats(ctx, id, userID, q)
+		if err != nil {
+			http.Error(res, err.Error(), statsErrorStatus(err))
+			return
+		}
+
+		type point struct {
+			Time   time.Time `json:"time"`
+			Clicks int64     `json:"clicks"`
+		}
+		type count struct {
+			Value string `json:"value"`
+			Count int64  `json:"count"`
+		}
+		counts := func(values []settings.StatsCount) []count {
+			res := make([]count, 0, len(values))
+			for _, v := range values {
+				res = append(res, count{Value: v.Value, Count: v.Count})
+			}
+			return res
+		}
+		output := struct {
+			TotalClicks    int64   `json:"total_clicks"`
+			UniqueVisitors int64   `json:"unique_visitors"`
+			Series         []point `json:"series"`
+			TopReferrers   []count `json:"top_referrers"`
+			TopUserAgents  []count `json:"top_user_agents"`
+			TopCountries   []count `json:"top_countries"`
+			Variants       []count `json:"variants"`
+		}{
+			TotalClicks:    stats.TotalClicks,
+			UniqueVisitors: stats.UniqueVisitors,
+			Series:         make([]point, 0, len(stats.Series)),
+			TopReferrers:   counts(stats.TopReferrers),
+			TopUserAgents:  counts(stats.TopUserAgents),
+			TopCountries:   counts(stats.TopCountries),
+			Variants:       counts(stats.Variants),
+		}
+		for _, p := range stats.Series {
+			output.Series = append(output.Series, point{Time: p.Time, Clicks: p.Clicks})
+		}
+
+		result, err := json.Marshal(output)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res.Header().Set("content-type", "application/json")
+		res.WriteHeader(http.StatusOK)
+		res.Write(result)
+	}
+}
+
+// parseStatsQuery читает параметры запроса статистики.
+func parseStatsQuery(req *http.Request) (settings.StatsQuery, error) {
+	var (
+		q   settings.StatsQuery
+		err error
+	)
+	values := req.URL.Query()
+	if from := values.Get("from"); from != "" {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return q, fmt.Errorf("%w: from: %w", settings.ErrInvalidStatsQuery, err)
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return q, fmt.Errorf("%w: to: %w", settings.ErrInvalidStatsQuery, err)
+		}
+	}
+	if top := values.Get("top"); top != "" {
+		if q.Top, err = strconv.Atoi(top); err != nil {
+			return q, fmt.Errorf("%w: top: %w", settings.ErrInvalidStatsQuery, err)
+		}
+	}
+	q.Bucket = settings.StatsBucket(values.Get("bucket"))
+	return q, nil
+}
+
+// statsErrorStatus возвращает http статус ответа для ошибки запроса статистики.
+func statsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, settings.ErrInvalidStatsQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, settings.ErrNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, settings.ErrOriginalURLNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
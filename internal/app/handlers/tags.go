@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+)
+
+// GetUserTags - возвращает теги ссылок пользователя с числом ссылок, от самых частых.
+// Ссылки, помеченные на удаление, не учитываются.
+func (h *Handler) GetUserTags() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		tags, err := h.service.GetUserTags(ctx, userID)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(tags) == 0 {
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+		type output struct {
+			Tag   string `json:"tag"`
+			Count int    `json:"count"`
+		}
+		o := make([]output, 0, len(tags))
+		for _, t := range tags {
+			o = append(o, output{Tag: t.Tag, Count: t.Count})
+		}
+		writeJSON(res, http.StatusOK, o)
+	}
+}
+
+// MergeTags - заменяет теги from у ссылок пользователя тегом to.
+// Теги передаются в теле {"from": [...], "to": "..."}. Возвращает число измененных ссылок {"updated": n}.
+func (h *Handler) MergeTags() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		var input struct {
+			From []string `json:"from"`
+			To   string   `json:"to"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updated, err := h.service.MergeTags(ctx, userID, input.From, input.To)
+		if err != nil {
+			http.Error(res, err.Error(), errorStatus(err))
+			return
+		}
+		writeJSON(res, http.StatusOK, struct {
+			Updated int `json:"updated"`
+		}{Updated: updated})
+	}
+}
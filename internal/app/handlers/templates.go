@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Ссылка защищена паролем</title>
+</head>
+<body>
+<form method="post">
+<p>Для перехода по ссылке введите пароль.</p>
+{{if .}}<p>{{.}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Перейти</button>
+</form>
+</body>
+</html>
+`))
+
+// writePasswordForm отдает html форму ввода пароля для защищенной ссылки.
+func writePasswordForm(res http.ResponseWriter, status int, message string) {
+	res.Header().Set("content-type", "text/html; charset=utf-8")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(status)
+	passwordFormTemplate.Execute(res, message)
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Переход по ссылке {{.ShortURL}}</title>
+</head>
+<body>
+<p>Короткая ссылка {{.ShortURL}} ведет на:</p>
+{{if .Protected}}<p>адрес скрыт, ссылка защищена паролем</p>{{else}}<p><code>{{.OriginalURL}}</code></p>{{end}}
+{{with .Metadata}}{{with or .OpenGraph.Title .Title}}<h1>{{.}}</h1>
+{{end}}{{with .OpenGraph.SiteName}}<p>{{.}}</p>
+{{end}}{{with .OpenGraph.Description}}<p>{{.}}</p>
+{{end}}{{with .OpenGraph.Image}}<img src="{{.}}" alt="" referrerpolicy="no-referrer" style="max-width: 100%">
+{{end}}{{end}}<dl>
+<dt>Создана</dt><dd>{{if .CreatedAt.IsZero}}неизвестно{{else}}{{.CreatedAt.UTC.Format "2006-01-02 15:04 MST"}}{{end}}</dd>
+<dt>Переходов</dt><dd>{{.Clicks}}</dd>
+</dl>
+<form method="post" action="{{.ContinueURL}}">
+<input type="hidden" name="confirm" value="1">
+<button type="submit">Продолжить</button>
+</form>
+</body>
+</html>
+`))
+
+// writePreview отдает html страницу предпросмотра ссылки id с формой подтверждения перехода.
+// Страница никогда не выполняет редирект и не засчитывает переход.
+func (h *Handler) writePreview(res http.ResponseWriter, req *http.Request, id string) {
+	preview, err := h.service.GetLinkPreview(req.Context(), id)
+	if err != nil {
+		http.Error(res, err.Error(), redirectErrorStatus(err))
+		return
+	}
+	data := struct {
+		settings.LinkPreview
+		ContinueURL string
+	}{
+		LinkPreview: preview,
+		ContinueURL: continueURL(id, req.URL.Query()),
+	}
+	res.Header().Set("content-type", "text/html; charset=utf-8")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(http.StatusOK)
+	previewTemplate.Execute(res, data)
+}
+
+// continueURL возвращает адрес формы подтверждения перехода по ссылке id с сохранением параметров запроса.
+func continueURL(id string, query url.Values) string {
+	query.Del("preview")
+	query.Del("confirm")
+	if len(query) == 0 {
+		return "/" + url.PathEscape(id)
+	}
+	return "/" + url.PathEscape(id) + "?" + query.Encode()
+}
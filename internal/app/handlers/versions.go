@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	middleware "github.com/nasik90/url-shortener/internal/app/middlewares"
+	"github.com/nasik90/url-shortener/internal/app/storage"
+)
+
+// optional - поле тела PATCH запроса, которое отличает отсутствующее значение от null.
+type optional[T any] struct {
+	Set   bool
+	Value T
+}
+
+// UnmarshalJSON отмечает поле переданным. null оставляет нулевое значение, которое сбрасывает атрибут.
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// ptr возвращает указатель на значение переданного поля или nil, если поле не передано.
+func (o optional[T]) ptr() *T {
+	if !o.Set {
+		return nil
+	}
+	return &o.Value
+}
+
+// UpdateShortURL - изменяет адрес назначения и атрибуты ссылки пользователя.
+// Короткий урл передается в пути /api/user/urls/{id}, изменения - в теле в JSON. Не переданные поля не меняются,
+// null сбрасывает атрибут. Возвращает новую версию ссылки.
+func (h *Handler) UpdateShortURL() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimPrefix(req.URL.Path, "/api/user/urls/")
+		var input struct {
+			URL              optional[string]                 `json:"url"`
+			ExpiresAt        optional[time.Time]              `json:"expires_at"`
+			MaxClicks        optional[int64]                  `json:"max_clicks"`
+			Password         optional[string]                 `json:"password"`
+			Interstitial     optional[bool]                   `json:"interstitial"`
+			FallbackURL      optional[string]                 `json:"fallback_url"`
+			QueryPassthrough optional[string]                 `json:"query_passthrough"`
+			UTM              optional[settings.UTMParams]     `json:"utm"`
+			RedirectStatus   optional[int]                    `json:"redirect_status"`
+			Routing          optional[[]settings.RoutingRule] `json:"routing"`
+			Variants         optional[[]settings.Variant]     `json:"variants"`
+			Tags             optional[[]string]               `json:"tags"`
+			Folder           optional[string]                 `json:"folder"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if input.URL.Set && input.URL.Value == "" {
+			http.Error(res, "empty url", http.StatusBadRequest)
+			return
+		}
+		update := settings.LinkUpdate{
+			OriginalURL:      input.URL.ptr(),
+			ExpiresAt:        input.ExpiresAt.ptr(),
+			MaxClicks:        input.MaxClicks.ptr(),
+			Password:         input.Password.ptr(),
+			Interstitial:     input.Interstitial.ptr(),
+			FallbackURL:      input.FallbackURL.ptr(),
+			QueryPassthrough: input.QueryPassthrough.ptr(),
+			UTM:              input.UTM.ptr(),
+			RedirectStatus:   input.RedirectStatus.ptr(),
+			Routing:          input.Routing.ptr(),
+			Variants:         input.Variants.ptr(),
+			Tags:             input.Tags.ptr(),
+			Folder:           input.Folder.ptr(),
+		}
+		version, err := h.service.UpdateShortURL(ctx, id, userID, update)
+		if err != nil {
+			http.Error(res, err.Error(), ownLinkErrorStatus(err))
+			return
+		}
+		writeJSON(res, http.StatusOK, linkVersionJSON(version))
+	}
+}
+
+// GetLinkVersions - возвращает версии ссылки пользователя от первой до текущей.
+// Короткий урл передается в пути /api/user/urls/{id}/versions.
+func (h *Handler) GetLinkVersions() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/user/urls/"), "/versions")
+		versions, err := h.service.GetLinkVersions(ctx, id, userID)
+		if err != nil {
+			http.Error(res, err.Error(), ownLinkErrorStatus(err))
+			return
+		}
+		output := make([]linkVersion, 0, len(versions))
+		for _, v := range versions {
+			output = append(output, linkVersionJSON(v))
+		}
+		writeJSON(res, http.StatusOK, output)
+	}
+}
+
+// RollbackShortURL - возвращает ссылке пользователя адрес назначения и атрибуты одной из версий.
+// Короткий урл передается в пути /api/user/urls/{id}/rollback, номер версии - в теле {"version": n}.
+// Возвращает новую версию ссылки.
+func (h *Handler) RollbackShortURL() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		userID := middleware.UserIDFromContext(ctx)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/user/urls/"), "/rollback")
+		var input struct {
+			Version int `json:"version"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		version, err := h.service.RollbackShortURL(ctx, id, userID, input.Version)
+		if err != nil {
+			http.Error(res, err.Error(), ownLinkErrorStatus(err))
+			return
+		}
+		writeJSON(res, http.StatusOK, linkVersionJSON(version))
+	}
+}
+
+// linkVersion - версия ссылки в ответах API. Пароль не раскрывается, передается только признак его наличия.
+type linkVersion struct {
+	Version          int                       `json:"version"`
+	OriginalURL      string                    `json:"original_url"`
+	CreatedAt        time.Time                 `json:"created_at,omitzero"`
+	ExpiresAt        time.Time                 `json:"expires_at,omitzero"`
+	MaxClicks        int64                     `json:"max_clicks,omitzero"`
+	Protected        bool                      `json:"protected,omitzero"`
+	Interstitial     bool                      `json:"interstitial,omitzero"`
+	FallbackURL      string                    `json:"fallback_url,omitzero"`
+	QueryPassthrough settings.QueryPassthrough `json:"query_passthrough,omitzero"`
+	UTM              settings.UTMParams        `json:"utm,omitzero"`
+	RedirectStatus   int                       `json:"redirect_status,omitzero"`
+	Routing          []settings.RoutingRule    `json:"routing,omitempty"`
+	Variants         []settings.Variant        `json:"variants,omitempty"`
+}
+
+func linkVersionJSON(v settings.LinkVersion) linkVersion {
+	return linkVersion{
+		Version:          v.Version,
+		OriginalURL:      v.OriginalURL,
+		CreatedAt:        v.CreatedAt,
+		ExpiresAt:        v.Attrs.ExpiresAt,
+		MaxClicks:        v.Attrs.MaxClicks,
+		Protected:        v.Attrs.PasswordHash != "",
+		Interstitial:     v.Attrs.Interstitial,
+		FallbackURL:      v.Attrs.FallbackURL,
+		QueryPassthrough: v.Attrs.QueryPassthrough,
+		UTM:              v.Attrs.UTM,
+		RedirectStatus:   v.Attrs.RedirectStatus,
+		Routing:          v.Attrs.Routing,
+		Variants:         v.Attrs.Variants,
+	}
+}
+
+// writeJSON пишет ответ со статусом status и телом value в JSON.
+func writeJSON(res http.ResponseWriter, status int, value any) {
+	result, err := json.Marshal(value)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(status)
+	res.Write(result)
+}
+
+// ownLinkErrorStatus возвращает http статус ответа для ошибки изменения или восстановления ссылки пользователя.
+func ownLinkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, settings.ErrNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, settings.ErrOriginalURLNotFound), errors.Is(err, settings.ErrVersionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrRecordMarkedForDel):
+		return http.StatusGone
+	case errors.Is(err, settings.ErrVersionConflict), errors.Is(err, settings.ErrOriginalURLNotUnique):
+		return http.StatusConflict
+	case errors.Is(err, settings.ErrMetadataDisabled):
+		return http.StatusNotImplemented
+	}
+	return errorStatus(err)
+}
@@ -0,0 +1,126 @@
+// Пакет health проверяет доступность оригинальных URL запросами HEAD и GET.
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/nasik90/url-shortener/internal/app/netguard"
+)
+
+// Настройки проверки доступности.
+const (
+	// checkTimeout - время ожидания ответа на один запрос.
+	checkTimeout = 10 * time.Second
+	// dialTimeout - время ожидания установки соединения.
+	dialTimeout = 2 * time.Second
+	// maxRedirects - допустимое число редиректов.
+	maxRedirects = 5
+	// hostInterval - минимальный интервал между запросами к одному хосту.
+	hostInterval = time.Second
+	// limiterSweepSize - размер мапы хостов, при котором из нее удаляются устаревшие записи.
+	limiterSweepSize = 10000
+	// userAgent - значение заголовка User-Agent запросов проверки.
+	userAgent = "url-shortener-health-checker/1.0"
+)
+
+// Checker - структура, которая выполняет проверки, ограничивая частоту запросов к каждому хосту.
+type Checker struct {
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+// NewChecker создает экземпляр структуры Checker. Проверки обращаются только к публичным адресам,
+// адрес каждого перехода по редиректу проверяется заново, поэтому статус ответа не раскрывает
+// владельцу ссылки устройство внутренней сети.
+func NewChecker() *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout:       checkTimeout,
+			Transport:     netguard.NewTransport(dialTimeout),
+			CheckRedirect: netguard.CheckRedirect(maxRedirects),
+		},
+		limiter: &hostLimiter{interval: hostInterval, next: make(map[string]time.Time)},
+	}
+}
+
+// Check возвращает http статус ответа по адресу originalURL.
+// Сначала отправляется запрос HEAD; если он завершился ошибкой или неуспешным статусом,
+// адрес проверяется запросом GET, так как часть серверов не поддерживает HEAD.
+func (c *Checker) Check(ctx context.Context, originalURL string) (int, error) {
+	u, err := url.Parse(originalURL)
+	if err != nil {
+		return 0, err
+	}
+	if err := netguard.CheckScheme(u); err != nil {
+		return 0, err
+	}
+	status, err := c.do(ctx, http.MethodHead, u)
+	if err == nil && status < http.StatusBadRequest {
+		return status, nil
+	}
+	return c.do(ctx, http.MethodGet, u)
+}
+
+// do отправляет запрос method, дождавшись своей очереди к хосту, и возвращает статус ответа.
+// Тело ответа не читается.
+func (c *Checker) do(ctx context.Context, method string, u *url.URL) (int, error) {
+	if err := c.limiter.wait(ctx, u.Host); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
+// hostLimiter распределяет запросы к каждому хосту не чаще одного в interval.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	// next - ближайший момент, в который можно отправить запрос к хосту
+	next map[string]time.Time
+}
+
+// wait ждет очереди для запроса к хосту host или отмены контекста.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	if len(l.next) >= limiterSweepSize {
+		l.sweepLocked(now)
+	}
+	at := now
+	if next, ok := l.next[host]; ok && next.After(now) {
+		at = next
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// sweepLocked удаляет хосты, к которым уже можно отправлять запросы без ожидания.
+func (l *hostLimiter) sweepLocked(now time.Time) {
+	for host, next := range l.next {
+		if !next.After(now) {
+			delete(l.next, host)
+		}
+	}
+}
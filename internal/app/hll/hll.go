@@ -0,0 +1,105 @@
+// Пакет hll реализует оценку числа уникальных значений алгоритмом HyperLogLog.
+package hll
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Precision - число бит хеша для выбора регистра.
+// 2^12 регистров по байту дают скетч размером 4 КБ со стандартной ошибкой около 1.6%.
+const Precision = 12
+
+// registersCount - число регистров скетча.
+const registersCount = 1 << Precision
+
+// ErrInvalidSketch - ошибка - данные скетча повреждены или получены с другой точностью.
+var ErrInvalidSketch = errors.New("invalid hyperloglog sketch")
+
+// Sketch - скетч HyperLogLog фиксированного размера.
+// Скетчи можно объединять: объединение дает оценку числа уникальных значений во всех исходных скетчах.
+type Sketch struct {
+	registers [registersCount]uint8
+}
+
+// New создает пустой скетч.
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add учитывает значение в скетче.
+func (s *Sketch) Add(value []byte) {
+	h := hash(value)
+	idx := h >> (64 - Precision)
+	// ранг - позиция первой единицы в оставшихся битах, сторожевой бит ограничивает ранг
+	rank := uint8(bits.LeadingZeros64(h<<Precision|1<<(Precision-1)) + 1)
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+// AddString учитывает строковое значение в скетче.
+func (s *Sketch) AddString(value string) {
+	s.Add([]byte(value))
+}
+
+// Merge объединяет скетч other с текущим. Повторное объединение с тем же скетчем ничего не меняет.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Estimate возвращает оценку числа уникальных значений.
+func (s *Sketch) Estimate() uint64 {
+	const m = float64(registersCount)
+	sum := 0.0
+	zeros := 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// на малых значениях точнее линейный подсчет по пустым регистрам
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary возвращает регистры скетча.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, registersCount)
+	copy(data, s.registers[:])
+	return data, nil
+}
+
+// UnmarshalBinary восстанавливает скетч из регистров.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) != registersCount {
+		return ErrInvalidSketch
+	}
+	copy(s.registers[:], data)
+	return nil
+}
+
+// hash возвращает 64-битный хеш значения: FNV-1a с финальным перемешиванием splitmix64,
+// чтобы старшие биты, выбирающие регистр, распределялись равномерно.
+func hash(value []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(value)
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
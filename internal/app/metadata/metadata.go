@@ -0,0 +1,177 @@
+// Пакет metadata загружает страницы назначения ссылок и извлекает из них заголовок и метки OpenGraph.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/netguard"
+)
+
+// Настройки загрузки страниц.
+const (
+	// fetchTimeout - время ожидания загрузки страницы вместе с редиректами.
+	fetchTimeout = 5 * time.Second
+	// dialTimeout - время ожидания установки соединения.
+	dialTimeout = 2 * time.Second
+	// maxBodySize - сколько байт страницы читается; заголовок и метки ищутся только в них.
+	maxBodySize = 512 << 10
+	// maxRedirects - допустимое число редиректов.
+	maxRedirects = 5
+	// maxFieldLen - максимальная длина сохраняемого значения в символах.
+	maxFieldLen = 512
+	// userAgent - значение заголовка User-Agent запросов.
+	userAgent = "url-shortener-metadata-fetcher/1.0"
+)
+
+// ErrNotHTML - ошибка - страница не является html документом.
+var ErrNotHTML = errors.New("not an html page")
+
+// Fetcher - структура, которая загружает страницы назначения.
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher создает экземпляр структуры Fetcher, который соединяется только с публичными адресами
+// (см. netguard.NewTransport).
+func NewFetcher() *Fetcher {
+	return NewFetcherWithClient(&http.Client{Transport: netguard.NewTransport(dialTimeout)})
+}
+
+// NewFetcherWithClient создает экземпляр структуры Fetcher, который загружает страницы клиентом client.
+// Ограничения времени и числа редиректов устанавливаются на копию клиента.
+func NewFetcherWithClient(client *http.Client) *Fetcher {
+	c := *client
+	c.Timeout = fetchTimeout
+	c.CheckRedirect = netguard.CheckRedirect(maxRedirects)
+	return &Fetcher{client: &c}
+}
+
+// Fetch загружает страницу по адресу pageURL и возвращает ее заголовок и метки OpenGraph.
+// Читается не больше maxBodySize байт страницы.
+func (f *Fetcher) Fetch(ctx context.Context, pageURL string) (settings.LinkMetadata, error) {
+	var meta settings.LinkMetadata
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return meta, err
+	}
+	if err := netguard.CheckScheme(u); err != nil {
+		return meta, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return meta, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	res, err := f.client.Do(req)
+	if err != nil {
+		return meta, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return meta, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return meta, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+		}
+	}
+	return parse(io.LimitReader(res.Body, maxBodySize))
+}
+
+// parse извлекает из html документа заголовок и метки OpenGraph. Разбор заканчивается на теге <body>,
+// так как метаданные находятся в <head>. Обрезанный документ разбирается до места обрыва.
+func parse(r io.Reader) (settings.LinkMetadata, error) {
+	var meta settings.LinkMetadata
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return meta, nil
+			}
+			return meta, z.Err()
+		case html.TextToken:
+			if inTitle && meta.Title == "" {
+				meta.Title = clean(string(z.Text()))
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				return meta, nil
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				if hasAttr {
+					setOpenGraph(&meta.OpenGraph, z)
+				}
+			}
+		}
+	}
+}
+
+// setOpenGraph заполняет метку og:* из тега <meta property="og:..." content="...">.
+// Повторная метка не заменяет первую.
+func setOpenGraph(og *settings.OpenGraph, z *html.Tokenizer) {
+	var property, content string
+	for more := true; more; {
+		var key, value []byte
+		key, value, more = z.TagAttr()
+		switch string(key) {
+		case "property", "name":
+			if property == "" {
+				property = strings.ToLower(string(value))
+			}
+		case "content":
+			content = clean(string(value))
+		}
+	}
+	var field *string
+	switch property {
+	case "og:title":
+		field = &og.Title
+	case "og:description":
+		field = &og.Description
+	case "og:image", "og:image:url":
+		field = &og.Image
+	case "og:site_name":
+		field = &og.SiteName
+	case "og:type":
+		field = &og.Type
+	case "og:url":
+		field = &og.URL
+	default:
+		return
+	}
+	if *field == "" {
+		*field = content
+	}
+}
+
+// clean схлопывает пробельные символы и обрезает значение до maxFieldLen символов.
+func clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxFieldLen {
+		s = string(runes[:maxFieldLen])
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+// Пакет netguard защищает исходящие запросы сервиса к адресам пользователей от обращений во внутреннюю сеть.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress - ошибка - адрес ведет во внутреннюю сеть или использует неподдерживаемую схему.
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// blockedPrefixes - диапазоны адресов, не покрытые проверками netip.Addr, к которым нельзя обращаться:
+// "эта" сеть, разделяемое адресное пространство операторов, служебные, тестовые и зарезервированные диапазоны.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// NewTransport создает транспорт, который соединяется только с публичными адресами.
+// Адрес проверяется после разрешения имени при каждом соединении, в том числе после редиректов,
+// поэтому имя, указывающее во внутреннюю сеть, не позволит обойти проверку. Прокси не используется.
+func NewTransport(dialTimeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeout, Control: CheckAddress}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: dialTimeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+	}
+}
+
+// CheckRedirect возвращает функцию проверки редиректов для http.Client: не больше maxRedirects переходов
+// и только по http и https.
+func CheckRedirect(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return CheckScheme(req.URL)
+	}
+}
+
+// CheckAddress запрещает соединения с адресами внутренней сети. Подходит для net.Dialer.Control.
+func CheckAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// IsPublic сообщает, является ли адрес публичным адресом индивидуальной рассылки.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckScheme разрешает запросы только по http и https.
+func CheckScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrForbiddenAddress, u.Scheme)
+	}
+	return nil
+}
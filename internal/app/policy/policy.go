@@ -0,0 +1,212 @@
+// Пакет policy проверяет оригинальные URL по спискам запрещенных и разрешенных доменов.
+//
+// Файл списка содержит по одному правилу в строке, пустые строки и строки, начинающиеся с #, пропускаются:
+//
+//	example.com      - точное совпадение домена;
+//	.example.com     - домен и все его поддомены;
+//	*.example.*      - шаблон домена, * соответствует любой последовательности символов;
+//	re:^https?://... - регулярное выражение, которое проверяется по всему URL.
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// reloadInterval - период проверки изменения файлов списков.
+const reloadInterval = 30 * time.Second
+
+// regexpPrefix - префикс правила с регулярным выражением.
+const regexpPrefix = "re:"
+
+// ruleSet - разобранный список правил.
+type ruleSet struct {
+	exact     map[string]bool
+	suffixes  []string
+	wildcards []string
+	regexps   []*regexp.Regexp
+}
+
+// parseRules разбирает файл списка правил.
+func parseRules(fileName string) (*ruleSet, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rules := &ruleSet{exact: make(map[string]bool)}
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, regexpPrefix):
+			re, err := regexp.Compile(strings.TrimPrefix(line, regexpPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", fileName, n, err)
+			}
+			rules.regexps = append(rules.regexps, re)
+		case strings.ContainsAny(line, "*?["):
+			pattern := normalizeHost(line)
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", fileName, n, err)
+			}
+			rules.wildcards = append(rules.wildcards, pattern)
+		case strings.HasPrefix(line, "."):
+			rules.suffixes = append(rules.suffixes, normalizeHost(line))
+		default:
+			rules.exact[normalizeHost(line)] = true
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// match возвращает правило, которому соответствует URL rawURL с доменом host.
+func (r *ruleSet) match(host, rawURL string) (string, bool) {
+	if r.exact[host] {
+		return host, true
+	}
+	for _, suffix := range r.suffixes {
+		if host == suffix[1:] || strings.HasSuffix(host, suffix) {
+			return suffix, true
+		}
+	}
+	for _, pattern := range r.wildcards {
+		// домен не содержит "/", поэтому * в шаблоне захватывает и точки
+		if ok, _ := path.Match(pattern, host); ok {
+			return pattern, true
+		}
+	}
+	for _, re := range r.regexps {
+		if re.MatchString(rawURL) {
+			return regexpPrefix + re.String(), true
+		}
+	}
+	return "", false
+}
+
+// normalizeHost приводит домен к виду, в котором он сравнивается с правилами.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// Engine - структура, которая хранит списки запрещенных и разрешенных доменов и перечитывает их при изменении файлов.
+type Engine struct {
+	blocklistPath string
+	allowlistPath string
+	mu            sync.RWMutex
+	blocklist     *ruleSet
+	allowlist     *ruleSet
+	modTimes      map[string]time.Time
+}
+
+// NewEngine загружает списки правил. Пустой путь означает, что список не используется.
+// Если задан список разрешенных доменов, сокращать можно только URL, подходящие под его правила.
+func NewEngine(blocklistPath, allowlistPath string) (*Engine, error) {
+	e := &Engine{blocklistPath: blocklistPath, allowlistPath: allowlistPath}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Check проверяет оригинальный URL по спискам.
+// Для запрещенных URL возвращает ошибку *settings.BlockedURLError.
+func (e *Engine) Check(originalURL string) error {
+	u, err := url.Parse(originalURL)
+	if err != nil {
+		return &settings.InvalidURLError{URL: originalURL, Reason: err.Error()}
+	}
+	host := normalizeHost(u.Hostname())
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.blocklist != nil {
+		if rule, ok := e.blocklist.match(host, originalURL); ok {
+			return &settings.BlockedURLError{URL: originalURL, Reason: "matches blocklist rule " + rule}
+		}
+	}
+	if e.allowlist != nil {
+		if _, ok := e.allowlist.match(host, originalURL); !ok {
+			return &settings.BlockedURLError{URL: originalURL, Reason: "domain is not in allowlist"}
+		}
+	}
+	return nil
+}
+
+// Reload перечитывает файлы списков и подменяет ими текущие.
+// Если один из файлов не удалось разобрать, продолжают действовать ранее загруженные списки.
+func (e *Engine) Reload() error {
+	modTimes := make(map[string]time.Time)
+	load := func(fileName string) (*ruleSet, error) {
+		if fileName == "" {
+			return nil, nil
+		}
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[fileName] = info.ModTime()
+		return parseRules(fileName)
+	}
+	blocklist, err := load(e.blocklistPath)
+	if err != nil {
+		return err
+	}
+	allowlist, err := load(e.allowlistPath)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.blocklist, e.allowlist, e.modTimes = blocklist, allowlist, modTimes
+	e.mu.Unlock()
+	return nil
+}
+
+// changed сообщает, изменился ли какой-либо из файлов списков после загрузки.
+func (e *Engine) changed() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for fileName, modTime := range e.modTimes {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			logger.Log.Info("cannot stat policy list", zap.String("path", fileName), zap.Error(err))
+			continue
+		}
+		if !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleReload перечитывает списки при изменении файлов и при получении сигнала из канала signals (SIGHUP).
+func (e *Engine) HandleReload(signals <-chan os.Signal) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !e.changed() {
+				continue
+			}
+		case <-signals:
+		}
+		if err := e.Reload(); err != nil {
+			logger.Log.Info("cannot reload policy lists", zap.Error(err))
+			continue
+		}
+		logger.Log.Info("policy lists reloaded", zap.String("blocklist", e.blocklistPath), zap.String("allowlist", e.allowlistPath))
+	}
+}
@@ -0,0 +1,144 @@
+// Пакет qr формирует изображения QR кодов в форматах PNG и SVG.
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Ограничения параметров изображения.
+const (
+	defaultSize   = 256
+	minSize       = 64
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 16
+)
+
+// levels - уровни коррекции ошибок по их обозначениям.
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Normalize проверяет параметры изображения и заполняет значения по умолчанию.
+func Normalize(o settings.QROptions) (settings.QROptions, error) {
+	o.Format = strings.ToLower(o.Format)
+	switch o.Format {
+	case "":
+		o.Format = settings.QRFormatPNG
+	case settings.QRFormatPNG, settings.QRFormatSVG:
+	default:
+		return o, fmt.Errorf("%w: unknown format %q", settings.ErrInvalidQROptions, o.Format)
+	}
+	if o.Size == 0 {
+		o.Size = defaultSize
+	}
+	if o.Size < minSize || o.Size > maxSize {
+		return o, fmt.Errorf("%w: size must be between %d and %d", settings.ErrInvalidQROptions, minSize, maxSize)
+	}
+	o.Level = strings.ToUpper(o.Level)
+	if o.Level == "" {
+		o.Level = "M"
+	}
+	if _, ok := levels[o.Level]; !ok {
+		return o, fmt.Errorf("%w: level must be one of L, M, Q, H", settings.ErrInvalidQROptions)
+	}
+	if o.Margin < 0 {
+		o.Margin = defaultMargin
+	}
+	if o.Margin > maxMargin {
+		return o, fmt.Errorf("%w: margin must not exceed %d", settings.ErrInvalidQROptions, maxMargin)
+	}
+	return o, nil
+}
+
+// ContentType возвращает MIME тип изображения формата format.
+func ContentType(format string) string {
+	if format == settings.QRFormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Encode возвращает изображение QR кода с содержимым content.
+// Параметры должны быть предварительно проверены Normalize.
+func Encode(content string, o settings.QROptions) ([]byte, error) {
+	code, err := qrcode.New(content, levels[o.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	modules := withMargin(code.Bitmap(), o.Margin)
+	if o.Format == settings.QRFormatSVG {
+		return encodeSVG(modules, o.Size), nil
+	}
+	return encodePNG(modules, o.Size)
+}
+
+// withMargin добавляет вокруг модулей пустую рамку шириной margin.
+func withMargin(bitmap [][]bool, margin int) [][]bool {
+	total := len(bitmap) + 2*margin
+	res := make([][]bool, total)
+	for y := range res {
+		res[y] = make([]bool, total)
+		if y >= margin && y < margin+len(bitmap) {
+			copy(res[y][margin:], bitmap[y-margin])
+		}
+	}
+	return res
+}
+
+// encodePNG рисует модули в черно-белое изображение размером size.
+// Если модулей больше, чем пикселей, изображение увеличивается до одного пикселя на модуль.
+func encodePNG(modules [][]bool, size int) ([]byte, error) {
+	total := len(modules)
+	size = max(size, total)
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < size; y++ {
+		row := modules[y*total/size]
+		for x := 0; x < size; x++ {
+			if row[x*total/size] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeSVG рисует модули векторным изображением размером size, объединяя соседние модули строки.
+func encodeSVG(modules [][]bool, size int) []byte {
+	total := len(modules)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+	for y, row := range modules {
+		for x := 0; x < total; x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < total && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
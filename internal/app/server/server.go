@@ -42,9 +42,19 @@ func (s *Server) RunServer() error {
 		r.Post("/api/shorten", s.handler.GetShortURLJSON())
 		r.Post("/api/shorten/batch", s.handler.GetShortURLs())
 		r.Get("/{id}", s.handler.GetOriginalURL())
+		r.Post("/{id}", s.handler.GetProtectedOriginalURL())
+		r.Get("/{id}/qr", s.handler.GetQRCode())
 		r.Get("/ping", s.handler.Ping())
 		r.Get("/api/user/urls", s.handler.GetUserURLs())
 		r.Delete("/api/user/urls", s.handler.MarkRecordsForDeletion())
+		r.Post("/api/user/urls/{id}/restore", s.handler.RestoreShortURL())
+		r.Post("/api/user/urls/{id}/metadata", s.handler.RefetchMetadata())
+		r.Patch("/api/user/urls/{id}", s.handler.UpdateShortURL())
+		r.Get("/api/user/urls/{id}/stats", s.handler.GetLinkStats())
+		r.Get("/api/user/urls/{id}/versions", s.handler.GetLinkVersions())
+		r.Post("/api/user/urls/{id}/rollback", s.handler.RollbackShortURL())
+		r.Get("/api/user/tags", s.handler.GetUserTags())
+		r.Post("/api/user/tags/merge", s.handler.MergeTags())
 		r.Get("/api/internal/stats", s.handler.GetURLsStats())
 	})
 	s.Handler = logger.RequestLogger(middleware.Auth(middleware.GzipMiddleware(r.ServeHTTP)))
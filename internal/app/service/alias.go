@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// reservedAliases - алиасы, совпадающие с путями сервиса, которые нельзя занимать пользователю.
+var reservedAliases = map[string]bool{
+	"api":     true,
+	"ping":    true,
+	"debug":   true,
+	"admin":   true,
+	"static":  true,
+	"health":  true,
+	"metrics": true,
+}
+
+// validateAlias проверяет пользовательский алиас на длину, допустимые символы и зарезервированные слова.
+func validateAlias(alias string) error {
+	if len(alias) < settings.AliasMinLen || len(alias) > settings.AliasMaxLen {
+		return fmt.Errorf("%w: length must be between %d and %d", settings.ErrInvalidAlias, settings.AliasMinLen, settings.AliasMaxLen)
+	}
+	for _, r := range alias {
+		if !isAliasChar(r) {
+			return fmt.Errorf("%w: unsupported character %q", settings.ErrInvalidAlias, r)
+		}
+	}
+	if reservedAliases[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: %q is reserved", settings.ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+func isAliasChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// Параметры подбора свободного короткого кода.
+const (
+	// maxAllocAttempts - число попыток сохранить сгенерированный код, после которого запрос завершается ошибкой.
+	maxAllocAttempts = 10
+	// collisionRateWeight - вес последнего наблюдения в скользящей доле коллизий.
+	collisionRateWeight = 0.01
+	// collisionRateLimit - доля коллизий, при превышении которой длина кода увеличивается.
+	collisionRateLimit = 0.05
+	// collisionMinSamples - минимальное число наблюдений перед принятием решения об увеличении длины.
+	collisionMinSamples = 100
+)
+
+// codeAllocator отслеживает долю коллизий сгенерированных кодов и увеличивает длину кода,
+// когда пространство кодов текущей длины становится тесным.
+// Увеличенная длина не сохраняется между перезапусками: после рестарта рост начнется заново при коллизиях.
+type codeAllocator struct {
+	mu        sync.Mutex
+	length    int
+	maxLength int
+	rate      float64
+	samples   int
+}
+
+func newCodeAllocator(length, maxLength int) *codeAllocator {
+	return &codeAllocator{length: length, maxLength: max(length, maxLength)}
+}
+
+// currentLength возвращает текущую длину генерируемых кодов.
+func (a *codeAllocator) currentLength() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.length
+}
+
+// observe учитывает результат сохранения сгенерированного кода длины length.
+// Наблюдения для кодов прежней длины после роста не учитываются.
+func (a *codeAllocator) observe(length int, collided bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if length != a.length {
+		return
+	}
+	var v float64
+	if collided {
+		v = 1
+	}
+	a.rate += (v - a.rate) * collisionRateWeight
+	a.samples++
+	if a.samples < collisionMinSamples || a.rate < collisionRateLimit || a.length >= a.maxLength {
+		return
+	}
+	a.length++
+	a.rate = 0
+	a.samples = 0
+	logger.Log.Info("short code length increased", zap.Int("length", a.length))
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/hll"
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// ClickRepository - интерфейс хранилища событий переходов по ссылкам.
+type ClickRepository interface {
+	SaveClicks(ctx context.Context, events ...settings.ClickEvent) error
+	GetClickStats(ctx context.Context, shortURL string, q settings.StatsQuery) (settings.LinkStats, error)
+	MergeVisitorSketches(ctx context.Context, sketches map[settings.VisitorSketchKey]*hll.Sketch) error
+	GetVisitorSketch(ctx context.Context, shortURL string, from, to time.Time) (*hll.Sketch, error)
+	// DeleteClicksBefore удаляет события переходов до момента before и скетчи посетителей за дни до него.
+	// Возвращает число удаленных событий.
+	DeleteClicksBefore(ctx context.Context, before time.Time) (int, error)
+	// DeleteLinkClicks удаляет события переходов и скетчи посетителей коротких урлов shortURLs.
+	DeleteLinkClicks(ctx context.Context, shortURLs ...string) error
+	Close() error
+}
+
+// GeoResolver - интерфейс определения местоположения клиента по IP адресу.
+type GeoResolver interface {
+	Lookup(ip string) settings.GeoLocation
+}
+
+// Настройки конвейера событий переходов.
+const (
+	// clickBufferSize - размер буфера событий, при его заполнении новые события отбрасываются.
+	clickBufferSize = 4096
+	// clickBatchSize - число событий, при накоплении которого они сохраняются, не дожидаясь тикера.
+	clickBatchSize = 500
+	// clickFlushInterval - период сохранения накопленных событий.
+	clickFlushInterval = time.Second
+)
+
+// clickSweepInterval - период запуска удаления старых событий переходов.
+const clickSweepInterval = time.Hour
+
+// clickPipeline - асинхронный буферизованный конвейер сохранения событий переходов.
+type clickPipeline struct {
+	repo    ClickRepository
+	events  chan settings.ClickEvent
+	done    chan struct{}
+	dropped atomic.Int64
+	// mu защищает отправку в events от закрытия канала в CloseClicks
+	mu     sync.RWMutex
+	closed bool
+	// started - конвейер уже запущен в HandleClicks или CloseClicks
+	started atomic.Bool
+}
+
+func newClickPipeline(repo ClickRepository) *clickPipeline {
+	return &clickPipeline{
+		repo:   repo,
+		events: make(chan settings.ClickEvent, clickBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// RecordClick ставит событие перехода в очередь на сохранение, не блокируя вызывающего.
+// Если хранилище событий не настроено, буфер заполнен или прием событий остановлен, событие отбрасывается.
+func (s *Service) RecordClick(event settings.ClickEvent) {
+	if s.clicks == nil {
+		return
+	}
+	s.clicks.mu.RLock()
+	defer s.clicks.mu.RUnlock()
+	if s.clicks.closed {
+		return
+	}
+	select {
+	case s.clicks.events <- event:
+	default:
+		if s.clicks.dropped.Add(1)%clickBufferSize == 1 {
+			logger.Log.Info("click buffer is full, events dropped", zap.Int64("dropped", s.clicks.dropped.Load()))
+		}
+	}
+}
+
+// HandleClicks сохраняет события переходов из очереди пачками.
+// Завершается после вызова CloseClicks, сохранив оставшиеся события. Повторный вызов сразу завершается.
+func (s *Service) HandleClicks() {
+	if s.clicks == nil || !s.clicks.started.CompareAndSwap(false, true) {
+		return
+	}
+	s.saveClicks()
+}
+
+// saveClicks сохраняет события переходов из очереди пачками, пока очередь не будет закрыта.
+func (s *Service) saveClicks() {
+	defer close(s.clicks.done)
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	// events - события, еще не учтенные в скетчах; sketched - учтенные, но еще не сохраненные
+	var events, sketched []settings.ClickEvent
+	flush := func() {
+		var err error
+		if len(events) > 0 {
+			// объединение скетчей идемпотентно, поэтому при повторе после ошибки посетители не задвоятся
+			err = s.clicks.repo.MergeVisitorSketches(context.TODO(), visitorSketches(events))
+			if err == nil {
+				// IP адрес нужен только для скетча и местоположения, в событиях его не храним
+				for i := range events {
+					events[i].IP = ""
+				}
+				sketched = append(sketched, events...)
+				events = nil
+			}
+		}
+		if err == nil && len(sketched) > 0 {
+			err = s.clicks.repo.SaveClicks(context.TODO(), sketched...)
+			if err == nil {
+				sketched = nil
+			}
+		}
+		if err != nil {
+			logger.Log.Info("cannot save clicks", zap.Error(err))
+			// не будем терять события, попробуем сохранить их чуть позже, но не копим больше буфера
+			if len(events)+len(sketched) >= clickBufferSize {
+				events, sketched = nil, nil
+			}
+		}
+	}
+	for {
+		select {
+		case event, ok := <-s.clicks.events:
+			if !ok {
+				flush()
+				return
+			}
+			events = append(events, s.enrichClick(event))
+			if len(events) >= clickBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// enrichClick дополняет событие местоположением клиента, если настроена база GeoIP.
+// Выполняется в конвейере, чтобы не замедлять редирект.
+func (s *Service) enrichClick(event settings.ClickEvent) settings.ClickEvent {
+	if s.geo == nil || event.IP == "" {
+		return event
+	}
+	loc := s.geo.Lookup(event.IP)
+	event.Country = loc.Country
+	event.Region = loc.Region
+	event.City = loc.City
+	return event
+}
+
+// visitorSketches строит дневные скетчи уникальных посетителей по событиям переходов:
+// для каждой ссылки и общий для всех ссылок сервиса. Посетитель определяется парой IP и User-Agent.
+func visitorSketches(events []settings.ClickEvent) map[settings.VisitorSketchKey]*hll.Sketch {
+	sketches := make(map[settings.VisitorSketchKey]*hll.Sketch)
+	add := func(key settings.VisitorSketchKey, visitor string) {
+		sketch, ok := sketches[key]
+		if !ok {
+			sketch = hll.New()
+			sketches[key] = sketch
+		}
+		sketch.AddString(visitor)
+	}
+	for _, event := range events {
+		day := settings.SketchDay(event.Time)
+		visitor := event.IP + "\x00" + event.UserAgent
+		add(settings.VisitorSketchKey{ShortURL: event.ShortURL, Day: day}, visitor)
+		add(settings.VisitorSketchKey{Day: day}, visitor)
+	}
+	return sketches
+}
+
+// CloseClicks останавливает прием событий переходов и ждет сохранения накопленных.
+// Если HandleClicks не запускался, накопленные события сохраняются в вызывающем потоке.
+// Повторный вызов ничего не делает.
+func (s *Service) CloseClicks() {
+	if s.clicks == nil {
+		return
+	}
+	s.clicks.mu.Lock()
+	if s.clicks.closed {
+		s.clicks.mu.Unlock()
+		return
+	}
+	s.clicks.closed = true
+	close(s.clicks.events)
+	s.clicks.mu.Unlock()
+	if s.clicks.started.CompareAndSwap(false, true) {
+		s.saveClicks()
+		return
+	}
+	<-s.clicks.done
+}
+
+// HandleOldClicks периодически удаляет события переходов и скетчи посетителей старше clickRetention.
+// Первое удаление выполняется сразу. Если сбор переходов не настроен или clickRetention нулевой, сразу завершается.
+func (s *Service) HandleOldClicks() {
+	if s.clicks == nil || s.clickRetention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(clickSweepInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeleteOldClicks(context.TODO()); err != nil {
+			logger.Log.Info("cannot delete old clicks", zap.Error(err))
+		}
+		<-ticker.C
+	}
+}
+
+// DeleteOldClicks удаляет события переходов и скетчи посетителей старше clickRetention.
+// Возвращает число удаленных событий.
+func (s *Service) DeleteOldClicks(ctx context.Context) (int, error) {
+	if s.clicks == nil || s.clickRetention <= 0 {
+		return 0, nil
+	}
+	deleted, err := s.clicks.repo.DeleteClicksBefore(ctx, time.Now().Add(-s.clickRetention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Log.Info("old clicks deleted", zap.Int("count", deleted))
+	}
+	return deleted, nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Стратегии генерации коротких кодов.
+const (
+	// CodeStrategyRandom - случайные символы алфавита.
+	CodeStrategyRandom = "random"
+	// CodeStrategyCounter - последовательный счетчик в системе счисления алфавита.
+	CodeStrategyCounter = "counter"
+	// CodeStrategyHashids - счетчик, перемешанный обратимым преобразованием, как в hashids.
+	CodeStrategyHashids = "hashids"
+	// CodeStrategyHash - детерминированный хеш оригинального URL.
+	CodeStrategyHash = "hash"
+)
+
+// CodeGenerator - интерфейс генератора коротких кодов.
+type CodeGenerator interface {
+	// Generate возвращает короткий код длиной length для оригинального URL.
+	// attempt - номер попытки для одного URL, начиная с 0. Детерминированные стратегии
+	// должны возвращать разные коды для разных попыток.
+	Generate(originalURL string, length, attempt int) (string, error)
+}
+
+// CodeCounter - хранилище верхней границы выданных значений счетчика стратегий counter и hashids.
+// Граница переживает перезапуск и безвозвратное удаление ссылок, поэтому выданные коды не повторяются.
+type CodeCounter interface {
+	// ReserveCodes сдвигает сохраненную границу на n и возвращает прежнюю: вызывающему принадлежат
+	// значения счетчика из (прежняя граница, прежняя граница + n].
+	ReserveCodes(ctx context.Context, n uint64) (uint64, error)
+}
+
+// codeBlockSize - сколько значений счетчика резервируется в хранилище за раз.
+// После перезапуска неиспользованные значения блока пропускаются.
+const codeBlockSize = 1000
+
+// NewCodeGenerator создает генератор по названию стратегии.
+// counter - хранилище границы счетчика для стратегий counter и hashids,
+// salt - соль для перемешивания алфавита в стратегии hashids.
+func NewCodeGenerator(strategy, alphabet, salt string, counter CodeCounter) (CodeGenerator, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	symbols := []rune(alphabet)
+	switch strategy {
+	case CodeStrategyRandom, "":
+		return &randomGenerator{alphabet: symbols}, nil
+	case CodeStrategyCounter, CodeStrategyHashids:
+		if counter == nil {
+			return nil, fmt.Errorf("code strategy %q requires a code counter", strategy)
+		}
+		if strategy == CodeStrategyCounter {
+			return &counterGenerator{alphabet: symbols, counter: counter}, nil
+		}
+		return &hashidsGenerator{counterGenerator: counterGenerator{alphabet: shuffle(symbols, salt), counter: counter}}, nil
+	case CodeStrategyHash:
+		return &hashGenerator{alphabet: symbols}, nil
+	}
+	return nil, fmt.Errorf("unknown code strategy %q", strategy)
+}
+
+// ValidateCodeLength проверяет длину генерируемых коротких кодов.
+func ValidateCodeLength(length int) error {
+	if length < 1 || length > settings.MaxCodeLen {
+		return fmt.Errorf("code length must be between 1 and %d", settings.MaxCodeLen)
+	}
+	return nil
+}
+
+// validateAlphabet проверяет, что алфавит состоит минимум из двух неповторяющихся символов,
+// допустимых в коротком урле.
+func validateAlphabet(alphabet string) error {
+	seen := make(map[rune]bool)
+	for _, r := range alphabet {
+		if !isAliasChar(r) {
+			return fmt.Errorf("unsupported alphabet character %q", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("duplicate alphabet character %q", r)
+		}
+		seen[r] = true
+	}
+	if len(seen) < 2 {
+		return errors.New("alphabet must contain at least two characters")
+	}
+	return nil
+}
+
+// randomGenerator генерирует код из случайных символов алфавита.
+type randomGenerator struct {
+	alphabet []rune
+}
+
+// Generate возвращает случайный код.
+func (g *randomGenerator) Generate(originalURL string, length, attempt int) (string, error) {
+	res := make([]rune, length)
+	for i := range res {
+		r, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.alphabet))))
+		if err != nil {
+			return "", err
+		}
+		res[i] = g.alphabet[int(r.Int64())]
+	}
+	return string(res), nil
+}
+
+// counterGenerator генерирует коды из последовательного счетчика.
+// Значения выдаются из блоков, зарезервированных в хранилище counter.
+type counterGenerator struct {
+	alphabet []rune
+	counter  CodeCounter
+	mu       sync.Mutex
+	// last - последнее выданное значение, limit - последнее значение зарезервированного блока
+	last  uint64
+	limit uint64
+}
+
+// Generate возвращает следующее значение счетчика, дополненное слева до длины length.
+func (g *counterGenerator) Generate(originalURL string, length, attempt int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", err
+	}
+	return encode(n, g.alphabet, length), nil
+}
+
+// next возвращает следующее значение счетчика, резервируя новый блок, когда текущий исчерпан.
+func (g *counterGenerator) next() (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.last == g.limit {
+		start, err := g.counter.ReserveCodes(context.Background(), codeBlockSize)
+		if err != nil {
+			return 0, err
+		}
+		g.last, g.limit = start, start+codeBlockSize
+	}
+	g.last++
+	return g.last, nil
+}
+
+// hashidsGenerator генерирует коды из счетчика, переставленного в пространстве кодов длины length,
+// поэтому соседние значения счетчика дают непохожие коды.
+type hashidsGenerator struct {
+	counterGenerator
+}
+
+// Generate возвращает перемешанное значение следующего значения счетчика.
+func (g *hashidsGenerator) Generate(originalURL string, length, attempt int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", err
+	}
+	space := keyspace(len(g.alphabet), length)
+	if space == 0 || n >= space {
+		// счетчик вышел за пространство кодов длины length, перестановка невозможна
+		return encode(n, g.alphabet, length), nil
+	}
+	return encode(permute(n, space), g.alphabet, length), nil
+}
+
+// hashGenerator генерирует код из хеша оригинального URL.
+type hashGenerator struct {
+	alphabet []rune
+}
+
+// Generate возвращает код из sha256 хеша URL и номера попытки.
+func (g *hashGenerator) Generate(originalURL string, length, attempt int) (string, error) {
+	sum := sha256.Sum256([]byte(originalURL + "#" + strconv.Itoa(attempt)))
+	base := big.NewInt(int64(len(g.alphabet)))
+	n := new(big.Int).SetBytes(sum[:])
+	mod := new(big.Int)
+	res := make([]rune, length)
+	for i := range res {
+		n.DivMod(n, base, mod)
+		res[i] = g.alphabet[mod.Int64()]
+	}
+	return string(res), nil
+}
+
+// encode записывает n в системе счисления алфавита, дополняя слева нулевым символом до длины length.
+func encode(n uint64, alphabet []rune, length int) string {
+	base := uint64(len(alphabet))
+	var res []rune
+	for n > 0 {
+		res = append(res, alphabet[n%base])
+		n /= base
+	}
+	for len(res) < length {
+		res = append(res, alphabet[0])
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return string(res)
+}
+
+// keyspace возвращает число кодов длины length или 0, если оно не помещается в uint64.
+func keyspace(base, length int) uint64 {
+	space := uint64(1)
+	for i := 0; i < length; i++ {
+		if space > ^uint64(0)/uint64(base) {
+			return 0
+		}
+		space *= uint64(base)
+	}
+	return space
+}
+
+// permute биективно отображает n на [0, space) умножением на число, взаимно простое со space.
+func permute(n, space uint64) uint64 {
+	const multiplier = 0x9E3779B97F4A7C15 // нечетная часть золотого сечения
+	m := multiplier % space
+	for gcd(m, space) != 1 {
+		m++
+	}
+	res := new(big.Int).Mul(new(big.Int).SetUint64(n), new(big.Int).SetUint64(m))
+	return res.Mod(res, new(big.Int).SetUint64(space)).Uint64()
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// shuffle детерминированно перемешивает алфавит по соли.
+func shuffle(alphabet []rune, salt string) []rune {
+	res := make([]rune, len(alphabet))
+	copy(res, alphabet)
+	if salt == "" {
+		return res
+	}
+	sum := sha256.Sum256([]byte(salt))
+	for i := len(res) - 1; i > 0; i-- {
+		j := int(binary.BigEndian.Uint32(sum[(i%8)*4:]) % uint32(i+1))
+		res[i], res[j] = res[j], res[i]
+		sum = sha256.Sum256(sum[:])
+	}
+	return res
+}
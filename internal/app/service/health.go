@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// HealthChecker - интерфейс проверки доступности оригинального URL.
+type HealthChecker interface {
+	// Check возвращает http статус ответа по адресу originalURL.
+	Check(ctx context.Context, originalURL string) (int, error)
+}
+
+// Настройки проверки доступности оригинальных URL.
+const (
+	// healthSweepInterval - период выбора ссылок для проверки.
+	healthSweepInterval = time.Minute
+	// healthBatchSize - максимальное число ссылок, проверяемых за один проход.
+	healthBatchSize = 500
+	// healthWorkers - число одновременных проверок.
+	healthWorkers = 8
+)
+
+// healthMonitor - настройки фоновой проверки доступности оригинальных URL.
+type healthMonitor struct {
+	checker HealthChecker
+	// interval - период повторной проверки каждой ссылки
+	interval time.Duration
+}
+
+// HandleHealthChecks периодически проверяет доступность оригинальных URL,
+// которые не проверялись дольше заданного интервала.
+func (s *Service) HandleHealthChecks() {
+	if s.health == nil {
+		return
+	}
+	ticker := time.NewTicker(min(healthSweepInterval, s.health.interval))
+	for range ticker.C {
+		checked, err := s.CheckLinksHealth(context.TODO())
+		if err != nil {
+			logger.Log.Info("cannot check links health", zap.Error(err))
+			continue
+		}
+		if checked > 0 {
+			logger.Log.Info("links health checked", zap.Int("count", checked))
+		}
+	}
+}
+
+// CheckLinksHealth выполняет один проход проверки доступности и возвращает число проверенных ссылок.
+// Неудачной считается только проверка с сетевой ошибкой или статусом 5xx: статусы 4xx (401, 403, 405 и т.п.)
+// часто отдаются роботам живыми страницами и не должны переводить ссылку на запасной адрес.
+func (s *Service) CheckLinksHealth(ctx context.Context) (int, error) {
+	if s.health == nil {
+		return 0, nil
+	}
+	links, err := s.repo.GetLinksToCheck(ctx, time.Now().Add(-s.health.interval), healthBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	jobs := make(chan [2]string)
+	var wg sync.WaitGroup
+	for range min(healthWorkers, len(links)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				s.checkLinkHealth(ctx, link[0], link[1])
+			}
+		}()
+	}
+	for shortURL, originalURL := range links {
+		jobs <- [2]string{shortURL, originalURL}
+	}
+	close(jobs)
+	wg.Wait()
+	return len(links), nil
+}
+
+// checkLinkHealth проверяет оригинальный URL и сохраняет результат.
+func (s *Service) checkLinkHealth(ctx context.Context, shortURL, originalURL string) {
+	status, err := s.health.checker.Check(ctx, originalURL)
+	healthy := err == nil && status < http.StatusInternalServerError
+	if err := s.repo.UpdateLinkHealth(ctx, shortURL, status, time.Now(), healthy); err != nil {
+		logger.Log.Info("cannot save link health", zap.String("shortURL", shortURL), zap.Error(err))
+	}
+}
+
+// destination возвращает адрес перехода по ссылке: запасной, если оригинальный URL мертв и запасной задан.
+func destination(originalURL string, attrs settings.LinkAttributes) string {
+	if attrs.FallbackURL != "" && attrs.Health.Dead() {
+		return attrs.FallbackURL
+	}
+	return originalURL
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/logger"
+)
+
+// MetadataFetcher - интерфейс загрузки сведений о странице назначения.
+type MetadataFetcher interface {
+	// Fetch загружает страницу по адресу pageURL и возвращает ее заголовок и метки OpenGraph.
+	Fetch(ctx context.Context, pageURL string) (settings.LinkMetadata, error)
+}
+
+// Настройки фоновой загрузки сведений о страницах назначения.
+const (
+	// metadataQueueSize - размер очереди загрузок, при ее заполнении новые загрузки отбрасываются.
+	metadataQueueSize = 1024
+	// metadataWorkers - число одновременных загрузок.
+	metadataWorkers = 4
+	// metadataTimeout - время ожидания одной загрузки.
+	metadataTimeout = 10 * time.Second
+)
+
+// metadataJob - ссылка, для которой нужно загрузить сведения о странице назначения.
+type metadataJob struct {
+	shortURL    string
+	originalURL string
+}
+
+// metadataLoader - очередь фоновой загрузки сведений о страницах назначения.
+type metadataLoader struct {
+	fetcher MetadataFetcher
+	jobs    chan metadataJob
+	dropped atomic.Int64
+}
+
+func newMetadataLoader(fetcher MetadataFetcher) *metadataLoader {
+	return &metadataLoader{
+		fetcher: fetcher,
+		jobs:    make(chan metadataJob, metadataQueueSize),
+	}
+}
+
+// enqueueMetadata ставит загрузку сведений о странице originalURL в очередь, не блокируя вызывающего.
+// Если загрузка не настроена или очередь заполнена, загрузка отбрасывается, ее можно повторить через RefetchMetadata.
+func (s *Service) enqueueMetadata(shortURL, originalURL string) {
+	if s.metadata == nil {
+		return
+	}
+	select {
+	case s.metadata.jobs <- metadataJob{shortURL: shortURL, originalURL: originalURL}:
+	default:
+		if s.metadata.dropped.Add(1)%metadataQueueSize == 1 {
+			logger.Log.Info("metadata queue is full, fetches dropped", zap.Int64("dropped", s.metadata.dropped.Load()))
+		}
+	}
+}
+
+// HandleMetadataFetches загружает сведения о страницах назначения из очереди в metadataWorkers потоков.
+func (s *Service) HandleMetadataFetches() {
+	if s.metadata == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for range metadataWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range s.metadata.jobs {
+				meta := s.fetchMetadata(context.Background(), job.originalURL, settings.LinkMetadata{})
+				err := s.repo.UpdateLinkMetadata(context.Background(), job.shortURL, job.originalURL, meta)
+				// адрес назначения изменили, пока шла загрузка: сведения о новом адресе загрузит своя задача
+				if err != nil && !errors.Is(err, settings.ErrVersionConflict) {
+					logger.Log.Info("cannot save link metadata", zap.String("shortURL", job.shortURL), zap.Error(err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// RefetchMetadata заново загружает сведения о странице назначения ссылки пользователя userID и возвращает их.
+func (s *Service) RefetchMetadata(ctx context.Context, shortURL, userID string) (settings.LinkMetadata, error) {
+	if s.metadata == nil {
+		return settings.LinkMetadata{}, settings.ErrMetadataDisabled
+	}
+	owner, err := s.repo.GetURLOwner(ctx, shortURL)
+	if err != nil {
+		return settings.LinkMetadata{}, err
+	}
+	if owner != userID {
+		return settings.LinkMetadata{}, settings.ErrNotOwner
+	}
+	originalURL, attrs, err := s.repo.GetLink(ctx, shortURL)
+	if err != nil {
+		return settings.LinkMetadata{}, err
+	}
+	meta := s.fetchMetadata(ctx, originalURL, attrs.Metadata)
+	if err := s.repo.UpdateLinkMetadata(ctx, shortURL, originalURL, meta); err != nil {
+		return settings.LinkMetadata{}, err
+	}
+	return meta, nil
+}
+
+// fetchMetadata загружает сведения о странице originalURL. При неудаче возвращает предыдущие сведения previous
+// с причиной неудачи, чтобы временная недоступность страницы не стирала загруженные ранее заголовок и метки.
+func (s *Service) fetchMetadata(ctx context.Context, originalURL string, previous settings.LinkMetadata) settings.LinkMetadata {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+	meta, err := s.metadata.fetcher.Fetch(ctx, originalURL)
+	if err != nil {
+		meta = previous
+		meta.Error = err.Error()
+	}
+	meta.FetchedAt = time.Now()
+	return meta
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// defaultPorts - порты по умолчанию, которые удаляются из канонической формы URL.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// urlNormalizer проверяет оригинальные URL и приводит их к канонической форме.
+type urlNormalizer struct {
+	allowedSchemes map[string]bool
+	sortQuery      bool
+}
+
+func newURLNormalizer() urlNormalizer {
+	return urlNormalizer{allowedSchemes: map[string]bool{"http": true, "https": true}}
+}
+
+// normalize возвращает каноническую форму URL: схема и хост в нижнем регистре,
+// без порта по умолчанию и, если включено, с отсортированными параметрами запроса.
+// Некорректные URL возвращают ошибку *settings.InvalidURLError.
+func (n urlNormalizer) normalize(rawURL string) (string, error) {
+	invalid := func(reason string) error {
+		return &settings.InvalidURLError{URL: rawURL, Reason: reason}
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", invalid(err.Error())
+	}
+	if u.Scheme == "" {
+		return "", invalid("scheme is missing")
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !n.allowedSchemes[scheme] {
+		return "", invalid("scheme " + scheme + " is not allowed")
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", invalid("host is missing")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return "", invalid("host is missing")
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 адрес без порта
+		host = "[" + host + "]"
+	}
+	u.Scheme = scheme
+	u.Host = host
+	if n.sortQuery && u.RawQuery != "" {
+		// Encode сортирует параметры по ключу
+		u.RawQuery = u.Query().Encode()
+	}
+	return u.String(), nil
+}
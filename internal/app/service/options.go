@@ -0,0 +1,130 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Option - функция для настройки сервиса при создании.
+type Option func(*Service)
+
+// WithAllowedSchemes задает допустимые схемы оригинальных URL.
+// Схемы передаются строкой через запятую, например "http,https".
+func WithAllowedSchemes(schemes string) Option {
+	return func(s *Service) {
+		s.normalizer.allowedSchemes = make(map[string]bool)
+		for _, scheme := range strings.Split(schemes, ",") {
+			scheme = strings.ToLower(strings.TrimSpace(scheme))
+			if scheme != "" {
+				s.normalizer.allowedSchemes[scheme] = true
+			}
+		}
+	}
+}
+
+// WithClickRepository включает сбор событий переходов по ссылкам в хранилище repo.
+func WithClickRepository(repo ClickRepository) Option {
+	return func(s *Service) {
+		s.clicks = newClickPipeline(repo)
+	}
+}
+
+// WithClickRetention задает, сколько хранить события переходов и скетчи посетителей.
+// Нулевое значение выключает удаление, статистика хранится бессрочно.
+func WithClickRetention(retention time.Duration) Option {
+	return func(s *Service) {
+		s.clickRetention = retention
+	}
+}
+
+// WithCodeGenerator задает генератор коротких кодов и длину генерируемых кодов.
+func WithCodeGenerator(codeGen CodeGenerator, length int) Option {
+	return func(s *Service) {
+		s.codeGen = codeGen
+		s.codeLength = length
+	}
+}
+
+// WithDeletedRetention задает, сколько хранить помеченные на удаление ссылки, прежде чем удалить их безвозвратно.
+// Нулевое значение выключает безвозвратное удаление.
+func WithDeletedRetention(retention time.Duration) Option {
+	return func(s *Service) {
+		s.deletedRetention = retention
+	}
+}
+
+// WithGeoResolver задает определение местоположения клиентов для событий переходов.
+func WithGeoResolver(geo GeoResolver) Option {
+	return func(s *Service) {
+		s.geo = geo
+	}
+}
+
+// WithHealthChecker включает фоновую проверку доступности оригинальных URL: каждая ссылка
+// проверяется не чаще одного раза в interval.
+func WithHealthChecker(checker HealthChecker, interval time.Duration) Option {
+	return func(s *Service) {
+		s.health = &healthMonitor{checker: checker, interval: interval}
+	}
+}
+
+// WithMetadataFetcher включает фоновую загрузку заголовка и меток OpenGraph страниц назначения
+// созданных ссылок и загрузку по запросу владельца.
+func WithMetadataFetcher(fetcher MetadataFetcher) Option {
+	return func(s *Service) {
+		s.metadata = newMetadataLoader(fetcher)
+	}
+}
+
+// WithInterstitial задает режим показа промежуточной страницы перед переходом по ссылкам.
+func WithInterstitial(mode settings.InterstitialMode) Option {
+	return func(s *Service) {
+		s.interstitial = mode
+	}
+}
+
+// WithMaxCodeLength задает предельную длину, до которой может вырасти длина генерируемых кодов
+// при большой доле коллизий.
+func WithMaxCodeLength(length int) Option {
+	return func(s *Service) {
+		s.maxCodeLength = length
+	}
+}
+
+// WithReservedCodes оставляет короткие урлы безвозвратно удаленных ссылок занятыми, чтобы их нельзя было выдать повторно.
+func WithReservedCodes(reserve bool) Option {
+	return func(s *Service) {
+		s.reserveCodes = reserve
+	}
+}
+
+// WithRedirectStatus задает http статус редиректа для ссылок, у которых он не указан.
+func WithRedirectStatus(status int) Option {
+	return func(s *Service) {
+		s.redirectStatus = status
+	}
+}
+
+// WithRedirectCacheMaxAge задает, сколько браузеры и общие кэши могут кэшировать постоянные редиректы.
+// Нулевое значение запрещает кэширование.
+func WithRedirectCacheMaxAge(maxAge time.Duration) Option {
+	return func(s *Service) {
+		s.redirectCacheMaxAge = maxAge
+	}
+}
+
+// WithURLPolicy задает проверку оригинальных URL по спискам запрещенных и разрешенных доменов.
+func WithURLPolicy(policy URLPolicy) Option {
+	return func(s *Service) {
+		s.policy = policy
+	}
+}
+
+// WithSortedQuery включает сортировку параметров запроса в оригинальных URL.
+func WithSortedQuery(sortQuery bool) Option {
+	return func(s *Service) {
+		s.normalizer.sortQuery = sortQuery
+	}
+}
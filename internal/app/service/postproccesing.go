@@ -9,6 +9,17 @@ import (
 	"go.uber.org/zap"
 )
 
+// Настройки очистки ссылок с истекшим сроком действия.
+const (
+	// expiredSweepInterval - период запуска очистки.
+	expiredSweepInterval = time.Minute
+	// expiredRetention - сколько хранить ссылку после истечения срока, отвечая на запросы 410.
+	expiredRetention = 24 * time.Hour
+)
+
+// deletedSweepInterval - период запуска безвозвратного удаления помеченных на удаление ссылок.
+const deletedSweepInterval = time.Hour
+
 // HandleRecords помечает на удаление короткие урлы, которые находятся в канале recordsForDel.
 func (s *Service) HandleRecords() {
 	// будем сохранять сообщения, накопленные за последние 5 секунд
@@ -37,3 +48,61 @@ func (s *Service) HandleRecords() {
 		}
 	}
 }
+
+// HandleExpiredRecords периодически удаляет из репозитория ссылки,
+// срок действия которых истек более expiredRetention назад, вместе с их статистикой переходов.
+func (s *Service) HandleExpiredRecords() {
+	ticker := time.NewTicker(expiredSweepInterval)
+	for range ticker.C {
+		deleted, err := s.repo.DeleteExpiredRecords(context.TODO(), time.Now().Add(-expiredRetention), s.reserveCodes)
+		if err == nil {
+			err = s.deleteLinkClicks(context.TODO(), deleted)
+		}
+		if err != nil {
+			logger.Log.Info("cannot delete expired records", zap.Error(err))
+			continue
+		}
+		if len(deleted) > 0 {
+			logger.Log.Info("expired records deleted", zap.Int("count", len(deleted)))
+		}
+	}
+}
+
+// HandleDeletedRecords периодически безвозвратно удаляет из репозитория ссылки,
+// помеченные на удаление более deletedRetention назад. При нулевом deletedRetention сразу завершается.
+func (s *Service) HandleDeletedRecords() {
+	if s.deletedRetention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(deletedSweepInterval)
+	for range ticker.C {
+		if _, err := s.PurgeDeletedRecords(context.TODO()); err != nil {
+			logger.Log.Info("cannot purge deleted records", zap.Error(err))
+		}
+	}
+}
+
+// PurgeDeletedRecords безвозвратно удаляет ссылки, помеченные на удаление более deletedRetention назад,
+// вместе с их статистикой переходов. Возвращает число удаленных ссылок.
+func (s *Service) PurgeDeletedRecords(ctx context.Context) (int, error) {
+	deleted, err := s.repo.PurgeDeletedRecords(ctx, time.Now().Add(-s.deletedRetention), s.reserveCodes)
+	if err == nil {
+		err = s.deleteLinkClicks(ctx, deleted)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(deleted) > 0 {
+		logger.Log.Info("deleted records purged", zap.Int("count", len(deleted)), zap.Bool("reserved", s.reserveCodes))
+	}
+	return len(deleted), nil
+}
+
+// deleteLinkClicks удаляет события переходов и скетчи посетителей безвозвратно удаленных ссылок,
+// чтобы ссылка, получившая освободившийся код, не унаследовала чужую статистику.
+func (s *Service) deleteLinkClicks(ctx context.Context, shortURLs []string) error {
+	if s.clicks == nil || len(shortURLs) == 0 {
+		return nil
+	}
+	return s.clicks.repo.DeleteLinkClicks(ctx, shortURLs...)
+}
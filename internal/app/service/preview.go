@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// GetLinkPreview возвращает сведения о ссылке для страницы предпросмотра, не засчитывая переход.
+// Для ссылок, защищенных паролем, адрес назначения не раскрывается.
+func (s *Service) GetLinkPreview(ctx context.Context, shortURL string) (settings.LinkPreview, error) {
+	originalURL, attrs, err := s.repo.GetLink(ctx, shortURL)
+	if err != nil {
+		return settings.LinkPreview{}, err
+	}
+	if err := s.checkPolicy(originalURL); err != nil {
+		return settings.LinkPreview{}, err
+	}
+	preview := settings.LinkPreview{
+		ShortURL:  shortURLWithHost(s.host, shortURL),
+		CreatedAt: attrs.CreatedAt,
+		Clicks:    attrs.Clicks,
+		Protected: attrs.PasswordHash != "",
+	}
+	if !preview.Protected {
+		preview.OriginalURL = originalURL
+		preview.Metadata = attrs.Metadata
+	}
+	return preview, nil
+}
+
+// needsInterstitial сообщает, нужно ли показать промежуточную страницу перед переходом на originalURL.
+func (s *Service) needsInterstitial(originalURL string, attrs settings.LinkAttributes) bool {
+	switch {
+	case attrs.Interstitial, s.interstitial == settings.InterstitialAlways:
+		return true
+	case s.interstitial == settings.InterstitialExternal:
+		return s.isExternal(originalURL)
+	}
+	return false
+}
+
+// isExternal сообщает, ведет ли originalURL на сторонний домен: не на хост сервиса и не на его поддомен.
+func (s *Service) isExternal(originalURL string) bool {
+	base, err := url.Parse(s.host)
+	if err != nil {
+		return true
+	}
+	dest, err := url.Parse(originalURL)
+	if err != nil {
+		return true
+	}
+	host, destHost := strings.ToLower(base.Hostname()), strings.ToLower(dest.Hostname())
+	return destHost != host && !strings.HasSuffix(destHost, "."+host)
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/qr"
+)
+
+// GetQRCode возвращает изображение QR кода полного короткого урла и его MIME тип.
+// Для недоступных ссылок возвращает те же ошибки, что и GetOriginalURL, переход при этом не засчитывается.
+func (s *Service) GetQRCode(ctx context.Context, shortURL string, opts settings.QROptions) ([]byte, string, error) {
+	opts, err := qr.Normalize(opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, _, err := s.repo.GetLink(ctx, shortURL); err != nil {
+		return nil, "", err
+	}
+	image, err := qr.Encode(shortURLWithHost(s.host, shortURL), opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return image, qr.ContentType(opts.Format), nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// DefaultRedirectCacheMaxAge - сколько по умолчанию кэшировать постоянные редиректы.
+const DefaultRedirectCacheMaxAge = 365 * 24 * time.Hour
+
+// redirect формирует редирект на адрес dest со статусом ссылки или статусом по умолчанию сервиса.
+// Кэшировать можно только постоянные редиректы ссылок, переход по которым всегда ведет на один адрес:
+// ссылки с лимитом переходов, сроком действия, паролем, запасным адресом, правилами маршрутизации
+// и вариантами не кэшируются.
+func (s *Service) redirect(dest string, attrs settings.LinkAttributes) settings.Redirect {
+	r := settings.Redirect{URL: dest, Status: attrs.RedirectStatus}
+	if r.Status == 0 {
+		r.Status = s.redirectStatus
+	}
+	if !settings.PermanentRedirect(r.Status) || attrs.MaxClicks > 0 || !attrs.ExpiresAt.IsZero() ||
+		attrs.PasswordHash != "" || attrs.FallbackURL != "" || len(attrs.Routing) > 0 || len(attrs.Variants) > 0 {
+		return r
+	}
+	r.CacheMaxAge = s.redirectCacheMaxAge
+	return r
+}
+
+// redirectURL формирует адрес перехода: добавляет к адресу назначения dest параметры запроса короткой ссылки
+// в режиме передачи ссылки и UTM метки по умолчанию, которых нет ни в адресе, ни в запросе.
+// Параметры адреса назначения остаются в исходном порядке и кодировке, из них убираются только
+// замененные параметрами запроса, новые добавляются в конец.
+func redirectURL(dest string, attrs settings.LinkAttributes, query url.Values) string {
+	utm := attrs.UTM.Values()
+	passthrough := attrs.QueryPassthrough == settings.QueryPassthroughMerge || attrs.QueryPassthrough == settings.QueryPassthroughPreserve
+	if (!passthrough || len(query) == 0) && len(utm) == 0 {
+		return dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return dest
+	}
+	values := u.Query()
+	added := make(url.Values)
+	replaced := make(map[string]bool)
+	if passthrough {
+		for name, value := range query {
+			if _, ok := values[name]; ok {
+				if attrs.QueryPassthrough == settings.QueryPassthroughPreserve {
+					continue
+				}
+				replaced[name] = true
+			}
+			added[name] = value
+		}
+	}
+	for name, value := range utm {
+		_, inDest := values[name]
+		_, inQuery := added[name]
+		if !inDest && !inQuery {
+			added[name] = value
+		}
+	}
+	if len(added) == 0 {
+		return dest
+	}
+	var parts []string
+	for part := range strings.SplitSeq(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(part, "=")
+		if name, err := url.QueryUnescape(name); part == "" || err == nil && replaced[name] {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	u.RawQuery = strings.Join(append(parts, added.Encode()), "&")
+	return u.String()
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// maxRoutingRules - предельное число правил маршрутизации у одной ссылки.
+const maxRoutingRules = 20
+
+// normalizeRouting проверяет правила маршрутизации и приводит их к канонической форме:
+// платформа и язык в нижнем регистре, страна в верхнем, адреса назначения нормализованы и проверены политикой доменов.
+func (s *Service) normalizeRouting(rules []settings.RoutingRule) ([]settings.RoutingRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > maxRoutingRules {
+		return nil, fmt.Errorf("%w: at most %d rules are allowed", settings.ErrInvalidRoutingRule, maxRoutingRules)
+	}
+	normalized := make([]settings.RoutingRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Platform = strings.ToLower(strings.TrimSpace(rule.Platform))
+		rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		switch rule.Platform {
+		case "", settings.PlatformIOS, settings.PlatformAndroid, settings.PlatformWindows, settings.PlatformMacOS, settings.PlatformLinux:
+		default:
+			return nil, fmt.Errorf("%w: rule %d: unknown platform %q", settings.ErrInvalidRoutingRule, i, rule.Platform)
+		}
+		if rule.Country != "" && len(rule.Country) != 2 {
+			return nil, fmt.Errorf("%w: rule %d: country must be an ISO 3166-1 alpha-2 code", settings.ErrInvalidRoutingRule, i)
+		}
+		if rule.Platform == "" && rule.Language == "" && rule.Country == "" {
+			return nil, fmt.Errorf("%w: rule %d has no conditions", settings.ErrInvalidRoutingRule, i)
+		}
+		url, err := s.normalizer.normalize(rule.URL)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if err := s.checkPolicy(url); err != nil {
+			return nil, err
+		}
+		rule.URL = url
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// route возвращает адрес назначения первого правила маршрутизации, которому подходит клиент.
+// Если ни одно правило не подошло, а у ссылки есть варианты адреса назначения, возвращает выбранный вариант и его имя.
+// Иначе возвращает адрес назначения ссылки по умолчанию.
+func (s *Service) route(shortURL, originalURL string, attrs settings.LinkAttributes, r settings.RedirectRequest) (string, string) {
+	if dest, ok := s.matchRoutingRule(attrs.Routing, r); ok {
+		return dest, ""
+	}
+	if len(attrs.Variants) > 0 {
+		v := pickVariant(shortURL, attrs.Variants, r)
+		return v.URL, v.Name
+	}
+	return destination(originalURL, attrs), ""
+}
+
+// matchRoutingRule возвращает адрес назначения первого правила маршрутизации, которому подходит клиент.
+// Страна клиента определяется не более одного раза и только если она нужна какому-то правилу.
+func (s *Service) matchRoutingRule(rules []settings.RoutingRule, r settings.RedirectRequest) (string, bool) {
+	if len(rules) == 0 {
+		return "", false
+	}
+	platform := platformFromUserAgent(r.UserAgent)
+	language := preferredLanguage(r.AcceptLanguage)
+	var (
+		country         string
+		countryResolved bool
+	)
+	for _, rule := range rules {
+		if rule.Platform != "" && rule.Platform != platform {
+			continue
+		}
+		if rule.Language != "" && language != rule.Language && !strings.HasPrefix(language, rule.Language+"-") {
+			continue
+		}
+		if rule.Country != "" {
+			if !countryResolved {
+				countryResolved = true
+				if s.geo != nil && r.IP != "" {
+					country = s.geo.Lookup(r.IP).Country
+				}
+			}
+			if rule.Country != country {
+				continue
+			}
+		}
+		return rule.URL, true
+	}
+	return "", false
+}
+
+// platformFromUserAgent определяет платформу клиента по заголовку User-Agent.
+// iOS проверяется раньше macOS, так как User-Agent iOS содержит "like Mac OS X".
+func platformFromUserAgent(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return settings.PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return settings.PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return settings.PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return settings.PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		return settings.PlatformLinux
+	}
+	return ""
+}
+
+// preferredLanguage возвращает язык клиента с наибольшим весом из заголовка Accept-Language в нижнем регистре.
+// При равных весах выбирается язык, указанный первым.
+func preferredLanguage(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			langs = append(langs, weighted{tag: tag, q: q})
+		}
+	}
+	if len(langs) == 0 {
+		return ""
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	return langs[0].tag
+}
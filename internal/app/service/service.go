@@ -3,53 +3,158 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
 	"errors"
-	"math/big"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/nasik90/url-shortener/cmd/shortener/settings"
 )
 
 // Интерфейс Repository описывает методы типа Repository.
 type Repository interface {
-	SaveShortURL(ctx context.Context, shortURL, originalURL, userID string) error
-	SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string) error
-	GetOriginalURL(ctx context.Context, shortURL string) (string, error)
+	SaveShortURL(ctx context.Context, shortURL, originalURL, userID string, attrs settings.LinkAttributes) error
+	// SaveShortURLs сохраняет записи, не конфликтующие с уже сохраненными, и возвращает ошибки по остальным:
+	// ключ - короткий урл, значение - settings.ErrShortURLNotUnique или settings.ErrOriginalURLNotUnique.
+	// Если занят один из коротких урлов aliases, не сохраняет ни одной записи и возвращает settings.ErrAliasNotUnique,
+	// а если оригинальный URL алиаса уже сокращен - settings.ErrOriginalURLNotUnique.
+	SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string, aliases map[string]bool) (map[string]error, error)
+	// GetOriginalURL возвращает оригинальный урл и атрибуты доступной ссылки и засчитывает переход,
+	// если accept не вернул ошибку; иначе возвращает ошибку accept. nil accept засчитывает переход без проверки.
+	GetOriginalURL(ctx context.Context, shortURL string, accept settings.AcceptRedirect) (string, settings.LinkAttributes, error)
+	GetLink(ctx context.Context, shortURL string) (string, settings.LinkAttributes, error)
 	Ping(ctx context.Context) error
 	Close() error
-	GetShortURL(ctx context.Context, originalURL string) (string, error)
-	GetUserURLs(ctx context.Context, userID string) (map[string]string, error)
+	GetShortURL(ctx context.Context, originalURL, userID string) (string, error)
+	// GetUserURLs возвращает не более q.Limit ссылок пользователя после позиции q.After в порядке q.Sort, q.Order.
+	GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) ([]settings.UserURL, error)
+	// GetLinksToCheck возвращает не более limit доступных ссылок, которые не проверялись с момента checkedBefore.
+	GetLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int) (map[string]string, error)
+	UpdateLinkHealth(ctx context.Context, shortURL string, status int, checkedAt time.Time, healthy bool) error
+	GetURLOwner(ctx context.Context, shortURL string) (string, error)
 	MarkRecordsForDeletion(ctx context.Context, records ...settings.Record) error
 	GetURLsCount(ctx context.Context) (int, error)
 	GetUsersCount(ctx context.Context) (int, error)
+	CodeCounter
+	// DeleteExpiredRecords и PurgeDeletedRecords безвозвратно удаляют записи, reserve оставляет их короткие урлы занятыми.
+	// Возвращают короткие урлы удаленных записей.
+	DeleteExpiredRecords(ctx context.Context, expiredBefore time.Time, reserve bool) ([]string, error)
+	PurgeDeletedRecords(ctx context.Context, deletedBefore time.Time, reserve bool) ([]string, error)
+	// RestoreShortURL снимает со ссылки пометку на удаление.
+	RestoreShortURL(ctx context.Context, shortURL string) error
+	// GetLinkVersions возвращает версии ссылки от первой до текущей.
+	GetLinkVersions(ctx context.Context, shortURL string) ([]settings.LinkVersion, error)
+	// UpdateLink применяет к ссылке версию, следующую за текущей, иначе возвращает settings.ErrVersionConflict.
+	// Вместе с версией атомарно меняются теги tags и папка folder, значения nil не меняются.
+	UpdateLink(ctx context.Context, shortURL string, version settings.LinkVersion, tags *[]string, folder *string) error
+	// UpdateLinkLabels меняет теги и папку ссылки, значения nil не меняются.
+	UpdateLinkLabels(ctx context.Context, shortURL string, tags *[]string, folder *string) error
+	// GetUserTags возвращает теги непомеченных на удаление ссылок пользователя с числом ссылок.
+	GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error)
+	// MergeTags заменяет теги from у ссылок пользователя тегом to и возвращает число измененных ссылок.
+	MergeTags(ctx context.Context, userID string, from []string, to string) (int, error)
+	// UpdateLinkMetadata сохраняет сведения о странице назначения ссылки, если она все еще ведет на originalURL,
+	// иначе возвращает settings.ErrVersionConflict.
+	UpdateLinkMetadata(ctx context.Context, shortURL, originalURL string, meta settings.LinkMetadata) error
+}
+
+// URLPolicy - интерфейс проверки оригинальных URL по спискам запрещенных и разрешенных доменов.
+type URLPolicy interface {
+	// Check возвращает ошибку, оборачивающую settings.ErrURLBlocked, если URL запрещен.
+	Check(originalURL string) error
 }
 
 // Service - структура, которая хранит ссылку на репозиторий, адрес хоста и канал для хранения URL`ов к удалению.
 type Service struct {
-	repo          Repository
-	host          string
-	recordsForDel chan settings.Record
+	repo             Repository
+	host             string
+	recordsForDel    chan settings.Record
+	passwordAttempts *attemptLimiter
+	normalizer       urlNormalizer
+	codeGen          CodeGenerator
+	codeLength       int
+	maxCodeLength    int
+	allocator        *codeAllocator
+	clicks           *clickPipeline
+	geo              GeoResolver
+	interstitial     settings.InterstitialMode
+	policy           URLPolicy
+	health           *healthMonitor
+	redirectStatus   int
+	// redirectCacheMaxAge - сколько браузеры и общие кэши могут кэшировать постоянные редиректы.
+	redirectCacheMaxAge time.Duration
+	deletedRetention    time.Duration
+	clickRetention      time.Duration
+	reserveCodes        bool
+	metadata            *metadataLoader
 }
 
 // NewService создает экземпляр объекта типа Service.
-func NewService(store Repository, host string) *Service {
-	return &Service{repo: store, host: host, recordsForDel: make(chan settings.Record)}
+func NewService(store Repository, host string, opts ...Option) *Service {
+	s := &Service{
+		repo:                store,
+		host:                host,
+		recordsForDel:       make(chan settings.Record),
+		passwordAttempts:    newAttemptLimiter(),
+		normalizer:          newURLNormalizer(),
+		codeGen:             &randomGenerator{alphabet: []rune(settings.TemplateForRand)},
+		codeLength:          settings.ShortURLlen,
+		interstitial:        settings.InterstitialOff,
+		redirectStatus:      http.StatusTemporaryRedirect,
+		redirectCacheMaxAge: DefaultRedirectCacheMaxAge,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.allocator = newCodeAllocator(s.codeLength, s.maxCodeLength)
+	return s
 }
 
 // GetShortURL - реализует логику по получению короткой ссылки по оригинальной.
-func (s *Service) GetShortURL(ctx context.Context, originalURL, userID string) (string, error) {
-	shortURL, err := newShortURL()
+// Если в opts передан алиас, он используется в качестве короткого кода.
+// Оригинальный URL сохраняется в канонической форме.
+func (s *Service) GetShortURL(ctx context.Context, originalURL, userID string, opts settings.LinkOptions) (string, error) {
+	originalURL, err := s.normalizer.normalize(originalURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkPolicy(originalURL); err != nil {
+		return "", err
+	}
+	if opts.Alias != "" {
+		if err := validateAlias(opts.Alias); err != nil {
+			return "", err
+		}
+	}
+	attrs, err := linkAttributes(opts, time.Now())
 	if err != nil {
 		return "", err
 	}
-	err = s.repo.SaveShortURL(ctx, shortURL, originalURL, userID)
-	for errors.Is(err, settings.ErrShortURLNotUnique) {
-		err = s.repo.SaveShortURL(ctx, shortURL, originalURL, userID)
+	if attrs.FallbackURL, err = s.normalizeFallbackURL(attrs.FallbackURL); err != nil {
+		return "", err
+	}
+	if attrs.Routing, err = s.normalizeRouting(opts.Routing); err != nil {
+		return "", err
+	}
+	if attrs.Variants, err = s.normalizeVariants(opts.Variants); err != nil {
+		return "", err
+	}
+	var shortURL string
+	if opts.Alias != "" {
+		shortURL = opts.Alias
+		err = s.repo.SaveShortURL(ctx, shortURL, originalURL, userID, attrs)
+		if errors.Is(err, settings.ErrShortURLNotUnique) {
+			return "", settings.ErrAliasNotUnique
+		}
+	} else {
+		shortURL, err = s.allocateShortURL(ctx, originalURL, userID, attrs)
 	}
 
 	if err != nil {
 		if errors.Is(err, settings.ErrOriginalURLNotUnique) {
-			shortURL, err = s.repo.GetShortURL(ctx, originalURL)
+			shortURL, err = s.repo.GetShortURL(ctx, originalURL, userID)
 			if err != nil {
 				return "", err
 			}
@@ -60,76 +165,291 @@ func (s *Service) GetShortURL(ctx context.Context, originalURL, userID string) (
 		}
 	}
 
+	s.enqueueMetadata(shortURL, originalURL)
 	shortURLWithHost := shortURLWithHost(s.host, shortURL)
 	return shortURLWithHost, nil
 }
 
 // GetOriginalURL - реализует логику по получению оригинальной ссылки по короткому
-func (s *Service) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
-	originalURL, err := s.repo.GetOriginalURL(ctx, shortURL)
+// Для ссылок с паролем возвращает ошибку settings.ErrPasswordRequired.
+// Если перед переходом нужна промежуточная страница, а переход не подтвержден, возвращает settings.ErrInterstitialRequired.
+// Адрес назначения выбирается правилами маршрутизации ссылки по клиенту, затем вариантами A/B теста.
+// Если оригинальный URL мертв и у ссылки задан запасной адрес, возвращает запасной адрес.
+// Вместе с адресом возвращаются http статус редиректа и допустимое время его кэширования.
+// Ссылка читается и переход засчитывается одним вызовом хранилища.
+func (s *Service) GetOriginalURL(ctx context.Context, shortURL string, r settings.RedirectRequest) (settings.Redirect, error) {
+	var dest, variant string
+	_, attrs, err := s.repo.GetOriginalURL(ctx, shortURL, func(originalURL string, attrs settings.LinkAttributes) error {
+		dest, variant = s.route(shortURL, originalURL, attrs, r)
+		if err := s.checkPolicy(dest); err != nil {
+			return err
+		}
+		if attrs.PasswordHash != "" {
+			return settings.ErrPasswordRequired
+		}
+		if !r.Confirmed && s.needsInterstitial(dest, attrs) {
+			return settings.ErrInterstitialRequired
+		}
+		return nil
+	})
 	if err != nil {
-		return "", err
+		return settings.Redirect{}, err
 	}
-	return originalURL, nil
+	redirect := s.redirect(redirectURL(dest, attrs, r.Query), attrs)
+	redirect.Variant = variant
+	return redirect, nil
 }
 
-func randomString(charCount int) (res string, err error) {
-	template := []rune(settings.TemplateForRand)
-	templateLen := len(template)
-	resChar := make([]rune, charCount)
-	for i := 0; i < charCount; i++ {
+// GetProtectedOriginalURL - реализует логику по получению оригинальной ссылки по короткому с проверкой пароля.
+// Число неудачных попыток для каждого короткого урла ограничено.
+// Ввод пароля считается подтверждением перехода, промежуточная страница не показывается.
+// Адрес назначения выбирается правилами маршрутизации ссылки по клиенту из r.
+func (s *Service) GetProtectedOriginalURL(ctx context.Context, shortURL, password string, r settings.RedirectRequest) (settings.Redirect, error) {
+	var dest, variant string
+	_, attrs, err := s.repo.GetOriginalURL(ctx, shortURL, func(originalURL string, attrs settings.LinkAttributes) error {
+		dest, variant = s.route(shortURL, originalURL, attrs, r)
+		if err := s.checkPolicy(dest); err != nil {
+			return err
+		}
+		if attrs.PasswordHash == "" {
+			return nil
+		}
+		// попытка засчитывается до проверки пароля и снимается при успехе
+		windowStart, ok := s.passwordAttempts.reserve(shortURL, time.Now())
+		if !ok {
+			return settings.ErrTooManyAttempts
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(attrs.PasswordHash), []byte(password)); err != nil {
+			return settings.ErrWrongPassword
+		}
+		s.passwordAttempts.release(shortURL, windowStart)
+		return nil
+	})
+	if err != nil {
+		return settings.Redirect{}, err
+	}
+	redirect := s.redirect(redirectURL(dest, attrs, r.Query), attrs)
+	redirect.Variant = variant
+	return redirect, nil
+}
 
-		r, err := rand.Int(rand.Reader, big.NewInt(int64(templateLen)))
-		if err != nil {
-			return res, err
+// linkAttributes формирует атрибуты сохраняемой ссылки из переданных параметров.
+func linkAttributes(opts settings.LinkOptions, now time.Time) (settings.LinkAttributes, error) {
+	var attrs settings.LinkAttributes
+	switch {
+	case !opts.ExpiresAt.IsZero() && opts.TTL != 0:
+		return attrs, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", settings.ErrInvalidExpiration)
+	case opts.TTL < 0:
+		return attrs, fmt.Errorf("%w: ttl must be positive", settings.ErrInvalidExpiration)
+	case opts.TTL > 0:
+		attrs.ExpiresAt = now.Add(opts.TTL)
+	case !opts.ExpiresAt.IsZero():
+		if !opts.ExpiresAt.After(now) {
+			return attrs, fmt.Errorf("%w: expires_at is in the past", settings.ErrInvalidExpiration)
+		}
+		attrs.ExpiresAt = opts.ExpiresAt
+	}
+	if opts.MaxClicks < 0 {
+		return attrs, fmt.Errorf("%w: max_clicks must not be negative", settings.ErrInvalidMaxClicks)
+	}
+	attrs.MaxClicks = opts.MaxClicks
+	hash, err := hashPassword(opts.Password)
+	if err != nil {
+		return attrs, err
+	}
+	attrs.PasswordHash = hash
+	attrs.Interstitial = opts.Interstitial
+	attrs.FallbackURL = opts.FallbackURL
+	passthrough, err := settings.ParseQueryPassthrough(opts.QueryPassthrough)
+	if err != nil {
+		return attrs, err
+	}
+	attrs.QueryPassthrough = passthrough
+	attrs.UTM = opts.UTM
+	if opts.RedirectStatus != 0 {
+		if err := settings.ValidateRedirectStatus(opts.RedirectStatus); err != nil {
+			return attrs, err
 		}
-		resChar[i] = template[int(r.Int64())]
 	}
+	attrs.RedirectStatus = opts.RedirectStatus
+	if attrs.Tags, err = normalizeTags(opts.Tags); err != nil {
+		return attrs, err
+	}
+	if attrs.Folder, err = normalizeFolder(opts.Folder); err != nil {
+		return attrs, err
+	}
+	attrs.CreatedAt = now
+	return attrs, nil
+}
+
+// hashPassword возвращает bcrypt хеш пароля ссылки. Пустой пароль означает ссылку без пароля.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", settings.ErrInvalidPassword, err)
+	}
+	return string(hash), nil
+}
+
+// normalizeFallbackURL нормализует запасной адрес ссылки и проверяет его политикой доменов.
+// Пустой адрес означает ссылку без запасного адреса.
+func (s *Service) normalizeFallbackURL(fallbackURL string) (string, error) {
+	if fallbackURL == "" {
+		return "", nil
+	}
+	fallbackURL, err := s.normalizer.normalize(fallbackURL)
+	if err != nil {
+		return "", err
+	}
+	return fallbackURL, s.checkPolicy(fallbackURL)
+}
 
-	return string(resChar), nil
+// checkPolicy проверяет оригинальный URL политикой доменов, если она настроена.
+// Проверка выполняется и при сохранении, и при переходе, чтобы ссылки на новые запрещенные домены перестали работать.
+func (s *Service) checkPolicy(originalURL string) error {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy.Check(originalURL)
 }
 
 func shortURLWithHost(host, randomString string) string {
 	return host + "/" + randomString
 }
 
-func newShortURL() (string, error) {
-	return randomString(settings.ShortURLlen)
+// allocateShortURL сохраняет оригинальный урл под сгенерированным коротким кодом.
+// При коллизии генерирует новый код, число попыток ограничено maxAllocAttempts.
+func (s *Service) allocateShortURL(ctx context.Context, originalURL, userID string, attrs settings.LinkAttributes) (string, error) {
+	for attempt := 0; attempt < maxAllocAttempts; attempt++ {
+		length := s.allocator.currentLength()
+		shortURL, err := s.codeGen.Generate(originalURL, length, attempt)
+		if err != nil {
+			return "", err
+		}
+		err = s.repo.SaveShortURL(ctx, shortURL, originalURL, userID, attrs)
+		collided := errors.Is(err, settings.ErrShortURLNotUnique)
+		s.allocator.observe(length, collided)
+		if !collided {
+			return shortURL, err
+		}
+	}
+	return "", settings.ErrShortURLAllocation
 }
 
 // GetShortURLs - реализует логику по получению списка коротких ссылок по переданным коротким.
-// На входе принимает мапу, где ключ - id, значение - оригинальный урл.
+// На входе принимает мапу, где ключ - id, значение - оригинальный урл и необязательный алиас.
 // На выходе тот же id, значение - короткий урл.
-func (s *Service) GetShortURLs(ctx context.Context, originalURLs map[string]string, userID string) (map[string]string, error) {
+// Коллизии сгенерированных кодов и повторы оригинальных урлов разрешаются для каждой записи отдельно.
+// Алиас не заменяется существующей ссылкой: если его оригинальный урл уже сокращен, пакет не сохраняется.
+func (s *Service) GetShortURLs(ctx context.Context, originalURLs map[string]settings.BatchURL, userID string) (map[string]string, error) {
 	shortURLs := make(map[string]string)
-	shortOriginalURLs := make(map[string]string)
+	// pending - записи, ожидающие сохранения, ключ - короткий урл
+	pending := make(map[string]batchItem)
+	// aliases - короткие урлы, заданные пользователем: занятый алиас отменяет сохранение всего пакета
+	aliases := make(map[string]bool)
 
-	for id, shortOriginalURL := range originalURLs {
-		shortURL, err := newShortURL()
+	length := s.allocator.currentLength()
+	for id, batchURL := range originalURLs {
+		originalURL, err := s.normalizer.normalize(batchURL.OriginalURL)
 		if err != nil {
 			return shortURLs, err
 		}
-		shortURLWithHost := shortURLWithHost(s.host, shortURL)
-		shortURLs[id] = shortURLWithHost
-		shortOriginalURLs[shortURL] = shortOriginalURL
+		if err := s.checkPolicy(originalURL); err != nil {
+			return shortURLs, err
+		}
+		item := batchItem{id: id, originalURL: originalURL, alias: batchURL.Alias != ""}
+		shortURL := batchURL.Alias
+		if item.alias {
+			if err := validateAlias(shortURL); err != nil {
+				return shortURLs, err
+			}
+			if _, ok := pending[shortURL]; ok {
+				return shortURLs, settings.ErrAliasNotUnique
+			}
+			aliases[shortURL] = true
+		} else {
+			shortURL, err = s.generateBatchCode(pending, &item, length)
+			if err != nil {
+				return shortURLs, err
+			}
+		}
+		pending[shortURL] = item
 	}
-	err := s.repo.SaveShortURLs(ctx, shortOriginalURLs, userID)
-	return shortURLs, err
-}
 
-// GetUserURLs - реализует логику получения списка коротких и оригинальных урлов пользователя.
-// На входе id пользователя, на выходе мапа(ключ - короткий урл, значение - оригинальный).
-func (s *Service) GetUserURLs(ctx context.Context, userID string) (map[string]string, error) {
-	data := make(map[string]string)
-	userURLs, err := s.repo.GetUserURLs(ctx, userID)
-	if err != nil {
-		return data, err
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt == maxAllocAttempts {
+			return shortURLs, settings.ErrShortURLAllocation
+		}
+		shortOriginalURLs := make(map[string]string, len(pending))
+		for shortURL, item := range pending {
+			shortOriginalURLs[shortURL] = item.originalURL
+		}
+		conflicts, err := s.repo.SaveShortURLs(ctx, shortOriginalURLs, userID, aliases)
+		if err != nil {
+			return shortURLs, err
+		}
+		// алиасы сохранены в первой попытке, повторно сохраняются только сгенерированные коды
+		aliases = nil
+		retry := make(map[string]batchItem)
+		for shortURL, item := range pending {
+			conflict := conflicts[shortURL]
+			if !item.alias {
+				s.allocator.observe(length, errors.Is(conflict, settings.ErrShortURLNotUnique))
+			}
+			switch {
+			case conflict == nil:
+				shortURLs[item.id] = shortURLWithHost(s.host, shortURL)
+				s.enqueueMetadata(shortURL, item.originalURL)
+			case errors.Is(conflict, settings.ErrOriginalURLNotUnique):
+				existing, err := s.repo.GetShortURL(ctx, item.originalURL, userID)
+				if err != nil {
+					return shortURLs, err
+				}
+				shortURLs[item.id] = shortURLWithHost(s.host, existing)
+			case errors.Is(conflict, settings.ErrShortURLNotUnique):
+				retry[shortURL] = item
+			default:
+				return shortURLs, conflict
+			}
+		}
+		pending = make(map[string]batchItem, len(retry))
+		length = s.allocator.currentLength()
+		for _, item := range retry {
+			shortURL, err := s.generateBatchCode(pending, &item, length)
+			if err != nil {
+				return shortURLs, err
+			}
+			pending[shortURL] = item
+		}
 	}
-	for shortURL, originalURL := range userURLs {
-		shortURLWithHost := shortURLWithHost(s.host, shortURL)
-		data[shortURLWithHost] = originalURL
+	return shortURLs, nil
+}
+
+// batchItem - запись пакетного сохранения.
+type batchItem struct {
+	id          string
+	originalURL string
+	alias       bool
+	// attempt - номер следующей попытки генерации кода для записи
+	attempt int
+}
+
+// generateBatchCode генерирует код, не совпадающий с уже выбранными в пакете.
+func (s *Service) generateBatchCode(pending map[string]batchItem, item *batchItem, length int) (string, error) {
+	for i := 0; i < maxAllocAttempts; i++ {
+		shortURL, err := s.codeGen.Generate(item.originalURL, length, item.attempt)
+		item.attempt++
+		if err != nil {
+			return "", err
+		}
+		if _, ok := pending[shortURL]; !ok {
+			return shortURL, nil
+		}
 	}
-	return data, err
+	return "", settings.ErrShortURLAllocation
 }
 
 // MarkRecordsForDeletion - реализует логику пометки на удаление переданный коротких урл пользователя.
@@ -144,21 +464,40 @@ func (s *Service) MarkRecordsForDeletion(ctx context.Context, shortURLs []string
 	}
 }
 
+// RestoreShortURL - снимает пометку на удаление со ссылки пользователя userID, если ее еще не удалили безвозвратно.
+func (s *Service) RestoreShortURL(ctx context.Context, shortURL, userID string) error {
+	owner, err := s.repo.GetURLOwner(ctx, shortURL)
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return settings.ErrNotOwner
+	}
+	return s.repo.RestoreShortURL(ctx, shortURL)
+}
+
 // Ping - пингует БД.
 func (s *Service) Ping(ctx context.Context) error {
 	return s.repo.Ping(ctx)
 }
 
 // GetURLsStats подсчитывает количество коротких урлов и пользователей в сервисе.
-// Возвращает число коротких урлов и число пользователей.
-func (s *Service) GetURLsStats(ctx context.Context) (urls, users int, err error) {
+// Возвращает число коротких урлов, число пользователей и оценку числа уникальных посетителей ссылок за все время.
+func (s *Service) GetURLsStats(ctx context.Context) (urls, users int, visitors int64, err error) {
 	URLsCount, err := s.repo.GetURLsCount(ctx)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 	UsersCount, err := s.repo.GetUsersCount(ctx)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
+	}
+	if s.clicks != nil {
+		sketch, err := s.clicks.repo.GetVisitorSketch(ctx, "", time.Time{}, time.Now())
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		visitors = int64(sketch.Estimate())
 	}
-	return URLsCount, UsersCount, nil
+	return URLsCount, UsersCount, visitors, nil
 }
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Ограничения запроса статистики переходов.
+const (
+	// statsDefaultRange - интервал статистики, если не задано начало.
+	statsDefaultRange = 30 * 24 * time.Hour
+	// statsDefaultTop - размер топов по умолчанию.
+	statsDefaultTop = 10
+	// statsMaxTop - максимальный размер топов.
+	statsMaxTop = 100
+	// statsMaxPoints - максимальное число точек временного ряда.
+	statsMaxPoints = 10000
+)
+
+// GetLinkStats возвращает статистику переходов по короткому урлу.
+// Статистика доступна только пользователю, создавшему ссылку.
+func (s *Service) GetLinkStats(ctx context.Context, shortURL, userID string, q settings.StatsQuery) (settings.LinkStats, error) {
+	var stats settings.LinkStats
+	owner, err := s.repo.GetURLOwner(ctx, shortURL)
+	if err != nil {
+		return stats, err
+	}
+	if owner != userID {
+		return stats, settings.ErrNotOwner
+	}
+	q, err = normalizeStatsQuery(q, time.Now())
+	if err != nil {
+		return stats, err
+	}
+	if s.clicks != nil {
+		stats, err = s.clicks.repo.GetClickStats(ctx, shortURL, q)
+		if err != nil {
+			return stats, err
+		}
+		visitors, err := s.clicks.repo.GetVisitorSketch(ctx, shortURL, q.From, q.To)
+		if err != nil {
+			return stats, err
+		}
+		stats.UniqueVisitors = int64(visitors.Estimate())
+	}
+	stats.Series = fillSeries(stats.Series, q)
+	return stats, nil
+}
+
+// normalizeStatsQuery проверяет параметры запроса статистики и заполняет значения по умолчанию.
+func normalizeStatsQuery(q settings.StatsQuery, now time.Time) (settings.StatsQuery, error) {
+	switch q.Bucket {
+	case "":
+		q.Bucket = settings.StatsBucketDay
+	case settings.StatsBucketHour, settings.StatsBucketDay:
+	default:
+		return q, fmt.Errorf("%w: unknown bucket %q", settings.ErrInvalidStatsQuery, q.Bucket)
+	}
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-statsDefaultRange)
+	}
+	if !q.From.Before(q.To) {
+		return q, fmt.Errorf("%w: from must be before to", settings.ErrInvalidStatsQuery)
+	}
+	if q.To.Sub(q.From)/q.Bucket.Duration() > statsMaxPoints {
+		return q, fmt.Errorf("%w: range is too long for bucket %s", settings.ErrInvalidStatsQuery, q.Bucket)
+	}
+	switch {
+	case q.Top == 0:
+		q.Top = statsDefaultTop
+	case q.Top < 0 || q.Top > statsMaxTop:
+		return q, fmt.Errorf("%w: top must be between 1 and %d", settings.ErrInvalidStatsQuery, statsMaxTop)
+	}
+	return q, nil
+}
+
+// fillSeries дополняет временной ряд нулевыми точками для шагов без переходов.
+func fillSeries(series []settings.StatsPoint, q settings.StatsQuery) []settings.StatsPoint {
+	step := q.Bucket.Duration()
+	clicks := make(map[time.Time]int64, len(series))
+	for _, point := range series {
+		clicks[point.Time.UTC()] = point.Clicks
+	}
+	var res []settings.StatsPoint
+	for t := q.From.UTC().Truncate(step); t.Before(q.To); t = t.Add(step) {
+		res = append(res, settings.StatsPoint{Time: t, Clicks: clicks[t]})
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Ограничения тегов и папок ссылок.
+const (
+	maxTags      = 20
+	maxTagLen    = 64
+	maxFolderLen = 128
+)
+
+// normalizeTags убирает пробелы по краям тегов, пустые теги и повторы, сохраняя порядок.
+// Возвращает settings.ErrInvalidTags, если тегов больше maxTags или тег длиннее maxTagLen символов.
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", settings.ErrInvalidTags, maxTags)
+	}
+	return normalized, nil
+}
+
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if utf8.RuneCountInString(tag) > maxTagLen {
+		return "", fmt.Errorf("%w: tag %q is longer than %d characters", settings.ErrInvalidTags, tag, maxTagLen)
+	}
+	return tag, nil
+}
+
+// normalizeFolder убирает пробелы по краям папки. Пустая папка означает ссылку без папки.
+func normalizeFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if utf8.RuneCountInString(folder) > maxFolderLen {
+		return "", fmt.Errorf("%w: folder is longer than %d characters", settings.ErrInvalidTags, maxFolderLen)
+	}
+	return folder, nil
+}
+
+// GetUserTags возвращает теги ссылок пользователя userID с числом ссылок, от самых частых.
+// Помеченные на удаление ссылки не учитываются.
+func (s *Service) GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error) {
+	return s.repo.GetUserTags(ctx, userID)
+}
+
+// MergeTags заменяет теги from у всех ссылок пользователя userID тегом to.
+// Переименование тега - слияние одного тега. Если у ссылки уже есть тег to, повтор не добавляется.
+// Возвращает число измененных ссылок.
+func (s *Service) MergeTags(ctx context.Context, userID string, from []string, to string) (int, error) {
+	from, err := normalizeTags(from)
+	if err != nil {
+		return 0, err
+	}
+	if to, err = normalizeTag(to); err != nil {
+		return 0, err
+	}
+	if len(from) == 0 || to == "" {
+		return 0, fmt.Errorf("%w: source and target tags are required", settings.ErrInvalidTags)
+	}
+	return s.repo.MergeTags(ctx, userID, from, to)
+}
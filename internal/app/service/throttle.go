@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Настройки ограничения попыток ввода пароля.
+const (
+	// passwordMaxAttempts - число неудачных попыток, после которого ввод пароля блокируется.
+	passwordMaxAttempts = 5
+	// passwordAttemptsWindow - окно, в котором считаются неудачные попытки, и время блокировки.
+	passwordAttemptsWindow = 15 * time.Minute
+	// attemptsSweepSize - размер мапы счетчиков, при котором из нее удаляются устаревшие записи.
+	attemptsSweepSize = 10000
+)
+
+type attempts struct {
+	failures    int
+	windowStart time.Time
+}
+
+// attemptLimiter считает неудачные попытки ввода пароля для каждого короткого урла.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	attempts map[string]attempts
+}
+
+func newAttemptLimiter() *attemptLimiter {
+	return &attemptLimiter{attempts: make(map[string]attempts)}
+}
+
+// reserve засчитывает попытку ввода пароля до его проверки и сообщает, разрешена ли она.
+// Проверка и учет выполняются под одной блокировкой, поэтому одновременные запросы не могут
+// проверить пароль больше passwordMaxAttempts раз за окно. Возвращает начало окна, в котором
+// засчитана попытка: по нему успешная попытка снимается через release.
+func (a *attemptLimiter) reserve(shortURL string, now time.Time) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.attempts) >= attemptsSweepSize {
+		a.sweepLocked(now)
+	}
+	at, ok := a.attempts[shortURL]
+	if !ok || now.Sub(at.windowStart) >= passwordAttemptsWindow {
+		at = attempts{windowStart: now}
+	}
+	if at.failures >= passwordMaxAttempts {
+		return time.Time{}, false
+	}
+	at.failures++
+	a.attempts[shortURL] = at
+	return at.windowStart, true
+}
+
+// sweepLocked удаляет устаревшие счетчики, чтобы размер мапы оставался ограниченным.
+func (a *attemptLimiter) sweepLocked(now time.Time) {
+	for shortURL, at := range a.attempts {
+		if now.Sub(at.windowStart) >= passwordAttemptsWindow {
+			delete(a.attempts, shortURL)
+		}
+	}
+}
+
+// release снимает попытку, засчитанную reserve в окне windowStart, после успешного ввода пароля.
+// Неудачные попытки других клиентов не сбрасываются и истекают вместе с окном, иначе любой
+// верный ввод пароля давал бы подбирающему пароль новый запас попыток.
+func (a *attemptLimiter) release(shortURL string, windowStart time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at, ok := a.attempts[shortURL]
+	if !ok || !at.windowStart.Equal(windowStart) || at.failures == 0 {
+		return
+	}
+	at.failures--
+	a.attempts[shortURL] = at
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// Размер страницы урлов пользователя. Размер по умолчанию действует, только если передан курсор:
+// без limit и курсора выдаются все ссылки, как до постраничной выдачи.
+const (
+	userURLsDefaultLimit = 100
+	userURLsMaxLimit     = 1000
+)
+
+// userURLsCursor - содержимое курсора страницы урлов пользователя.
+// Сортировка хранится в курсоре, чтобы курсор нельзя было применить к выборке в другом порядке.
+type userURLsCursor struct {
+	Sort  settings.UserURLsSort `json:"sort"`
+	Order settings.SortOrder    `json:"order"`
+	settings.UserURLsCursor
+}
+
+// GetUserURLs - реализует логику получения списка ссылок пользователя.
+// На входе id пользователя и параметры выборки, на выходе страница ссылок с полными короткими урлами
+// и курсор следующей страницы.
+func (s *Service) GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) (settings.UserURLsPage, error) {
+	var page settings.UserURLsPage
+	q, err := normalizeUserURLsQuery(q)
+	if err != nil {
+		return page, err
+	}
+	limit := q.Limit
+	// лишняя ссылка показывает, что за страницей есть следующая
+	if limit > 0 {
+		q.Limit++
+	}
+	userURLs, err := s.repo.GetUserURLs(ctx, userID, q)
+	if err != nil {
+		return page, err
+	}
+	if limit > 0 && len(userURLs) > limit {
+		userURLs = userURLs[:limit]
+		if page.NextCursor, err = encodeUserURLsCursor(q, userURLs[limit-1]); err != nil {
+			return page, err
+		}
+	}
+	for i := range userURLs {
+		userURLs[i].ShortURL = shortURLWithHost(s.host, userURLs[i].ShortURL)
+	}
+	page.URLs = userURLs
+	return page, nil
+}
+
+// normalizeUserURLsQuery проверяет параметры выборки урлов пользователя, заполняет значения по умолчанию
+// и разбирает курсор. По умолчанию ссылки выдаются от новых к старым без помеченных на удаление.
+func normalizeUserURLsQuery(q settings.UserURLsQuery) (settings.UserURLsQuery, error) {
+	switch {
+	case q.Limit == 0 && q.Cursor == "":
+		// без limit выдаются все ссылки, чтобы клиенты без постраничной выдачи не получали урезанный список
+	case q.Limit == 0:
+		q.Limit = userURLsDefaultLimit
+	case q.Limit < 0 || q.Limit > userURLsMaxLimit:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", settings.ErrInvalidUserURLsQuery, userURLsMaxLimit)
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = settings.UserURLsSortCreated
+	case settings.UserURLsSortCreated, settings.UserURLsSortClicks:
+	default:
+		return q, fmt.Errorf("%w: unknown sort %q", settings.ErrInvalidUserURLsQuery, q.Sort)
+	}
+	switch q.Order {
+	case "":
+		q.Order = settings.SortDesc
+	case settings.SortAsc, settings.SortDesc:
+	default:
+		return q, fmt.Errorf("%w: unknown order %q", settings.ErrInvalidUserURLsQuery, q.Order)
+	}
+	var err error
+	if q.Deleted, err = normalizeLinkFilter(q.Deleted, settings.LinkFilterExclude); err != nil {
+		return q, fmt.Errorf("deleted: %w", err)
+	}
+	if q.Expired, err = normalizeLinkFilter(q.Expired, settings.LinkFilterInclude); err != nil {
+		return q, fmt.Errorf("expired: %w", err)
+	}
+	q.After = nil
+	if q.Cursor != "" {
+		cursor, err := decodeUserURLsCursor(q.Cursor)
+		if err != nil {
+			return q, err
+		}
+		if cursor.Sort != q.Sort || cursor.Order != q.Order {
+			return q, fmt.Errorf("%w: cursor belongs to a different sort order", settings.ErrInvalidUserURLsQuery)
+		}
+		q.After = &cursor.UserURLsCursor
+	}
+	return q, nil
+}
+
+// normalizeLinkFilter проверяет режим отбора ссылок. Пустой режим заменяется на def.
+func normalizeLinkFilter(f, def settings.LinkFilter) (settings.LinkFilter, error) {
+	switch f {
+	case "":
+		return def, nil
+	case settings.LinkFilterExclude, settings.LinkFilterInclude, settings.LinkFilterOnly:
+		return f, nil
+	}
+	return f, fmt.Errorf("%w: unknown filter %q", settings.ErrInvalidUserURLsQuery, f)
+}
+
+// encodeUserURLsCursor возвращает курсор страницы, следующей за ссылкой last.
+func encodeUserURLsCursor(q settings.UserURLsQuery, last settings.UserURL) (string, error) {
+	cursor := userURLsCursor{Sort: q.Sort, Order: q.Order}
+	cursor.ShortURL = last.ShortURL
+	switch q.Sort {
+	case settings.UserURLsSortClicks:
+		cursor.Clicks = last.Clicks
+	default:
+		cursor.CreatedAt = last.CreatedAt
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserURLsCursor(value string) (userURLsCursor, error) {
+	var cursor userURLsCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.ShortURL == "" {
+		return cursor, fmt.Errorf("%w: malformed cursor", settings.ErrInvalidUserURLsQuery)
+	}
+	return cursor, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// maxVariantNameLen - предельная длина имени варианта адреса назначения.
+const maxVariantNameLen = 32
+
+// normalizeVariants проверяет варианты адреса назначения: их от двух до settings.MaxVariants,
+// веса положительные, имена уникальные и состоят из символов алиаса. Пустые имена заменяются буквами по порядку.
+// Адреса назначения нормализуются и проверяются политикой доменов.
+func (s *Service) normalizeVariants(variants []settings.Variant) ([]settings.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > settings.MaxVariants {
+		return nil, fmt.Errorf("%w: from 2 to %d variants are allowed", settings.ErrInvalidVariants, settings.MaxVariants)
+	}
+	normalized := make([]settings.Variant, 0, len(variants))
+	names := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		v.Name = strings.ToLower(strings.TrimSpace(v.Name))
+		if v.Name == "" {
+			v.Name = string(rune('a' + i))
+		}
+		if len(v.Name) > maxVariantNameLen || strings.IndexFunc(v.Name, func(r rune) bool { return !isAliasChar(r) }) >= 0 {
+			return nil, fmt.Errorf("%w: invalid name %q", settings.ErrInvalidVariants, v.Name)
+		}
+		if names[v.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", settings.ErrInvalidVariants, v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight <= 0 {
+			return nil, fmt.Errorf("%w: variant %q: weight must be positive", settings.ErrInvalidVariants, v.Name)
+		}
+		url, err := s.normalizer.normalize(v.URL)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q: %w", v.Name, err)
+		}
+		if err := s.checkPolicy(url); err != nil {
+			return nil, err
+		}
+		v.URL = url
+		normalized = append(normalized, v)
+	}
+	return normalized, nil
+}
+
+// pickVariant выбирает вариант адреса назначения для клиента.
+// Если клиент уже получал вариант, который у ссылки все еще есть, возвращается он.
+// Иначе вариант выбирается по весам детерминированно по хешу ссылки, IP и User-Agent,
+// поэтому клиент без cookie при повторных переходах попадает на тот же вариант.
+// Если о клиенте ничего не известно, вариант выбирается случайно.
+func pickVariant(shortURL string, variants []settings.Variant, r settings.RedirectRequest) settings.Variant {
+	var total uint64
+	for _, v := range variants {
+		if v.Name == r.Variant {
+			return v
+		}
+		total += uint64(v.Weight)
+	}
+	if r.IP == "" && r.UserAgent == "" {
+		return variantAt(variants, rand.Uint64N(total))
+	}
+	h := fnv.New64a()
+	h.Write([]byte(shortURL))
+	h.Write([]byte{0})
+	h.Write([]byte(r.IP))
+	h.Write([]byte{0})
+	h.Write([]byte(r.UserAgent))
+	return variantAt(variants, h.Sum64()%total)
+}
+
+// variantAt возвращает вариант, на отрезок весов которого приходится точка point из [0, сумма весов).
+func variantAt(variants []settings.Variant, point uint64) settings.Variant {
+	for _, v := range variants {
+		if point < uint64(v.Weight) {
+			return v
+		}
+		point -= uint64(v.Weight)
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// UpdateShortURL изменяет адрес назначения и атрибуты ссылки пользователя userID.
+// Новые значения проверяются так же, как при создании ссылки. Возвращает новую версию ссылки.
+// Изменение только тегов и папки новую версию не создает, тогда возвращается текущая версия.
+func (s *Service) UpdateShortURL(ctx context.Context, shortURL, userID string, update settings.LinkUpdate) (settings.LinkVersion, error) {
+	versions, err := s.ownLinkVersions(ctx, shortURL, userID)
+	if err != nil {
+		return settings.LinkVersion{}, err
+	}
+	current := versions[len(versions)-1]
+	if update.Tags != nil {
+		tags, err := normalizeTags(*update.Tags)
+		if err != nil {
+			return settings.LinkVersion{}, err
+		}
+		update.Tags = &tags
+	}
+	if update.Folder != nil {
+		folder, err := normalizeFolder(*update.Folder)
+		if err != nil {
+			return settings.LinkVersion{}, err
+		}
+		update.Folder = &folder
+	}
+	if !update.Versioned() {
+		if update.Tags != nil || update.Folder != nil {
+			if err := s.repo.UpdateLinkLabels(ctx, shortURL, update.Tags, update.Folder); err != nil {
+				return settings.LinkVersion{}, err
+			}
+		}
+		return current, nil
+	}
+	next, err := s.applyLinkUpdate(current, update, time.Now())
+	if err != nil {
+		return settings.LinkVersion{}, err
+	}
+	// версия, теги и папка применяются одним вызовом, чтобы изменение не применилось частично
+	if err := s.repo.UpdateLink(ctx, shortURL, next, update.Tags, update.Folder); err != nil {
+		return settings.LinkVersion{}, err
+	}
+	if next.OriginalURL != current.OriginalURL {
+		s.enqueueMetadata(shortURL, next.OriginalURL)
+	}
+	return next, nil
+}
+
+// GetLinkVersions возвращает версии ссылки пользователя userID от первой до текущей.
+func (s *Service) GetLinkVersions(ctx context.Context, shortURL, userID string) ([]settings.LinkVersion, error) {
+	return s.ownLinkVersions(ctx, shortURL, userID)
+}
+
+// RollbackShortURL возвращает ссылке пользователя userID адрес назначения и атрибуты версии version.
+// Откат не удаляет историю, а добавляет новую версию. Возвращает новую версию ссылки.
+// Атрибуты версии проверяются так же, как при изменении ссылки: домены могли попасть в список запрещенных,
+// а срок действия - истечь после создания версии.
+func (s *Service) RollbackShortURL(ctx context.Context, shortURL, userID string, version int) (settings.LinkVersion, error) {
+	versions, err := s.ownLinkVersions(ctx, shortURL, userID)
+	if err != nil {
+		return settings.LinkVersion{}, err
+	}
+	for _, v := range versions {
+		if v.Version != version {
+			continue
+		}
+		next, err := s.applyLinkUpdate(versions[len(versions)-1], versionUpdate(v), time.Now())
+		if err != nil {
+			return settings.LinkVersion{}, err
+		}
+		// пароль версии хранится только хешем, его восстанавливаем как есть
+		next.Attrs.PasswordHash = v.Attrs.PasswordHash
+		if err := s.repo.UpdateLink(ctx, shortURL, next, nil, nil); err != nil {
+			return settings.LinkVersion{}, err
+		}
+		if next.OriginalURL != versions[len(versions)-1].OriginalURL {
+			s.enqueueMetadata(shortURL, next.OriginalURL)
+		}
+		return next, nil
+	}
+	return settings.LinkVersion{}, settings.ErrVersionNotFound
+}
+
+// versionUpdate возвращает изменение, которое задает ссылке адрес назначения и атрибуты версии v, кроме пароля.
+func versionUpdate(v settings.LinkVersion) settings.LinkUpdate {
+	attrs := v.Attrs
+	queryPassthrough := string(attrs.QueryPassthrough)
+	return settings.LinkUpdate{
+		OriginalURL:      &v.OriginalURL,
+		ExpiresAt:        &attrs.ExpiresAt,
+		MaxClicks:        &attrs.MaxClicks,
+		Interstitial:     &attrs.Interstitial,
+		FallbackURL:      &attrs.FallbackURL,
+		QueryPassthrough: &queryPassthrough,
+		UTM:              &attrs.UTM,
+		RedirectStatus:   &attrs.RedirectStatus,
+		Routing:          &attrs.Routing,
+		Variants:         &attrs.Variants,
+	}
+}
+
+// ownLinkVersions возвращает версии ссылки, если она принадлежит пользователю userID.
+func (s *Service) ownLinkVersions(ctx context.Context, shortURL, userID string) ([]settings.LinkVersion, error) {
+	owner, err := s.repo.GetURLOwner(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	if owner != userID {
+		return nil, settings.ErrNotOwner
+	}
+	return s.repo.GetLinkVersions(ctx, shortURL)
+}
+
+// applyLinkUpdate возвращает версию, следующую за current, с примененными изменениями update.
+func (s *Service) applyLinkUpdate(current settings.LinkVersion, update settings.LinkUpdate, now time.Time) (settings.LinkVersion, error) {
+	next := settings.LinkVersion{
+		Version:     current.Version + 1,
+		OriginalURL: current.OriginalURL,
+		Attrs:       current.Attrs,
+		CreatedAt:   now,
+	}
+	attrs := &next.Attrs
+	var err error
+	if update.OriginalURL != nil {
+		if next.OriginalURL, err = s.normalizer.normalize(*update.OriginalURL); err != nil {
+			return next, err
+		}
+		if err := s.checkPolicy(next.OriginalURL); err != nil {
+			return next, err
+		}
+	}
+	if update.ExpiresAt != nil {
+		if !update.ExpiresAt.IsZero() && !update.ExpiresAt.After(now) {
+			return next, fmt.Errorf("%w: expires_at is in the past", settings.ErrInvalidExpiration)
+		}
+		attrs.ExpiresAt = *update.ExpiresAt
+	}
+	if update.MaxClicks != nil {
+		if *update.MaxClicks < 0 {
+			return next, fmt.Errorf("%w: max_clicks must not be negative", settings.ErrInvalidMaxClicks)
+		}
+		attrs.MaxClicks = *update.MaxClicks
+	}
+	if update.Password != nil {
+		if attrs.PasswordHash, err = hashPassword(*update.Password); err != nil {
+			return next, err
+		}
+	}
+	if update.Interstitial != nil {
+		attrs.Interstitial = *update.Interstitial
+	}
+	if update.FallbackURL != nil {
+		if attrs.FallbackURL, err = s.normalizeFallbackURL(*update.FallbackURL); err != nil {
+			return next, err
+		}
+	}
+	if update.QueryPassthrough != nil {
+		if attrs.QueryPassthrough, err = settings.ParseQueryPassthrough(*update.QueryPassthrough); err != nil {
+			return next, err
+		}
+	}
+	if update.UTM != nil {
+		attrs.UTM = *update.UTM
+	}
+	if update.RedirectStatus != nil {
+		if *update.RedirectStatus != 0 {
+			if err := settings.ValidateRedirectStatus(*update.RedirectStatus); err != nil {
+				return next, err
+			}
+		}
+		attrs.RedirectStatus = *update.RedirectStatus
+	}
+	if update.Routing != nil {
+		if attrs.Routing, err = s.normalizeRouting(*update.Routing); err != nil {
+			return next, err
+		}
+	}
+	if update.Variants != nil {
+		if attrs.Variants, err = s.normalizeVariants(*update.Variants); err != nil {
+			return next, err
+		}
+	}
+	return next, nil
+}
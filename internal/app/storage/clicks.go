@@ -0,0 +1,470 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/hll"
+)
+
+// ClickCache - структура для хранения событий переходов в памяти.
+type ClickCache struct {
+	mu sync.RWMutex
+	// Clicks - события переходов, ключ - короткий урл.
+	Clicks map[string][]settings.ClickEvent
+	// Sketches - дневные скетчи уникальных посетителей, ключи - короткий урл и день.
+	Sketches map[string]map[time.Time]*hll.Sketch
+}
+
+// NewClickCache создает экземпляр структуры ClickCache.
+func NewClickCache() *ClickCache {
+	return &ClickCache{
+		Clicks:   make(map[string][]settings.ClickEvent),
+		Sketches: make(map[string]map[time.Time]*hll.Sketch),
+	}
+}
+
+// SaveClicks добавляет события переходов в кэш.
+func (c *ClickCache) SaveClicks(ctx context.Context, events ...settings.ClickEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, event := range events {
+		c.Clicks[event.ShortURL] = append(c.Clicks[event.ShortURL], event)
+	}
+	return nil
+}
+
+// GetClickStats возвращает статистику переходов по короткому урлу за интервал запроса.
+// Временной ряд содержит только шаги, в которых были переходы.
+func (c *ClickCache) GetClickStats(ctx context.Context, shortURL string, q settings.StatsQuery) (settings.LinkStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var stats settings.LinkStats
+	series := make(map[time.Time]int64)
+	referrers := make(map[string]int64)
+	userAgents := make(map[string]int64)
+	countries := make(map[string]int64)
+	variants := make(map[string]int64)
+	for _, event := range c.Clicks[shortURL] {
+		if event.Time.Before(q.From) || !event.Time.Before(q.To) {
+			continue
+		}
+		stats.TotalClicks++
+		series[event.Time.UTC().Truncate(q.Bucket.Duration())]++
+		countValue(referrers, event.Referer)
+		countValue(userAgents, event.UserAgent)
+		countValue(countries, event.Country)
+		countValue(variants, event.Variant)
+	}
+	for t, clicks := range series {
+		stats.Series = append(stats.Series, settings.StatsPoint{Time: t, Clicks: clicks})
+	}
+	sort.Slice(stats.Series, func(i, j int) bool { return stats.Series[i].Time.Before(stats.Series[j].Time) })
+	stats.TopReferrers = topValues(referrers, q.Top)
+	stats.TopUserAgents = topValues(userAgents, q.Top)
+	stats.TopCountries = topValues(countries, q.Top)
+	stats.Variants = topValues(variants, settings.MaxVariants)
+	return stats, nil
+}
+
+// MergeVisitorSketches объединяет переданные скетчи уникальных посетителей с сохраненными.
+func (c *ClickCache) MergeVisitorSketches(ctx context.Context, sketches map[settings.VisitorSketchKey]*hll.Sketch) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, sketch := range sketches {
+		days, ok := c.Sketches[key.ShortURL]
+		if !ok {
+			days = make(map[time.Time]*hll.Sketch)
+			c.Sketches[key.ShortURL] = days
+		}
+		stored, ok := days[key.Day]
+		if !ok {
+			stored = hll.New()
+			days[key.Day] = stored
+		}
+		stored.Merge(sketch)
+	}
+	return nil
+}
+
+// GetVisitorSketch возвращает объединение дневных скетчей короткого урла за дни из [from, to).
+func (c *ClickCache) GetVisitorSketch(ctx context.Context, shortURL string, from, to time.Time) (*hll.Sketch, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res := hll.New()
+	for day, sketch := range c.Sketches[shortURL] {
+		if !day.Before(settings.SketchDay(from)) && day.Before(to) {
+			res.Merge(sketch)
+		}
+	}
+	return res, nil
+}
+
+// DeleteClicksBefore удаляет события переходов до момента before и скетчи посетителей за дни до него.
+// Возвращает число удаленных событий.
+func (c *ClickCache) DeleteClicksBefore(ctx context.Context, before time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deleted, _ := c.deleteClicksBeforeLocked(before)
+	return deleted, nil
+}
+
+// deleteClicksBeforeLocked удаляет старые события и скетчи и возвращает число удаленных событий и скетчей.
+func (c *ClickCache) deleteClicksBeforeLocked(before time.Time) (deletedClicks, deletedSketches int) {
+	for shortURL, events := range c.Clicks {
+		kept := events[:0]
+		for _, event := range events {
+			if event.Time.Before(before) {
+				deletedClicks++
+				continue
+			}
+			kept = append(kept, event)
+		}
+		if len(kept) == 0 {
+			delete(c.Clicks, shortURL)
+			continue
+		}
+		// обнуляем хвост, чтобы удаленные события не удерживались в памяти
+		clear(events[len(kept):])
+		c.Clicks[shortURL] = kept
+	}
+	firstDay := settings.SketchDay(before)
+	for shortURL, days := range c.Sketches {
+		for day := range days {
+			if day.Before(firstDay) {
+				delete(days, day)
+				deletedSketches++
+			}
+		}
+		if len(days) == 0 {
+			delete(c.Sketches, shortURL)
+		}
+	}
+	return deletedClicks, deletedSketches
+}
+
+// DeleteLinkClicks удаляет события переходов и скетчи посетителей коротких урлов shortURLs.
+func (c *ClickCache) DeleteLinkClicks(ctx context.Context, shortURLs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLinkClicksLocked(shortURLs)
+	return nil
+}
+
+func (c *ClickCache) deleteLinkClicksLocked(shortURLs []string) {
+	for _, shortURL := range shortURLs {
+		// общий скетч сервиса хранится под пустым коротким урлом и не удаляется
+		if shortURL == "" {
+			continue
+		}
+		delete(c.Clicks, shortURL)
+		delete(c.Sketches, shortURL)
+	}
+}
+
+// countValue учитывает непустое значение в счетчиках для топа.
+func countValue(counts map[string]int64, value string) {
+	if value != "" {
+		counts[value]++
+	}
+}
+
+// topValues возвращает top самых частых значений, при равенстве упорядоченных по значению.
+func topValues(counts map[string]int64, top int) []settings.StatsCount {
+	res := make([]settings.StatsCount, 0, len(counts))
+	for value, count := range counts {
+		res = append(res, settings.StatsCount{Value: value, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	if len(res) > top {
+		res = res[:top]
+	}
+	return res
+}
+
+// Close - заглушка для закрытия интерфейса.
+func (c *ClickCache) Close() error {
+	return nil
+}
+
+// ClickFileStorage - структура для хранения событий переходов в файле в формате JSONL.
+// События и скетчи дублируются в памяти для чтения.
+// Скетчи пишутся в файл не при каждом объединении, а при смене дня, переписывании файла и закрытии.
+type ClickFileStorage struct {
+	mu         sync.Mutex
+	fileName   string
+	file       *os.File
+	writer     *bufio.Writer
+	clickCache *ClickCache
+	// dirty - скетчи, измененные в памяти после последней записи в файл
+	dirty map[settings.VisitorSketchKey]bool
+	// day - последний день, за который объединялись скетчи
+	day time.Time
+}
+
+// NewClickFileStorage создает экземпляр структуры ClickFileStorage и восстанавливает события из файла.
+func NewClickFileStorage(fileName string) (*ClickFileStorage, error) {
+	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	f := &ClickFileStorage{
+		fileName:   fileName,
+		file:       file,
+		writer:     bufio.NewWriter(file),
+		clickCache: NewClickCache(),
+		dirty:      make(map[settings.VisitorSketchKey]bool),
+	}
+	if err := f.restore(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *ClickFileStorage) restore() error {
+	reader := bufio.NewReader(f.file)
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var line clickLine
+		if err := json.Unmarshal(data, &line); err != nil {
+			return err
+		}
+		if line.Purged {
+			f.clickCache.DeleteLinkClicks(context.Background(), line.ShortURL)
+			continue
+		}
+		if line.Sketch == nil {
+			f.clickCache.SaveClicks(context.Background(), line.ClickEvent)
+			continue
+		}
+		sketch := hll.New()
+		if err := sketch.UnmarshalBinary(line.Sketch); err != nil {
+			return err
+		}
+		key := settings.VisitorSketchKey{ShortURL: line.ShortURL, Day: line.Time.UTC()}
+		f.clickCache.MergeVisitorSketches(context.Background(), map[settings.VisitorSketchKey]*hll.Sketch{key: sketch})
+	}
+}
+
+// clickLine - строка файла событий переходов: событие, скетч посетителей или удаление статистики ссылки.
+// Для скетча ShortURL и Time задают короткий урл и день, Sketch - регистры.
+// Purged удаляет записанные ранее события и скетчи короткого урла ShortURL.
+type clickLine struct {
+	settings.ClickEvent
+	Sketch []byte `json:"sketch,omitzero"`
+	Purged bool   `json:"purged,omitzero"`
+}
+
+// writeLineLocked пишет строку в буфер файла.
+func (f *ClickFileStorage) writeLineLocked(line clickLine) error {
+	return writeClickLine(f.writer, line)
+}
+
+// writeClickLine пишет строку в буфер writer.
+func writeClickLine(writer *bufio.Writer, line clickLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.WriteByte('\n')
+}
+
+// SaveClicks пишет события переходов в файл и в кэш.
+func (f *ClickFileStorage) SaveClicks(ctx context.Context, events ...settings.ClickEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, event := range events {
+		if err := f.writeLineLocked(clickLine{ClickEvent: event}); err != nil {
+			return err
+		}
+	}
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	return f.clickCache.SaveClicks(ctx, events...)
+}
+
+// MergeVisitorSketches объединяет переданные скетчи с сохраненными в памяти.
+// В файл скетчи пишутся при наступлении следующего дня: скетчи прошедших дней больше не меняются,
+// и каждый пишется один раз. Скетчи текущего дня пишутся при закрытии, при аварийном завершении
+// их приращения теряются. При восстановлении скетчи одного дня объединяются.
+func (f *ClickFileStorage) MergeVisitorSketches(ctx context.Context, sketches map[settings.VisitorSketchKey]*hll.Sketch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.clickCache.MergeVisitorSketches(ctx, sketches); err != nil {
+		return err
+	}
+	day := f.day
+	for key := range sketches {
+		f.dirty[key] = true
+		if key.Day.After(day) {
+			day = key.Day
+		}
+	}
+	if !day.After(f.day) {
+		return nil
+	}
+	f.day = day
+	return f.writeSketchesLocked(func(key settings.VisitorSketchKey) bool { return key.Day.Before(day) })
+}
+
+// writeSketchesLocked пишет в файл измененные скетчи, для которых match возвращает true.
+func (f *ClickFileStorage) writeSketchesLocked(match func(key settings.VisitorSketchKey) bool) error {
+	f.clickCache.mu.RLock()
+	defer f.clickCache.mu.RUnlock()
+	for key := range f.dirty {
+		if !match(key) {
+			continue
+		}
+		sketch, ok := f.clickCache.Sketches[key.ShortURL][key.Day]
+		if ok {
+			data, err := sketch.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			line := clickLine{ClickEvent: settings.ClickEvent{ShortURL: key.ShortURL, Time: key.Day}, Sketch: data}
+			if err := f.writeLineLocked(line); err != nil {
+				return err
+			}
+		}
+		delete(f.dirty, key)
+	}
+	return f.writer.Flush()
+}
+
+// DeleteClicksBefore удаляет события переходов до момента before и скетчи посетителей за дни до него.
+// Если что-то удалено, файл переписывается из памяти: в нем остаются только сохраненные события
+// и по одному скетчу на ссылку и день. Возвращает число удаленных событий.
+func (f *ClickFileStorage) DeleteClicksBefore(ctx context.Context, before time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clickCache.mu.Lock()
+	defer f.clickCache.mu.Unlock()
+	deletedClicks, deletedSketches := f.clickCache.deleteClicksBeforeLocked(before)
+	if deletedClicks == 0 && deletedSketches == 0 {
+		return 0, nil
+	}
+	return deletedClicks, f.compactLocked()
+}
+
+// DeleteLinkClicks удаляет события переходов и скетчи посетителей коротких урлов shortURLs.
+// Удаление фиксируется в файле строкой с признаком purged.
+func (f *ClickFileStorage) DeleteLinkClicks(ctx context.Context, shortURLs ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, shortURL := range shortURLs {
+		if err := f.writeLineLocked(clickLine{ClickEvent: settings.ClickEvent{ShortURL: shortURL}, Purged: true}); err != nil {
+			return err
+		}
+	}
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	purged := make(map[string]bool, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		purged[shortURL] = true
+	}
+	for key := range f.dirty {
+		if purged[key.ShortURL] && key.ShortURL != "" {
+			delete(f.dirty, key)
+		}
+	}
+	return f.clickCache.DeleteLinkClicks(ctx, shortURLs...)
+}
+
+// compactLocked переписывает файл содержимым кэша через временный файл, заменяющий исходный.
+// Вызывается под блокировками f.mu и f.clickCache.mu.
+func (f *ClickFileStorage) compactLocked() error {
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	tmpName := f.fileName + ".tmp"
+	tmp, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpName)
+	defer tmp.Close()
+	writer := bufio.NewWriter(tmp)
+	for _, events := range f.clickCache.Clicks {
+		for _, event := range events {
+			if err := writeClickLine(writer, clickLine{ClickEvent: event}); err != nil {
+				return err
+			}
+		}
+	}
+	for shortURL, days := range f.clickCache.Sketches {
+		for day, sketch := range days {
+			data, err := sketch.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			line := clickLine{ClickEvent: settings.ClickEvent{ShortURL: shortURL, Time: day}, Sketch: data}
+			if err := writeClickLine(writer, line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, f.fileName); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.fileName, os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file = file
+	f.writer.Reset(file)
+	// все скетчи из памяти уже в новом файле
+	clear(f.dirty)
+	return nil
+}
+
+// GetVisitorSketch возвращает объединение дневных скетчей короткого урла за дни из [from, to).
+func (f *ClickFileStorage) GetVisitorSketch(ctx context.Context, shortURL string, from, to time.Time) (*hll.Sketch, error) {
+	return f.clickCache.GetVisitorSketch(ctx, shortURL, from, to)
+}
+
+// GetClickStats возвращает статистику переходов по короткому урлу за интервал запроса.
+func (f *ClickFileStorage) GetClickStats(ctx context.Context, shortURL string, q settings.StatsQuery) (settings.LinkStats, error) {
+	return f.clickCache.GetClickStats(ctx, shortURL, q)
+}
+
+// Close пишет в файл измененные скетчи и закрывает файл.
+func (f *ClickFileStorage) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.writeSketchesLocked(func(settings.VisitorSketchKey) bool { return true }); err != nil {
+		return err
+	}
+	return f.file.Close()
+}
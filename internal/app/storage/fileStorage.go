@@ -4,15 +4,53 @@ import (
 	"bufio"
 	"encoding/json"
 	"os"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
 )
 
 // Event - структура для хранения данных в json в файле.
 type Event struct {
-	UUID         string `json:"uuid"`
-	ShortURL     string `json:"short_url"`
-	OriginalURL  string `json:"original_url"`
-	UserID       string `json:"user_id"`
-	MarkedForDel bool   `json:"del"`
+	UUID         string    `json:"uuid"`
+	ShortURL     string    `json:"short_url"`
+	OriginalURL  string    `json:"original_url"`
+	UserID       string    `json:"user_id"`
+	MarkedForDel bool      `json:"del"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+	MaxClicks    int64     `json:"max_clicks,omitzero"`
+	PasswordHash string    `json:"password_hash,omitzero"`
+	CreatedAt    time.Time `json:"created_at,omitzero"`
+	Interstitial bool      `json:"interstitial,omitzero"`
+	FallbackURL  string    `json:"fallback_url,omitzero"`
+	// QueryPassthrough, UTM - передача параметров запроса и UTM метки по умолчанию.
+	QueryPassthrough settings.QueryPassthrough `json:"query_passthrough,omitzero"`
+	UTM              settings.UTMParams        `json:"utm,omitzero"`
+	RedirectStatus   int                       `json:"redirect_status,omitzero"`
+	Routing          []settings.RoutingRule    `json:"routing,omitempty"`
+	Variants         []settings.Variant        `json:"variants,omitempty"`
+	// Tags, Folder - теги и папка ссылки при создании и в событиях с признаком labels.
+	Tags   []string `json:"tags,omitempty"`
+	Folder string   `json:"folder,omitzero"`
+	// Labels - признак изменения тегов и папки записи ShortURL.
+	Labels bool `json:"labels,omitzero"`
+	// DeletedAt - момент пометки записи ShortURL на удаление в событиях с признаком del.
+	DeletedAt time.Time `json:"deleted_at,omitzero"`
+	// Restored - признак снятия с записи ShortURL пометки на удаление.
+	Restored bool `json:"restored,omitzero"`
+	// Purged - признак безвозвратного удаления записи ShortURL.
+	Purged bool `json:"purged,omitzero"`
+	// Reserved - короткий урл безвозвратно удаленной записи остается занятым.
+	Reserved bool `json:"reserved,omitzero"`
+	// Click - признак перехода по ссылке ShortURL.
+	Click bool `json:"click,omitzero"`
+	// Health - результат проверки доступности оригинального урла ShortURL.
+	Health *settings.LinkHealth `json:"health,omitempty"`
+	// LinkVersion - новая версия ссылки ShortURL после изменения владельцем.
+	LinkVersion *settings.LinkVersion `json:"link_version,omitempty"`
+	// Metadata - сведения о странице назначения ссылки ShortURL.
+	Metadata *settings.LinkMetadata `json:"metadata,omitempty"`
+	// CodeCounter - новая граница выданных значений счетчика коротких кодов.
+	CodeCounter uint64 `json:"code_counter,omitzero"`
 }
 
 // Producer - структура для хранения данных о писателе в файл.
@@ -0,0 +1,308 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/hll"
+)
+
+// ClickStore - структура для хранения событий переходов в БД.
+// Использует подключение основного хранилища и не закрывает его.
+type ClickStore struct {
+	conn *sql.DB
+}
+
+// NewClickStore создает экземпляр структуры ClickStore.
+func NewClickStore(conn *sql.DB) (*ClickStore, error) {
+	s := &ClickStore{conn: conn}
+	if err := s.Bootstrap(context.Background()); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// Bootstrap создает таблицу событий переходов и индекс по короткому урлу и времени.
+func (s *ClickStore) Bootstrap(ctx context.Context) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS clicks (
+			id bigserial PRIMARY KEY,
+			short_url varchar(%d) NOT NULL,
+			clicked_at timestamptz NOT NULL,
+			referer text DEFAULT '' NOT NULL,
+			user_agent text DEFAULT '' NOT NULL,
+			ip varchar(45) DEFAULT '' NOT NULL,
+			accept_language text DEFAULT '' NOT NULL
+		)
+	`, settings.MaxCodeLen))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS clicks_short_url_clicked_at_idx ON clicks (short_url, clicked_at)`)
+	if err != nil {
+		return err
+	}
+	// код страны клиента, пустая строка - страна неизвестна.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE clicks ADD COLUMN IF NOT EXISTS country varchar(8) DEFAULT '' NOT NULL`)
+	if err != nil {
+		return err
+	}
+	// код региона и город клиента, пустая строка - неизвестны.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE clicks
+			ADD COLUMN IF NOT EXISTS region varchar(8) DEFAULT '' NOT NULL,
+			ADD COLUMN IF NOT EXISTS city text DEFAULT '' NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	// имя варианта адреса назначения A/B теста, пустая строка - у ссылки нет вариантов.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant varchar(32) DEFAULT '' NOT NULL`)
+	if err != nil {
+		return err
+	}
+	// дневные скетчи HyperLogLog уникальных посетителей, пустой short_url - все ссылки сервиса.
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS visitor_sketches (
+			short_url varchar(%d) NOT NULL,
+			day date NOT NULL,
+			registers bytea NOT NULL,
+			PRIMARY KEY (short_url, day)
+		)
+	`, settings.MaxCodeLen))
+	if err != nil {
+		return err
+	}
+	// индекс удаления событий старше срока хранения.
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS clicks_clicked_at_idx ON clicks (clicked_at)`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveClicks добавляет события переходов в таблицу clicks одной транзакцией.
+func (s *ClickStore) SaveClicks(ctx context.Context, events ...settings.ClickEvent) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (short_url, clicked_at, referer, user_agent, ip, accept_language, country, region, city, variant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		_, err := stmt.ExecContext(ctx, e.ShortURL, e.Time, e.Referer, e.UserAgent, e.IP, e.AcceptLanguage, e.Country, e.Region, e.City, e.Variant)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetClickStats возвращает статистику переходов по короткому урлу за интервал запроса.
+// Временной ряд содержит только шаги, в которых были переходы.
+func (s *ClickStore) GetClickStats(ctx context.Context, shortURL string, q settings.StatsQuery) (settings.LinkStats, error) {
+	var stats settings.LinkStats
+	row := s.conn.QueryRowContext(ctx, `
+		SELECT count(*)
+		FROM clicks
+		WHERE short_url = $1 AND clicked_at >= $2 AND clicked_at < $3`,
+		shortURL, q.From, q.To)
+	if err := row.Scan(&stats.TotalClicks); err != nil {
+		return stats, err
+	}
+
+	// date_trunc считается в UTC, как и шаги временного ряда в памяти
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT date_trunc($4, clicked_at AT TIME ZONE 'UTC') AS bucket, count(*)
+		FROM clicks
+		WHERE short_url = $1 AND clicked_at >= $2 AND clicked_at < $3
+		GROUP BY bucket
+		ORDER BY bucket`,
+		shortURL, q.From, q.To, string(q.Bucket))
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var point settings.StatsPoint
+		if err := rows.Scan(&point.Time, &point.Clicks); err != nil {
+			return stats, err
+		}
+		point.Time = time.Date(point.Time.Year(), point.Time.Month(), point.Time.Day(), point.Time.Hour(), 0, 0, 0, time.UTC)
+		stats.Series = append(stats.Series, point)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	tops := map[string]*[]settings.StatsCount{
+		"referer":    &stats.TopReferrers,
+		"user_agent": &stats.TopUserAgents,
+		"country":    &stats.TopCountries,
+	}
+	for column, top := range tops {
+		if *top, err = s.topValues(ctx, column, shortURL, q); err != nil {
+			return stats, err
+		}
+	}
+	// вариантов немного, возвращаем все
+	variants := q
+	variants.Top = settings.MaxVariants
+	if stats.Variants, err = s.topValues(ctx, "variant", shortURL, variants); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// topValues возвращает самые частые непустые значения колонки column.
+// column подставляется в запрос как есть и должен быть именем колонки таблицы clicks.
+func (s *ClickStore) topValues(ctx context.Context, column, shortURL string, q settings.StatsQuery) ([]settings.StatsCount, error) {
+	rows, err := s.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %[1]s, count(*) AS cnt
+		FROM clicks
+		WHERE short_url = $1 AND clicked_at >= $2 AND clicked_at < $3 AND %[1]s <> ''
+		GROUP BY %[1]s
+		ORDER BY cnt DESC, %[1]s
+		LIMIT $4`, column),
+		shortURL, q.From, q.To, q.Top)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []settings.StatsCount{}
+	for rows.Next() {
+		var c settings.StatsCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// MergeVisitorSketches объединяет переданные скетчи уникальных посетителей с сохраненными.
+// Строки скетчей блокируются до конца транзакции, чтобы параллельные объединения не потеряли регистры.
+func (s *ClickStore) MergeVisitorSketches(ctx context.Context, sketches map[settings.VisitorSketchKey]*hll.Sketch) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for key, sketch := range sketches {
+		merged := hll.New()
+		merged.Merge(sketch)
+		var registers []byte
+		err := tx.QueryRowContext(ctx, `SELECT registers FROM visitor_sketches WHERE short_url = $1 AND day = $2 FOR UPDATE`,
+			key.ShortURL, key.Day).Scan(&registers)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return err
+		default:
+			stored := hll.New()
+			if err := stored.UnmarshalBinary(registers); err != nil {
+				return err
+			}
+			merged.Merge(stored)
+		}
+		if registers, err = merged.MarshalBinary(); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO visitor_sketches (short_url, day, registers) VALUES ($1, $2, $3)
+			ON CONFLICT (short_url, day) DO UPDATE SET registers = EXCLUDED.registers`,
+			key.ShortURL, key.Day, registers)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetVisitorSketch возвращает объединение дневных скетчей короткого урла за дни из [from, to).
+func (s *ClickStore) GetVisitorSketch(ctx context.Context, shortURL string, from, to time.Time) (*hll.Sketch, error) {
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT registers FROM visitor_sketches
+		WHERE short_url = $1 AND day >= $2 AND day <= $3`,
+		// колонка day имеет тип date, поэтому конец интервала переводим в последний захваченный день
+		shortURL, settings.SketchDay(from), settings.SketchDay(to.Add(-time.Nanosecond)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := hll.New()
+	for rows.Next() {
+		var registers []byte
+		if err := rows.Scan(&registers); err != nil {
+			return nil, err
+		}
+		sketch := hll.New()
+		if err := sketch.UnmarshalBinary(registers); err != nil {
+			return nil, err
+		}
+		res.Merge(sketch)
+	}
+	return res, rows.Err()
+}
+
+// DeleteClicksBefore удаляет события переходов до момента before и скетчи посетителей за дни до него.
+// Возвращает число удаленных событий.
+func (s *ClickStore) DeleteClicksBefore(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `DELETE FROM clicks WHERE clicked_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM visitor_sketches WHERE day < $1`, settings.SketchDay(before))
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), tx.Commit()
+}
+
+// DeleteLinkClicks удаляет события переходов и скетчи посетителей коротких урлов shortURLs.
+func (s *ClickStore) DeleteLinkClicks(ctx context.Context, shortURLs ...string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `DELETE FROM clicks WHERE short_url = ANY($1::text[])`, shortURLs)
+	if err != nil {
+		return err
+	}
+	// общий скетч сервиса хранится под пустым коротким урлом и не удаляется
+	_, err = tx.ExecContext(ctx, `DELETE FROM visitor_sketches WHERE short_url = ANY($1::text[]) AND short_url <> ''`, shortURLs)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close - заглушка для закрытия интерфейса, подключение закрывает основное хранилище.
+func (s *ClickStore) Close() error {
+	return nil
+}
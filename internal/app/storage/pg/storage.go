@@ -4,8 +4,11 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -16,14 +19,30 @@ import (
 	"github.com/nasik90/url-shortener/internal/app/storage"
 )
 
+// Имена уникальных индексов дедупликации оригинальных урлов.
+// Индексы частичные: ссылки, освободившие ключ дедупликации (dedup_released), в них не входят.
+const (
+	// originalURLKey - уникальность original_url во всем сервисе (режим global).
+	originalURLKey = "originalurl_live_ukey"
+	// userOriginalURLKey - уникальность original_url в пределах пользователя (режим per_user).
+	userOriginalURLKey = "user_originalurl_live_ukey"
+)
+
+// legacyDedupKeys - индексы дедупликации ранних версий схемы, которые учитывали все ссылки.
+var legacyDedupKeys = []string{"originalurl_ukey", "user_originalurl_ukey"}
+
 // Store - структура для хранения подключения к БД.
 type Store struct {
-	conn *sql.DB
+	conn      *sql.DB
+	dedupMode settings.DedupMode
+	// shortURLWidth - ширина колонки short_url, вмещающая и алиасы, и генерируемые коды.
+	shortURLWidth int
 }
 
 // NewStore создает экземпляр структуры Store.
-func NewStore(conn *sql.DB) (*Store, error) {
-	s := &Store{conn: conn}
+// codeLength - предельная длина генерируемых коротких кодов с учетом роста, по ней рассчитывается ширина колонки short_url.
+func NewStore(conn *sql.DB, dedupMode settings.DedupMode, codeLength int) (*Store, error) {
+	s := &Store{conn: conn, dedupMode: dedupMode, shortURLWidth: max(codeLength, settings.AliasMaxLen)}
 	err := s.Bootstrap(context.Background())
 	if err != nil {
 		return s, err
@@ -47,26 +66,354 @@ func (s Store) Bootstrap(ctx context.Context) error {
 	defer tx.Rollback()
 
 	// создаём таблицу сообщений и необходимые индексы.
-	tx.ExecContext(ctx, `
+	tx.ExecContext(ctx, fmt.Sprintf(`
         CREATE TABLE IF NOT EXISTS urlstorage (
-            short_url varchar(8) CONSTRAINT shorturl_pkey PRIMARY KEY NOT NULL,
-            original_url varchar(512) CONSTRAINT originalurl_ukey UNIQUE NOT NULL ,
+            short_url varchar(%d) CONSTRAINT shorturl_pkey PRIMARY KEY NOT NULL,
+            original_url varchar(512) NOT NULL ,
 			user_id varchar(64) NOT NULL, 
 			deleted_flag bool DEFAULT false NOT NULL  
         )
-    `)
+    `, s.shortURLWidth))
+
+	if err = s.widenShortURL(ctx, tx); err != nil {
+		return err
+	}
+
+	// срок действия ссылки, NULL - бессрочно.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ADD COLUMN IF NOT EXISTS expires_at timestamptz`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS urlstorage_expires_at_idx ON urlstorage (expires_at) WHERE expires_at IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+
+	// лимит переходов (0 - без ограничений) и счетчик совершенных переходов.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS max_clicks bigint DEFAULT 0 NOT NULL,
+			ADD COLUMN IF NOT EXISTS clicks bigint DEFAULT 0 NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	// признак ссылки с истекшим сроком или исчерпанным лимитом, которая уступила адрес новой ссылке
+	// и не участвует в дедупликации.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ADD COLUMN IF NOT EXISTS dedup_released bool DEFAULT false NOT NULL`)
+	if err != nil {
+		return err
+	}
+
+	if err = s.bootstrapDedupIndexes(ctx, tx); err != nil {
+		return err
+	}
+
+	// bcrypt хеш пароля ссылки, пустая строка - без пароля.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ADD COLUMN IF NOT EXISTS password_hash varchar(72) DEFAULT '' NOT NULL`)
+	if err != nil {
+		return err
+	}
+
+	// момент создания ссылки (NULL - ссылка создана до его учета) и признак обязательной промежуточной страницы.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS created_at timestamptz,
+			ADD COLUMN IF NOT EXISTS interstitial bool DEFAULT false NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ALTER COLUMN created_at SET DEFAULT now()`)
+	if err != nil {
+		return err
+	}
+
+	// запасной адрес мертвой ссылки и результат последней проверки доступности оригинального урла.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS fallback_url varchar(512) DEFAULT '' NOT NULL,
+			ADD COLUMN IF NOT EXISTS health_status int DEFAULT 0 NOT NULL,
+			ADD COLUMN IF NOT EXISTS health_checked_at timestamptz,
+			ADD COLUMN IF NOT EXISTS health_failures int DEFAULT 0 NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	// режим передачи параметров запроса ('' - off) и UTM метки по умолчанию в виде json.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS query_passthrough varchar(16) DEFAULT '' NOT NULL,
+			ADD COLUMN IF NOT EXISTS utm jsonb DEFAULT '{}' NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	// http статус редиректа (0 - статус по умолчанию сервиса).
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS redirect_status smallint DEFAULT 0 NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	// правила маршрутизации ссылки в виде json.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS routing jsonb DEFAULT '[]' NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	// варианты адреса назначения для A/B тестов в виде json.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS variants jsonb DEFAULT '[]' NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS urlstorage_health_checked_at_idx
+		ON urlstorage (health_checked_at NULLS FIRST) WHERE NOT deleted_flag`)
+	if err != nil {
+		return err
+	}
+	// индексы выборки урлов пользователя по страницам в порядке создания и числа переходов.
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS urlstorage_user_created_idx ON urlstorage (user_id, (`+userURLsCreatedKey+`), short_url)`)
+	if err != nil {
+		return err
+	}
+	// индекс по счетчику переходов делает обновления счетчика не HOT: каждый переход пишет и строку индекса.
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS urlstorage_user_clicks_idx ON urlstorage (user_id, clicks, short_url)`)
+	if err != nil {
+		return err
+	}
+	// момент пометки ссылки на удаление, по нему помеченные ссылки удаляются безвозвратно.
+	// ссылкам, помеченным до его учета, срок хранения отсчитывается с момента обновления схемы.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ADD COLUMN IF NOT EXISTS deleted_at timestamptz`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE urlstorage SET deleted_at = now() WHERE deleted_flag AND deleted_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS urlstorage_deleted_at_idx ON urlstorage (deleted_at) WHERE deleted_flag`)
+	if err != nil {
+		return err
+	}
+	// короткие урлы безвозвратно удаленных ссылок, которые нельзя занимать повторно.
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS reserved_short_urls (
+			short_url varchar(%d) PRIMARY KEY,
+			reserved_at timestamptz DEFAULT now() NOT NULL
+		)
+	`, settings.MaxCodeLen))
+	if err != nil {
+		return err
+	}
+	// теги и папка ссылки, по тегам ищутся ссылки пользователя.
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE urlstorage
+			ADD COLUMN IF NOT EXISTS tags text[] DEFAULT '{}' NOT NULL,
+			ADD COLUMN IF NOT EXISTS folder varchar(128) DEFAULT '' NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS urlstorage_tags_idx ON urlstorage USING gin (tags)`)
+	if err != nil {
+		return err
+	}
+	// сведения о странице назначения: заголовок и метки OpenGraph.
+	_, err = tx.ExecContext(ctx, `ALTER TABLE urlstorage ADD COLUMN IF NOT EXISTS metadata jsonb DEFAULT '{}' NOT NULL`)
+	if err != nil {
+		return err
+	}
+	// граница выданных значений счетчика коротких кодов, единственная строка.
+	// в базах, созданных до ее учета, выданные значения не превышают числа ссылок с учетом зарезервированных кодов.
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS code_counter (
+			id bool PRIMARY KEY DEFAULT true CHECK (id),
+			value bigint NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO code_counter (value)
+		SELECT (SELECT count(*) FROM urlstorage) + (SELECT count(*) FROM reserved_short_urls)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+	// история изменений ссылок, удаляется вместе со ссылкой.
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS url_versions (
+			short_url varchar(%d) NOT NULL REFERENCES urlstorage (short_url) ON DELETE CASCADE ON UPDATE CASCADE,
+			version int NOT NULL,
+			original_url varchar(512) NOT NULL,
+			attrs jsonb NOT NULL,
+			created_at timestamptz,
+			PRIMARY KEY (short_url, version)
+		)
+	`, settings.MaxCodeLen))
+	if err != nil {
+		return err
+	}
 
 	// коммитим транзакцию
 	return tx.Commit()
 }
 
 // SaveShortURL добавляет запись в таблицу urlstorage.
-func (s *Store) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string) error {
-	_, err := s.conn.ExecContext(ctx, `INSERT INTO urlstorage (short_url, original_url, user_id) VALUES ($1, $2, $3)`, shortURL, originalURL, userID)
-	err = checkInsertError(err)
+// Зарезервированный короткий урл считается занятым.
+func (s *Store) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string, attrs settings.LinkAttributes) error {
+	utm, routing, variants, err := marshalLinkJSON(attrs)
+	if err != nil {
+		return err
+	}
+	if err := checkReserved(ctx, s.conn, shortURL); err != nil {
+		return err
+	}
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := s.releaseDedupKey(ctx, tx, originalURL, userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO urlstorage (short_url, original_url, user_id, expires_at, max_clicks, password_hash, created_at, interstitial, fallback_url,
+			query_passthrough, utm, redirect_status, routing, variants, tags, folder)
+		VALUES ($1, $2, $3, $4, $5, $6, coalesce($7, now()), $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		shortURL, originalURL, userID, nullTime(attrs.ExpiresAt), attrs.MaxClicks, attrs.PasswordHash,
+		nullTime(attrs.CreatedAt), attrs.Interstitial, attrs.FallbackURL, attrs.QueryPassthrough, utm, attrs.RedirectStatus, routing, variants,
+		emptyIfNil(attrs.Tags), attrs.Folder)
+	if err = checkInsertError(err); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// releaseDedupKey снимает с ссылок на originalURL с истекшим сроком или исчерпанным лимитом переходов
+// участие в дедупликации, чтобы новая ссылка на тот же адрес не получала в ответ недоступную ссылку.
+// В режиме per_user освобождаются только ссылки пользователя userID. Возвращает число освобожденных ссылок.
+func (s *Store) releaseDedupKey(ctx context.Context, tx *sql.Tx, originalURL, userID string) (int64, error) {
+	if s.dedupMode == settings.DedupOff {
+		return 0, nil
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE urlstorage SET
+			dedup_released = true
+		WHERE original_url = $1
+			AND ($2 OR user_id = $3)
+			AND NOT dedup_released
+			AND (expires_at <= now() OR (max_clicks > 0 AND clicks >= max_clicks))`,
+		originalURL, s.dedupMode == settings.DedupGlobal, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// checkReserved возвращает settings.ErrShortURLNotUnique, если короткий урл зарезервирован.
+func checkReserved(ctx context.Context, q queryRower, shortURL string) error {
+	var reserved bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM reserved_short_urls WHERE short_url = $1)`, shortURL).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return settings.ErrShortURLNotUnique
+	}
+	return nil
+}
+
+// marshalLinkJSON возвращает значения jsonb колонок ссылки: utm, routing и variants.
+func marshalLinkJSON(attrs settings.LinkAttributes) (utm, routing, variants []byte, err error) {
+	if utm, err = json.Marshal(attrs.UTM); err != nil {
+		return nil, nil, nil, err
+	}
+	if routing, err = json.Marshal(emptyIfNil(attrs.Routing)); err != nil {
+		return nil, nil, nil, err
+	}
+	if variants, err = json.Marshal(emptyIfNil(attrs.Variants)); err != nil {
+		return nil, nil, nil, err
+	}
+	return utm, routing, variants, nil
+}
+
+// emptyIfNil заменяет отсутствующий список пустым, чтобы в колонке jsonb не было json null.
+func emptyIfNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+// nullTime преобразует нулевое время в NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// widenShortURL расширяет колонку short_url в ранее созданной таблице, если она уже настроенной ширины.
+// Колонка никогда не сужается, чтобы не потерять сохраненные длинные коды.
+func (s Store) widenShortURL(ctx context.Context, tx *sql.Tx) error {
+	row := tx.QueryRowContext(ctx, `
+		SELECT character_maximum_length
+		FROM information_schema.columns
+		WHERE table_name = 'urlstorage' AND column_name = 'short_url'
+	`)
+	var width int
+	if err := row.Scan(&width); err != nil {
+		return err
+	}
+	if width >= s.shortURLWidth {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE urlstorage ALTER COLUMN short_url TYPE varchar(%d)`, s.shortURLWidth))
 	return err
 }
 
+// bootstrapDedupIndexes создает уникальный индекс, соответствующий режиму дедупликации,
+// и удаляет индексы других режимов. Если в таблице уже есть дубли, создание индекса завершится ошибкой.
+func (s Store) bootstrapDedupIndexes(ctx context.Context, tx *sql.Tx) error {
+	// в ранних версиях схемы уникальность original_url задавалась ограничением таблицы.
+	_, err := tx.ExecContext(ctx, `ALTER TABLE urlstorage DROP CONSTRAINT IF EXISTS `+legacyDedupKeys[0])
+	if err != nil {
+		return err
+	}
+	for _, name := range legacyDedupKeys {
+		if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS `+name); err != nil {
+			return err
+		}
+	}
+	indexes := map[string]string{
+		originalURLKey:     `CREATE UNIQUE INDEX IF NOT EXISTS ` + originalURLKey + ` ON urlstorage (original_url) WHERE NOT dedup_released`,
+		userOriginalURLKey: `CREATE UNIQUE INDEX IF NOT EXISTS ` + userOriginalURLKey + ` ON urlstorage (user_id, original_url) WHERE NOT dedup_released`,
+	}
+	wanted := map[settings.DedupMode]string{
+		settings.DedupGlobal:  originalURLKey,
+		settings.DedupPerUser: userOriginalURLKey,
+	}[s.dedupMode]
+	for name, create := range indexes {
+		query := `DROP INDEX IF EXISTS ` + name
+		if name == wanted {
+			query = create
+		}
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func checkInsertError(err error) error {
 	if err == nil {
 		return nil
@@ -76,103 +423,293 @@ func checkInsertError(err error) error {
 		if pgErr.ConstraintName == "shorturl_pkey" {
 			return settings.ErrShortURLNotUnique
 		}
-		if pgErr.ConstraintName == "originalurl_ukey" {
+		if pgErr.ConstraintName == originalURLKey || pgErr.ConstraintName == userOriginalURLKey {
 			return settings.ErrOriginalURLNotUnique
 		}
 	}
 	return err
 }
 
-// GetShortURL получает короткий урл из переданного оригинального.
-func (s *Store) GetShortURL(ctx context.Context, originalURL string) (string, error) {
+// GetURLOwner возвращает id пользователя, создавшего короткий урл, в том числе помеченный на удаление.
+func (s *Store) GetURLOwner(ctx context.Context, shortURL string) (string, error) {
+	row := s.conn.QueryRowContext(ctx, `SELECT user_id FROM urlstorage WHERE short_url = $1`, shortURL)
+	var userID string
+	err := row.Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", settings.ErrOriginalURLNotFound
+	}
+	return userID, err
+}
 
-	row := s.conn.QueryRowContext(ctx, `
-	SELECT
-		short_url
-	FROM urlstorage
-	WHERE original_url = $1
-	`, originalURL)
+// GetShortURL получает короткий урл из переданного оригинального.
+// В режиме дедупликации per_user ищет только среди урлов пользователя userID.
+func (s *Store) GetShortURL(ctx context.Context, originalURL, userID string) (string, error) {
+	var row *sql.Row
+	switch s.dedupMode {
+	case settings.DedupOff:
+		return "", settings.ErrOriginalURLNotFound
+	case settings.DedupPerUser:
+		row = s.conn.QueryRowContext(ctx, `
+		SELECT
+			short_url
+		FROM urlstorage
+		WHERE user_id = $1 AND original_url = $2 AND NOT dedup_released
+		`, userID, originalURL)
+	default:
+		row = s.conn.QueryRowContext(ctx, `
+		SELECT
+			short_url
+		FROM urlstorage
+		WHERE original_url = $1 AND NOT dedup_released
+		`, originalURL)
+	}
 
 	var shortURL string
 	err := row.Scan(&shortURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", settings.ErrOriginalURLNotFound
+	}
 	if err != nil {
 		return "", err
 	}
 	return shortURL, nil
-
 }
 
-// GetOriginalURL возвращает оригинальный урл по переданному короткому.
-func (s *Store) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
+// GetOriginalURL возвращает оригинальный урл и атрибуты ссылки по переданному короткому и засчитывает переход,
+// если accept не вернул ошибку. Ссылка читается тем же условным UPDATE, который увеличивает счетчик переходов,
+// поэтому обычный переход стоит одного запроса к БД, а лимит переходов не может быть превышен при одновременных
+// запросах. Если accept отклонил переход, засчитанный переход снимается вторым запросом.
+func (s *Store) GetOriginalURL(ctx context.Context, shortURL string, accept settings.AcceptRedirect) (string, settings.LinkAttributes, error) {
+	now := time.Now()
 	row := s.conn.QueryRowContext(ctx, `
-		SELECT
+		UPDATE urlstorage SET
+			clicks = clicks + 1
+		WHERE short_url = $1
+			AND NOT deleted_flag
+			AND (expires_at IS NULL OR expires_at > $2)
+			AND (max_clicks = 0 OR clicks < max_clicks)
+		RETURNING`+linkColumns, shortURL, now)
+
+	originalURL, _, attrs, err := scanLinkRow(row)
+	if errors.Is(err, settings.ErrOriginalURLNotFound) {
+		return "", attrs, s.unavailableReason(ctx, shortURL, now)
+	}
+	if err != nil {
+		return "", attrs, err
+	}
+	if accept == nil {
+		return originalURL, attrs, nil
+	}
+	if err := accept(originalURL, attrs); err != nil {
+		_, uerr := s.conn.ExecContext(ctx, `UPDATE urlstorage SET clicks = clicks - 1 WHERE short_url = $1 AND clicks > 0`, shortURL)
+		return "", attrs, errors.Join(err, uerr)
+	}
+	return originalURL, attrs, nil
+}
+
+// unavailableReason возвращает причину, по которой переход по короткому урлу невозможен.
+func (s *Store) unavailableReason(ctx context.Context, shortURL string, now time.Time) error {
+	_, _, err := s.getLink(ctx, shortURL, now)
+	if err != nil {
+		return err
+	}
+	// лимит исчерпан параллельным запросом между UPDATE и SELECT
+	return storage.ErrClickLimitReached
+}
+
+// GetLink возвращает оригинальный урл и атрибуты ссылки, не засчитывая переход.
+// Для недоступных ссылок возвращает те же ошибки, что и GetOriginalURL.
+func (s *Store) GetLink(ctx context.Context, shortURL string) (string, settings.LinkAttributes, error) {
+	return s.getLink(ctx, shortURL, time.Now())
+}
+
+func (s *Store) getLink(ctx context.Context, shortURL string, now time.Time) (string, settings.LinkAttributes, error) {
+	originalURL, deletedFlag, attrs, err := scanLink(ctx, s.conn, shortURL, false)
+	if err != nil {
+		return "", attrs, err
+	}
+	switch {
+	case deletedFlag:
+		return "", attrs, storage.ErrRecordMarkedForDel
+	case attrs.Expired(now):
+		return "", attrs, storage.ErrRecordExpired
+	case attrs.ClicksExhausted():
+		return "", attrs, storage.ErrClickLimitReached
+	}
+	return originalURL, attrs, nil
+}
+
+// queryRower - подключение к БД или транзакция, в которых выполняется запрос одной строки.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// linkColumns - колонки ссылки в порядке, в котором их читает scanLinkRow.
+const linkColumns = `
 			original_url,
-			deleted_flag
+			deleted_flag,
+			expires_at,
+			max_clicks,
+			clicks,
+			password_hash,
+			created_at,
+			interstitial,
+			fallback_url,
+			health_status,
+			health_checked_at,
+			health_failures,
+			query_passthrough,
+			utm,
+			redirect_status,
+			routing,
+			variants,
+			to_json(tags),
+			folder,
+			metadata`
+
+// scanLink читает ссылку без проверки ее доступности. forUpdate блокирует строку до конца транзакции.
+func scanLink(ctx context.Context, q queryRower, shortURL string, forUpdate bool) (string, bool, settings.LinkAttributes, error) {
+	query := `
+		SELECT` + linkColumns + `
 		FROM urlstorage
-		WHERE short_url = $1
-		`, shortURL)
+		WHERE short_url = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	return scanLinkRow(q.QueryRowContext(ctx, query, shortURL))
+}
 
+// scanLinkRow читает ссылку из строки с колонками linkColumns.
+func scanLinkRow(row *sql.Row) (string, bool, settings.LinkAttributes, error) {
 	var (
 		originalURL string
 		deletedFlag bool
+		expiresAt   sql.NullTime
+		createdAt   sql.NullTime
+		checkedAt   sql.NullTime
+		utm         []byte
+		routing     []byte
+		variants    []byte
+		tags        []byte
+		metadata    []byte
+		attrs       settings.LinkAttributes
 	)
-	err := row.Scan(&originalURL, &deletedFlag)
+	err := row.Scan(&originalURL, &deletedFlag, &expiresAt, &attrs.MaxClicks, &attrs.Clicks, &attrs.PasswordHash,
+		&createdAt, &attrs.Interstitial, &attrs.FallbackURL, &attrs.Health.Status, &checkedAt, &attrs.Health.Failures,
+		&attrs.QueryPassthrough, &utm, &attrs.RedirectStatus, &routing, &variants, &tags, &attrs.Folder, &metadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, attrs, settings.ErrOriginalURLNotFound
+	}
 	if err != nil {
-		return "", err
+		return "", false, attrs, err
 	}
-	if deletedFlag {
-		return originalURL, storage.ErrRecordMarkedForDel
-	}
-	return originalURL, nil
-}
-
-// SaveShortURLs добавляет записи в таблицу urlstorage.
-func (s *Store) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string) error {
-	// при массовом сохранении сейчас нет проверки на уникальность вставляемых shortURL и originalURL
-	// как вижу реализацию данной проверки:
-	// блокируем строки таблицы по вставляемым shortURL и отдельно по вставляемым originalURL
-	// проверяем селектом, что в БД нет таких shortURL и originalURL. Если нет, то вставляем записи.
-	// Если есть такие же originalURL, то возвращаем по таким shortURL из БД
-	// Если есть такие же shortURL, то по таким записям генерируем новый shortURL
-	// Все это делаем в одной транзакции
-	const batchLimit = 1000
-	shortOriginalURLBatch := make(map[string]string)
-	i := 0
-	for shortURL, originalURL := range shortOriginalURLs {
-		if i == batchLimit {
-			err := s.saveShortURLsBatch(ctx, shortOriginalURLBatch, userID)
-			if err != nil {
-				return err
-			}
-			for k := range shortOriginalURLBatch {
-				delete(shortOriginalURLBatch, k)
-			}
-			i = 0
-		}
-		shortOriginalURLBatch[shortURL] = originalURL
-		i++
+	attrs.ExpiresAt = expiresAt.Time
+	attrs.CreatedAt = createdAt.Time
+	attrs.Health.CheckedAt = checkedAt.Time
+	if err := json.Unmarshal(utm, &attrs.UTM); err != nil {
+		return "", false, attrs, err
 	}
-	err := s.saveShortURLsBatch(ctx, shortOriginalURLBatch, userID)
-	return err
+	if err := json.Unmarshal(routing, &attrs.Routing); err != nil {
+		return "", false, attrs, err
+	}
+	if err := json.Unmarshal(variants, &attrs.Variants); err != nil {
+		return "", false, attrs, err
+	}
+	if err := json.Unmarshal(tags, &attrs.Tags); err != nil {
+		return "", false, attrs, err
+	}
+	if err := json.Unmarshal(metadata, &attrs.Metadata); err != nil {
+		return "", false, attrs, err
+	}
+	return originalURL, deletedFlag, attrs, nil
 }
 
-func (s *Store) saveShortURLsBatch(ctx context.Context, shortOriginalURLBatch map[string]string, userID string) error {
+// SaveShortURLs добавляет записи в таблицу urlstorage в одной транзакции.
+// Записи, конфликтующие с уже сохраненными по короткому урлу или индексу дедупликации, пропускаются
+// и возвращаются в мапе конфликтов. Если занят один из алиасов aliases, транзакция откатывается
+// и возвращается settings.ErrAliasNotUnique.
+func (s *Store) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string, aliases map[string]bool) (map[string]error, error) {
+	conflicts := make(map[string]error)
 	tx, err := s.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return conflicts, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO urlstorage (short_url, original_url, user_id) VALUES ($1, $2, $3)")
+	// ON CONFLICT DO NOTHING не прерывает транзакцию, поэтому конфликтующие записи можно разобрать по одной
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO urlstorage (short_url, original_url, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING")
 	if err != nil {
-		return err
+		return conflicts, err
 	}
-	for shortURL, originalURL := range shortOriginalURLBatch {
-		_, err := stmt.ExecContext(ctx, shortURL, originalURL, userID)
+	existsStmt, err := tx.PrepareContext(ctx, "SELECT EXISTS(SELECT 1 FROM urlstorage WHERE short_url = $1)")
+	if err != nil {
+		return conflicts, err
+	}
+	// алиасы сохраняются первыми, чтобы сгенерированный код на тот же адрес получил ссылку алиаса
+	order := make([]string, 0, len(shortOriginalURLs))
+	for shortURL := range aliases {
+		order = append(order, shortURL)
+	}
+	for shortURL := range shortOriginalURLs {
+		if !aliases[shortURL] {
+			order = append(order, shortURL)
+		}
+	}
+	for _, shortURL := range order {
+		originalURL := shortOriginalURLs[shortURL]
+		if err := checkReserved(ctx, tx, shortURL); err != nil {
+			if !errors.Is(err, settings.ErrShortURLNotUnique) {
+				return conflicts, err
+			}
+			if aliases[shortURL] {
+				return nil, settings.ErrAliasNotUnique
+			}
+			conflicts[shortURL] = err
+			continue
+		}
+		res, err := stmt.ExecContext(ctx, shortURL, originalURL, userID)
 		if err != nil {
-			return err
+			return conflicts, err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return conflicts, err
+		}
+		if inserted != 0 {
+			continue
+		}
+		var shortURLExists bool
+		if err := existsStmt.QueryRowContext(ctx, shortURL).Scan(&shortURLExists); err != nil {
+			return conflicts, err
+		}
+		switch {
+		case shortURLExists && aliases[shortURL]:
+			return nil, settings.ErrAliasNotUnique
+		case shortURLExists:
+			conflicts[shortURL] = settings.ErrShortURLNotUnique
+			continue
+		}
+		// адрес занят недоступной ссылкой: она освобождает ключ дедупликации, и запись сохраняется повторно
+		released, err := s.releaseDedupKey(ctx, tx, originalURL, userID)
+		if err != nil {
+			return conflicts, err
+		}
+		if released != 0 {
+			if res, err = stmt.ExecContext(ctx, shortURL, originalURL, userID); err != nil {
+				return conflicts, err
+			}
+			if inserted, err = res.RowsAffected(); err != nil {
+				return conflicts, err
+			}
+		}
+		switch {
+		// алиас не заменяется существующей ссылкой: пакет отменяется целиком
+		case inserted == 0 && aliases[shortURL]:
+			return nil, settings.ErrOriginalURLNotUnique
+		case inserted == 0:
+			conflicts[shortURL] = settings.ErrOriginalURLNotUnique
 		}
 	}
-	return tx.Commit()
+	return conflicts, tx.Commit()
 }
 
 // Ping проверяет работоспособность БД.
@@ -180,30 +717,110 @@ func (s *Store) Ping(ctx context.Context) error {
 	return s.conn.PingContext(ctx)
 }
 
-// GetUserURLs возвращает список урлов пользователя.
-// Возвращает мапу (ключ - короткий урл, значение - оригинальный).
-func (s *Store) GetUserURLs(ctx context.Context, userID string) (map[string]string, error) {
-	data := make(map[string]string)
-	rows, err := s.conn.QueryContext(ctx, `
+// userURLsCreatedKey - ключ сортировки урлов пользователя по моменту создания.
+// Ссылки, созданные до учета момента создания, считаются созданными в начале эпохи.
+// Выражение совпадает с выражением индекса urlstorage_user_created_idx.
+const userURLsCreatedKey = `coalesce(created_at, 'epoch'::timestamptz)`
+
+// GetUserURLs возвращает не более q.Limit урлов пользователя, следующих за позицией q.After
+// в порядке q.Sort, q.Order, с учетом отборов и поиска по оригинальному урлу.
+// Сортировка и продолжение с позиции курсора выполняются по индексу (user_id, ключ сортировки, short_url).
+func (s *Store) GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) ([]settings.UserURL, error) {
+	var data []settings.UserURL
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	query := `
 	SELECT
 		short_url,
-		original_url
+		original_url,
+		health_status,
+		health_checked_at,
+		health_failures,
+		created_at,
+		clicks,
+		expires_at,
+		deleted_flag,
+		to_json(tags),
+		folder,
+		metadata
 	FROM urlstorage
-	WHERE user_id = $1
-	`, userID)
+	WHERE user_id = $1`
+	switch q.Deleted {
+	case settings.LinkFilterExclude:
+		query += ` AND NOT deleted_flag`
+	case settings.LinkFilterOnly:
+		query += ` AND deleted_flag`
+	}
+	switch q.Expired {
+	case settings.LinkFilterExclude:
+		query += ` AND (expires_at IS NULL OR expires_at > now())`
+	case settings.LinkFilterOnly:
+		query += ` AND expires_at <= now()`
+	}
+	if q.Search != "" {
+		query += ` AND strpos(lower(original_url), lower(` + arg(q.Search) + `)) > 0`
+	}
+	if q.Tag != "" {
+		query += ` AND tags @> ARRAY[` + arg(q.Tag) + `::text]`
+	}
+	if q.Folder != "" {
+		query += ` AND folder = ` + arg(q.Folder)
+	}
+	key := userURLsCreatedKey
+	if q.Sort == settings.UserURLsSortClicks {
+		key = `clicks`
+	}
+	order, next := `ASC`, `>`
+	if q.Order == settings.SortDesc {
+		order, next = `DESC`, `<`
+	}
+	if q.After != nil {
+		var value any = q.After.Clicks
+		if q.Sort != settings.UserURLsSortClicks {
+			value = q.After.CreatedAt
+			if q.After.CreatedAt.IsZero() {
+				value = time.Unix(0, 0)
+			}
+		}
+		query += fmt.Sprintf(` AND (%s, short_url) %s (%s, %s)`, key, next, arg(value), arg(q.After.ShortURL))
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, short_url %s`, key, order, order)
+	if q.Limit > 0 {
+		query += ` LIMIT ` + arg(q.Limit)
+	}
 
+	rows, err := s.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return data, err
 	}
+	defer rows.Close()
 
-	var (
-		shortURL, originalURL string
-	)
 	for rows.Next() {
-		if err := rows.Scan(&shortURL, &originalURL); err != nil {
+		var (
+			userURL   settings.UserURL
+			checkedAt sql.NullTime
+			createdAt sql.NullTime
+			expiresAt sql.NullTime
+			tags      []byte
+			metadata  []byte
+		)
+		if err := rows.Scan(&userURL.ShortURL, &userURL.OriginalURL, &userURL.Health.Status, &checkedAt, &userURL.Health.Failures,
+			&createdAt, &userURL.Clicks, &expiresAt, &userURL.Deleted, &tags, &userURL.Folder, &metadata); err != nil {
 			return data, err
 		}
-		data[shortURL] = originalURL
+		if err := json.Unmarshal(tags, &userURL.Tags); err != nil {
+			return data, err
+		}
+		if err := json.Unmarshal(metadata, &userURL.Metadata); err != nil {
+			return data, err
+		}
+		userURL.Health.CheckedAt = checkedAt.Time
+		userURL.CreatedAt = createdAt.Time
+		userURL.ExpiresAt = expiresAt.Time
+		data = append(data, userURL)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -213,7 +830,75 @@ func (s *Store) GetUserURLs(ctx context.Context, userID string) (map[string]stri
 	return data, nil
 }
 
-// MarkRecordsForDeletion помечает запись на удаление.
+// GetLinksToCheck возвращает не более limit доступных ссылок, которые не проверялись с момента checkedBefore,
+// начиная с давно проверенных. Возвращает мапу (ключ - короткий урл, значение - оригинальный).
+func (s *Store) GetLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int) (map[string]string, error) {
+	data := make(map[string]string)
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT short_url, original_url
+		FROM urlstorage
+		WHERE NOT deleted_flag
+			AND (expires_at IS NULL OR expires_at > now())
+			AND (health_checked_at IS NULL OR health_checked_at < $1)
+		ORDER BY health_checked_at NULLS FIRST
+		LIMIT $2`, checkedBefore, limit)
+	if err != nil {
+		return data, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var shortURL, originalURL string
+		if err := rows.Scan(&shortURL, &originalURL); err != nil {
+			return data, err
+		}
+		data[shortURL] = originalURL
+	}
+	return data, rows.Err()
+}
+
+// UpdateLinkHealth сохраняет результат проверки доступности оригинального урла.
+// Неудачные проверки подряд накапливаются в счетчике, удачная проверка его сбрасывает.
+func (s *Store) UpdateLinkHealth(ctx context.Context, shortURL string, status int, checkedAt time.Time, healthy bool) error {
+	_, err := s.conn.ExecContext(ctx, `
+		UPDATE urlstorage SET
+			health_status = $2,
+			health_checked_at = $3,
+			health_failures = CASE WHEN $4 THEN 0 ELSE health_failures + 1 END
+		WHERE short_url = $1`,
+		shortURL, status, checkedAt, healthy)
+	return err
+}
+
+// UpdateLinkMetadata сохраняет сведения о странице назначения ссылки, если она все еще ведет на originalURL.
+// Если адрес назначения успели изменить, возвращает settings.ErrVersionConflict.
+func (s *Store) UpdateLinkMetadata(ctx context.Context, shortURL, originalURL string, meta settings.LinkMetadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	res, err := s.conn.ExecContext(ctx, `UPDATE urlstorage SET metadata = $2 WHERE short_url = $1 AND original_url = $3`,
+		shortURL, data, originalURL)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+	var exists bool
+	if err := s.conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urlstorage WHERE short_url = $1)`, shortURL).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return settings.ErrOriginalURLNotFound
+	}
+	return settings.ErrVersionConflict
+}
+
+// MarkRecordsForDeletion помечает запись на удаление и запоминает момент пометки.
 func (s *Store) MarkRecordsForDeletion(ctx context.Context, records ...settings.Record) error {
 	for _, r := range records {
 		logger.Log.Info("record marked for deletion(plan)", zap.String("shortURL", r.ShortURL), zap.String("userID", r.UserID))
@@ -226,7 +911,7 @@ func (s *Store) MarkRecordsForDeletion(ctx context.Context, records ...settings.
 	}
 
 	query := `
-		UPDATE urlstorage SET deleted_flag = true FROM unnest($1::text[],$2::text[]) AS input(short_url, user_id) WHERE urlstorage.short_url = input.short_url and urlstorage.user_id = input.user_id 
+		UPDATE urlstorage SET deleted_flag = true, deleted_at = now() FROM unnest($1::text[],$2::text[]) AS input(short_url, user_id) WHERE urlstorage.short_url = input.short_url and urlstorage.user_id = input.user_id and NOT urlstorage.deleted_flag
 	`
 
 	res, err := s.conn.ExecContext(ctx, query, shortURLs, userIDs)
@@ -238,6 +923,69 @@ func (s *Store) MarkRecordsForDeletion(ctx context.Context, records ...settings.
 	return err
 }
 
+// RestoreShortURL снимает со ссылки пометку на удаление. Для непомеченной ссылки ничего не делает.
+func (s *Store) RestoreShortURL(ctx context.Context, shortURL string) error {
+	res, err := s.conn.ExecContext(ctx, `UPDATE urlstorage SET deleted_flag = false, deleted_at = NULL WHERE short_url = $1`, shortURL)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return settings.ErrOriginalURLNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredRecords безвозвратно удаляет записи, срок действия которых истек до expiredBefore.
+// reserve оставляет короткие урлы удаленных записей занятыми. Возвращает короткие урлы удаленных записей.
+func (s *Store) DeleteExpiredRecords(ctx context.Context, expiredBefore time.Time, reserve bool) ([]string, error) {
+	return s.purge(ctx, `expires_at <= $1`, expiredBefore, reserve)
+}
+
+// PurgeDeletedRecords безвозвратно удаляет записи, помеченные на удаление не позже deletedBefore.
+// reserve оставляет короткие урлы удаленных записей занятыми. Возвращает короткие урлы удаленных записей.
+func (s *Store) PurgeDeletedRecords(ctx context.Context, deletedBefore time.Time, reserve bool) ([]string, error) {
+	return s.purge(ctx, `deleted_flag AND deleted_at <= $1`, deletedBefore, reserve)
+}
+
+// purge удаляет записи, подходящие под условие where с параметром $1, и при reserve резервирует их короткие урлы
+// одним запросом, чтобы освободившийся код не успели занять.
+func (s *Store) purge(ctx context.Context, where string, before time.Time, reserve bool) ([]string, error) {
+	rows, err := s.conn.QueryContext(ctx, `
+		WITH purged AS (
+			DELETE FROM urlstorage WHERE `+where+` RETURNING short_url
+		), reserved AS (
+			INSERT INTO reserved_short_urls (short_url)
+			SELECT short_url FROM purged WHERE $2::bool
+			ON CONFLICT DO NOTHING
+		)
+		SELECT short_url FROM purged`, before, reserve)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var shortURLs []string
+	for rows.Next() {
+		var shortURL string
+		if err := rows.Scan(&shortURL); err != nil {
+			return nil, err
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	return shortURLs, rows.Err()
+}
+
+// ReserveCodes сдвигает границу счетчика коротких кодов на n и возвращает прежнюю.
+// Строка границы блокируется UPDATE, поэтому несколько экземпляров сервиса получают непересекающиеся блоки.
+func (s *Store) ReserveCodes(ctx context.Context, n uint64) (uint64, error) {
+	var start int64
+	err := s.conn.QueryRowContext(ctx, `UPDATE code_counter SET value = value + $1 RETURNING value - $1`, int64(n)).Scan(&start)
+	return uint64(start), err
+}
+
 // GetURLsCount подсчитывает количество коротких урлов в базе.
 // Возвращает число коротких урлов в базе.
 func (s *Store) GetURLsCount(ctx context.Context) (int, error) {
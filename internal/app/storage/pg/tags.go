@@ -0,0 +1,90 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+)
+
+// UpdateLinkLabels меняет теги и папку ссылки. Значения nil не меняются.
+func (s *Store) UpdateLinkLabels(ctx context.Context, shortURL string, tags *[]string, folder *string) error {
+	rowsAffected, err := updateLabels(ctx, s.conn, shortURL, tags, folder)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return settings.ErrOriginalURLNotFound
+	}
+	return nil
+}
+
+// execer - подключение к БД или транзакция, в которых выполняются запросы изменения.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// updateLabels меняет теги и папку ссылки в подключении или транзакции e. Возвращает число измененных строк.
+func updateLabels(ctx context.Context, e execer, shortURL string, tags *[]string, folder *string) (int64, error) {
+	var newTags []string
+	if tags != nil {
+		newTags = emptyIfNil(*tags)
+	}
+	res, err := e.ExecContext(ctx, `
+		UPDATE urlstorage
+		SET tags = coalesce($2::text[], tags), folder = coalesce($3, folder)
+		WHERE short_url = $1`, shortURL, newTags, folder)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetUserTags возвращает теги непомеченных на удаление ссылок пользователя с числом ссылок,
+// от самых частых, при равенстве - по алфавиту.
+func (s *Store) GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error) {
+	var data []settings.TagCount
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT tag, count(*)
+		FROM urlstorage, unnest(tags) AS tag
+		WHERE user_id = $1 AND NOT deleted_flag
+		GROUP BY tag
+		ORDER BY count(*) DESC, tag`, userID)
+	if err != nil {
+		return data, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tagCount settings.TagCount
+		if err := rows.Scan(&tagCount.Tag, &tagCount.Count); err != nil {
+			return data, err
+		}
+		data = append(data, tagCount)
+	}
+	return data, rows.Err()
+}
+
+// MergeTags заменяет теги from у ссылок пользователя, в том числе помеченных на удаление, тегом to,
+// сохраняя порядок тегов и убирая повторы. Возвращает число измененных ссылок.
+func (s *Store) MergeTags(ctx context.Context, userID string, from []string, to string) (int, error) {
+	res, err := s.conn.ExecContext(ctx, `
+		UPDATE urlstorage u
+		SET tags = (
+			SELECT array_agg(tag ORDER BY ord)
+			FROM (
+				SELECT tag, min(ord) AS ord
+				FROM (
+					SELECT CASE WHEN t.tag = ANY($2::text[]) THEN $3 ELSE t.tag END AS tag, t.ord
+					FROM unnest(u.tags) WITH ORDINALITY AS t(tag, ord)
+				) replaced
+				GROUP BY tag
+			) merged
+		)
+		WHERE u.user_id = $1 AND u.tags && $2::text[]`, userID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return int(rowsAffected), err
+}
@@ -0,0 +1,157 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/nasik90/url-shortener/cmd/shortener/settings"
+	"github.com/nasik90/url-shortener/internal/app/storage"
+)
+
+// queryer - подключение к БД или транзакция, в которых выполняются запросы чтения.
+type queryer interface {
+	queryRower
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// GetLinkVersions возвращает версии ссылки от первой до текущей, в том числе у ссылок с истекшим сроком действия.
+// Если ссылку не меняли после создания, возвращает единственную версию - ссылку в текущем виде.
+func (s *Store) GetLinkVersions(ctx context.Context, shortURL string) ([]settings.LinkVersion, error) {
+	return linkVersions(ctx, s.conn, shortURL, false)
+}
+
+func linkVersions(ctx context.Context, q queryer, shortURL string, forUpdate bool) ([]settings.LinkVersion, error) {
+	originalURL, deletedFlag, attrs, err := scanLink(ctx, q, shortURL, forUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if deletedFlag {
+		return nil, storage.ErrRecordMarkedForDel
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT version, original_url, attrs, created_at
+		FROM url_versions
+		WHERE short_url = $1
+		ORDER BY version`, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []settings.LinkVersion
+	for rows.Next() {
+		var (
+			v         settings.LinkVersion
+			data      []byte
+			createdAt sql.NullTime
+		)
+		if err := rows.Scan(&v.Version, &v.OriginalURL, &data, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &v.Attrs); err != nil {
+			return nil, err
+		}
+		v.CreatedAt = createdAt.Time
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		versions = append(versions, settings.LinkVersion{
+			Version:     1,
+			OriginalURL: originalURL,
+			Attrs:       attrs.Editable(),
+			CreatedAt:   attrs.CreatedAt,
+		})
+	}
+	return versions, nil
+}
+
+// UpdateLink применяет к ссылке новую версию и добавляет ее в таблицу url_versions.
+// Строка ссылки блокируется до конца транзакции, поэтому одновременные изменения не потеряются:
+// номер версии должен следовать за текущим, иначе возвращается settings.ErrVersionConflict.
+// Счетчик переходов и момент создания ссылки сохраняются, состояние проверок сбрасывается при смене адреса.
+// В той же транзакции меняются теги tags и папка folder, значения nil не меняются.
+func (s *Store) UpdateLink(ctx context.Context, shortURL string, version settings.LinkVersion, tags *[]string, folder *string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	versions, err := linkVersions(ctx, tx, shortURL, true)
+	if err != nil {
+		return err
+	}
+	if version.Version != versions[len(versions)-1].Version+1 {
+		return settings.ErrVersionConflict
+	}
+	// первая версия хранится в таблице только после первого изменения ссылки
+	if len(versions) == 1 {
+		if err := insertVersion(ctx, tx, shortURL, versions[0]); err != nil {
+			return err
+		}
+	}
+
+	attrs := version.Attrs
+	utm, routing, variants, err := marshalLinkJSON(attrs)
+	if err != nil {
+		return err
+	}
+	// новый адрес может удерживать недоступная ссылка, она освобождает ключ дедупликации
+	if versions[len(versions)-1].OriginalURL != version.OriginalURL {
+		var userID string
+		if err := tx.QueryRowContext(ctx, `SELECT user_id FROM urlstorage WHERE short_url = $1`, shortURL).Scan(&userID); err != nil {
+			return err
+		}
+		if _, err := s.releaseDedupKey(ctx, tx, version.OriginalURL, userID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE urlstorage SET
+			original_url = $2,
+			expires_at = $3,
+			max_clicks = $4,
+			password_hash = $5,
+			interstitial = $6,
+			fallback_url = $7,
+			query_passthrough = $8,
+			utm = $9,
+			redirect_status = $10,
+			routing = $11,
+			variants = $12,
+			health_status = CASE WHEN original_url = $2 THEN health_status ELSE 0 END,
+			health_checked_at = CASE WHEN original_url = $2 THEN health_checked_at END,
+			health_failures = CASE WHEN original_url = $2 THEN health_failures ELSE 0 END,
+			dedup_released = CASE WHEN original_url = $2 THEN dedup_released ELSE false END
+		WHERE short_url = $1`,
+		shortURL, version.OriginalURL, nullTime(attrs.ExpiresAt), attrs.MaxClicks, attrs.PasswordHash, attrs.Interstitial,
+		attrs.FallbackURL, attrs.QueryPassthrough, utm, attrs.RedirectStatus, routing, variants)
+	if err = checkInsertError(err); err != nil {
+		return err
+	}
+	if err := insertVersion(ctx, tx, shortURL, version); err != nil {
+		return err
+	}
+	if tags != nil || folder != nil {
+		if _, err := updateLabels(ctx, tx, shortURL, tags, folder); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertVersion добавляет версию ссылки в таблицу url_versions.
+func insertVersion(ctx context.Context, tx *sql.Tx, shortURL string, version settings.LinkVersion) error {
+	data, err := json.Marshal(version.Attrs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO url_versions (short_url, version, original_url, attrs, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		shortURL, version.Version, version.OriginalURL, data, nullTime(version.CreatedAt))
+	return err
+}
@@ -2,11 +2,16 @@
 package storage
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"io"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/nasik90/url-shortener/cmd/shortener/settings"
 )
@@ -15,59 +20,226 @@ import (
 var (
 	// ErrRecordMarkedForDel - ошибка для обозначения помеченной на удаление записи.
 	ErrRecordMarkedForDel = errors.New("record marked for deletion")
+	// ErrRecordExpired - ошибка для обозначения записи с истекшим сроком действия.
+	ErrRecordExpired = errors.New("record expired")
+	// ErrClickLimitReached - ошибка для обозначения записи с исчерпанным лимитом переходов.
+	ErrClickLimitReached = errors.New("click limit reached")
 )
 
 // LocalCache - структура для хранения данных.
 type LocalCache struct {
 	mu               sync.RWMutex
+	dedupMode        settings.DedupMode
 	ShortOriginalURL map[string]string
+	// OriginalShortURL - индекс дедупликации, ключ формирует dedupKey.
 	OriginalShortURL map[string]string
 	ShortURLUserID   map[string]string
 	MarkedForDelURL  map[string]bool
+	// DeletedAt - момент пометки ссылок на удаление, по нему помеченные ссылки удаляются безвозвратно.
+	DeletedAt     map[string]time.Time
+	ShortURLAttrs map[string]settings.LinkAttributes
+	// Versions - история изменений ссылок, пустая у ссылок, которые не меняли после создания.
+	Versions map[string][]settings.LinkVersion
+	// ReservedShortURL - короткие урлы безвозвратно удаленных ссылок, которые нельзя занимать повторно.
+	ReservedShortURL map[string]bool
+	// CodeCounter - граница выданных значений счетчика коротких кодов.
+	CodeCounter uint64
 }
 
 // NewLocalCahce служит для создания нового экземпляра структуры LocalCache.
-func NewLocalCahce() *LocalCache {
-	localCache := &LocalCache{}
+func NewLocalCahce(dedupMode settings.DedupMode) *LocalCache {
+	localCache := &LocalCache{dedupMode: dedupMode}
 	localCache.ShortOriginalURL = make(map[string]string)
 	localCache.OriginalShortURL = make(map[string]string)
 	localCache.ShortURLUserID = make(map[string]string)
 	localCache.MarkedForDelURL = make(map[string]bool)
+	localCache.DeletedAt = make(map[string]time.Time)
+	localCache.ShortURLAttrs = make(map[string]settings.LinkAttributes)
+	localCache.Versions = make(map[string][]settings.LinkVersion)
+	localCache.ReservedShortURL = make(map[string]bool)
 	return localCache
 }
 
 // SaveShortURL добавляет запись короткого и оригинального урлов в кэш.
-func (l *LocalCache) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string) error {
+// В зависимости от режима дедупликации возвращает settings.ErrOriginalURLNotUnique,
+// если оригинальный урл уже сокращен.
+func (l *LocalCache) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string, attrs settings.LinkAttributes) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkCanSaveLocked(shortURL, originalURL, userID); err != nil {
+		return err
+	}
+	l.saveShortURLLocked(shortURL, originalURL, userID, attrs)
+	return nil
+}
+
+// dedupKey возвращает ключ индекса дедупликации OriginalShortURL.
+// Пустой ключ означает, что дедупликация выключена.
+func (l *LocalCache) dedupKey(originalURL, userID string) string {
+	switch l.dedupMode {
+	case settings.DedupOff:
+		return ""
+	case settings.DedupPerUser:
+		return userID + "\x00" + originalURL
+	}
+	return originalURL
+}
+
+// checkCanSave проверяет, что короткий урл не занят и оригинальный урл не нарушает дедупликацию.
+func (l *LocalCache) checkCanSave(shortURL, originalURL, userID string) error {
 	l.mu.RLock()
-	if _, ok := l.ShortOriginalURL[shortURL]; ok {
+	defer l.mu.RUnlock()
+	return l.checkCanSaveLocked(shortURL, originalURL, userID)
+}
+
+func (l *LocalCache) checkCanSaveLocked(shortURL, originalURL, userID string) error {
+	if _, ok := l.ShortOriginalURL[shortURL]; ok || l.ReservedShortURL[shortURL] {
 		return settings.ErrShortURLNotUnique
 	}
-	l.mu.RUnlock()
-	l.saveShortURL(shortURL, originalURL, userID)
+	if l.dedupTakenLocked(l.dedupKey(originalURL, userID), time.Now()) {
+		return settings.ErrOriginalURLNotUnique
+	}
 	return nil
 }
 
-func (l *LocalCache) saveShortURL(shortURL, originalURL, userID string) {
+// dedupTakenLocked сообщает, занят ли ключ дедупликации key доступной ссылкой.
+// Ссылка с истекшим сроком или исчерпанным лимитом переходов ключ не удерживает:
+// новая ссылка на тот же адрес занимает ключ вместо нее.
+func (l *LocalCache) dedupTakenLocked(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+	holder, ok := l.OriginalShortURL[key]
+	if !ok {
+		return false
+	}
+	attrs := l.ShortURLAttrs[holder]
+	return !attrs.Expired(now) && !attrs.ClicksExhausted()
+}
+
+func (l *LocalCache) saveShortURL(shortURL, originalURL, userID string, attrs settings.LinkAttributes) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.saveShortURLLocked(shortURL, originalURL, userID, attrs)
+}
+
+func (l *LocalCache) saveShortURLLocked(shortURL, originalURL, userID string, attrs settings.LinkAttributes) {
 	l.ShortOriginalURL[shortURL] = originalURL
-	l.OriginalShortURL[originalURL] = shortURL
+	if key := l.dedupKey(originalURL, userID); key != "" {
+		l.OriginalShortURL[key] = shortURL
+	}
 	l.ShortURLUserID[shortURL] = userID
+	l.ShortURLAttrs[shortURL] = attrs
 }
 
-// GetOriginalURL возвращает оригинальный урл по переданному короткому.
-func (l *LocalCache) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// purgeShortURL безвозвратно удаляет запись из кэша. reserve оставляет короткий урл занятым.
+func (l *LocalCache) purgeShortURL(shortURL string, reserve bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.purgeShortURLLocked(shortURL, reserve)
+}
+
+func (l *LocalCache) purgeShortURLLocked(shortURL string, reserve bool) {
 	originalURL, ok := l.ShortOriginalURL[shortURL]
 	if !ok {
-		err := settings.ErrOriginalURLNotFound
-		return "", err
+		return
+	}
+	if key := l.dedupKey(originalURL, l.ShortURLUserID[shortURL]); key != "" && l.OriginalShortURL[key] == shortURL {
+		delete(l.OriginalShortURL, key)
+	}
+	delete(l.ShortOriginalURL, shortURL)
+	delete(l.ShortURLUserID, shortURL)
+	delete(l.MarkedForDelURL, shortURL)
+	delete(l.DeletedAt, shortURL)
+	delete(l.ShortURLAttrs, shortURL)
+	delete(l.Versions, shortURL)
+	if reserve {
+		l.ReservedShortURL[shortURL] = true
+	}
+}
+
+// expiredShortURLs возвращает короткие урлы, срок действия которых истек до expiredBefore.
+func (l *LocalCache) expiredShortURLs(expiredBefore time.Time) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var shortURLs []string
+	for shortURL, attrs := range l.ShortURLAttrs {
+		if attrs.Expired(expiredBefore) {
+			shortURLs = append(shortURLs, shortURL)
+		}
+	}
+	return shortURLs
+}
+
+// GetOriginalURL возвращает оригинальный урл и атрибуты ссылки по переданному короткому и засчитывает переход,
+// если accept не вернул ошибку.
+func (l *LocalCache) GetOriginalURL(ctx context.Context, shortURL string, accept settings.AcceptRedirect) (string, settings.LinkAttributes, error) {
+	if err := l.acceptRedirect(ctx, shortURL, accept); err != nil {
+		return "", settings.LinkAttributes{}, err
+	}
+	return l.addClickChecked(shortURL)
+}
+
+// acceptRedirect проверяет доступную ссылку функцией accept. Проверка выполняется без блокировки,
+// чтобы долгие проверки, например пароля, не задерживали другие переходы.
+func (l *LocalCache) acceptRedirect(ctx context.Context, shortURL string, accept settings.AcceptRedirect) error {
+	originalURL, attrs, err := l.GetLink(ctx, shortURL)
+	if err != nil || accept == nil {
+		return err
+	}
+	return accept(originalURL, attrs)
+}
+
+// addClickChecked засчитывает переход по доступной ссылке и возвращает ее оригинальный урл и атрибуты.
+func (l *LocalCache) addClickChecked(shortURL string) (string, settings.LinkAttributes, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	attrs, err := l.linkAttributesLocked(shortURL, time.Now())
+	if err != nil {
+		return "", attrs, err
+	}
+	attrs.Clicks++
+	l.ShortURLAttrs[shortURL] = attrs
+	return l.ShortOriginalURL[shortURL], attrs, nil
+}
+
+// GetLink возвращает оригинальный урл и атрибуты ссылки, не засчитывая переход.
+// Для недоступных ссылок возвращает те же ошибки, что и GetOriginalURL.
+func (l *LocalCache) GetLink(ctx context.Context, shortURL string) (string, settings.LinkAttributes, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	attrs, err := l.linkAttributesLocked(shortURL, time.Now())
+	if err != nil {
+		return "", attrs, err
+	}
+	return l.ShortOriginalURL[shortURL], attrs, nil
+}
+
+func (l *LocalCache) linkAttributesLocked(shortURL string, now time.Time) (settings.LinkAttributes, error) {
+	if _, ok := l.ShortOriginalURL[shortURL]; !ok {
+		return settings.LinkAttributes{}, settings.ErrOriginalURLNotFound
 	}
 	if l.MarkedForDelURL[shortURL] {
-		return "", ErrRecordMarkedForDel
+		return settings.LinkAttributes{}, ErrRecordMarkedForDel
+	}
+	attrs := l.ShortURLAttrs[shortURL]
+	if attrs.Expired(now) {
+		return settings.LinkAttributes{}, ErrRecordExpired
+	}
+	if attrs.ClicksExhausted() {
+		return settings.LinkAttributes{}, ErrClickLimitReached
+	}
+	return attrs, nil
+}
+
+// addClick засчитывает переход по ссылке без проверок. Используется при восстановлении из файла.
+func (l *LocalCache) addClick(shortURL string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if attrs, ok := l.ShortURLAttrs[shortURL]; ok {
+		attrs.Clicks++
+		l.ShortURLAttrs[shortURL] = attrs
 	}
-	return originalURL, nil
 }
 
 // Ping - заглушка для закрытия интерфейса.
@@ -81,49 +253,616 @@ func (l *LocalCache) Close() error {
 }
 
 // SaveShortURLs сохраняет список короткий-оригинальный урл.
-func (l *LocalCache) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string) error {
-	for shortURL, originalURL := range shortOriginalURLs {
-		err := l.SaveShortURL(ctx, shortURL, originalURL, userID)
+// Записи, конфликтующие с уже сохраненными, не сохраняются и возвращаются в мапе конфликтов.
+// Если занят один из алиасов aliases, не сохраняется ни одна запись.
+func (l *LocalCache) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string, aliases map[string]bool) (map[string]error, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkAliasesLocked(shortOriginalURLs, userID, aliases); err != nil {
+		return nil, err
+	}
+	conflicts := make(map[string]error)
+	attrs := settings.LinkAttributes{CreatedAt: time.Now()}
+	for _, shortURL := range batchOrder(shortOriginalURLs, aliases) {
+		originalURL := shortOriginalURLs[shortURL]
+		if err := l.checkCanSaveLocked(shortURL, originalURL, userID); err != nil {
+			conflicts[shortURL] = err
+			continue
+		}
+		l.saveShortURLLocked(shortURL, originalURL, userID, attrs)
+	}
+	return conflicts, nil
+}
+
+// checkAliasesLocked проверяет до сохранения пакета, что все алиасы aliases можно сохранить:
+// алиас не занят, а его оригинальный URL не сокращен ранее и не повторяется у другого алиаса пакета.
+// Иначе алиас был бы молча заменен существующей ссылкой.
+func (l *LocalCache) checkAliasesLocked(shortOriginalURLs map[string]string, userID string, aliases map[string]bool) error {
+	keys := make(map[string]bool, len(aliases))
+	for shortURL := range aliases {
+		err := l.checkCanSaveLocked(shortURL, shortOriginalURLs[shortURL], userID)
+		if errors.Is(err, settings.ErrShortURLNotUnique) {
+			return settings.ErrAliasNotUnique
+		}
 		if err != nil {
 			return err
 		}
+		if key := l.dedupKey(shortOriginalURLs[shortURL], userID); key != "" {
+			if keys[key] {
+				return settings.ErrOriginalURLNotUnique
+			}
+			keys[key] = true
+		}
 	}
 	return nil
 }
 
+// batchOrder возвращает короткие урлы пакета: сначала алиасы aliases, затем сгенерированные коды.
+// Алиасы сохраняются первыми, чтобы сгенерированный код на тот же адрес получил ссылку алиаса.
+func batchOrder(shortOriginalURLs map[string]string, aliases map[string]bool) []string {
+	order := make([]string, 0, len(shortOriginalURLs))
+	for shortURL := range aliases {
+		order = append(order, shortURL)
+	}
+	for shortURL := range shortOriginalURLs {
+		if !aliases[shortURL] {
+			order = append(order, shortURL)
+		}
+	}
+	return order
+}
+
 // GetShortURL получает короткий урл из переданного оригинального.
-func (l *LocalCache) GetShortURL(ctx context.Context, originalURL string) (string, error) {
+// В режиме дедупликации per_user ищет только среди урлов пользователя userID.
+func (l *LocalCache) GetShortURL(ctx context.Context, originalURL, userID string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	shortURL, ok := l.OriginalShortURL[l.dedupKey(originalURL, userID)]
+	if !ok {
+		return "", settings.ErrOriginalURLNotFound
+	}
+	return shortURL, nil
+}
+
+// GetURLOwner возвращает id пользователя, создавшего короткий урл, в том числе помеченный на удаление.
+func (l *LocalCache) GetURLOwner(ctx context.Context, shortURL string) (string, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return l.OriginalShortURL[originalURL], nil
+	userID, ok := l.ShortURLUserID[shortURL]
+	if !ok {
+		return "", settings.ErrOriginalURLNotFound
+	}
+	return userID, nil
 }
 
-// GetUserURLs возвращает список урлов пользователя.
-// Возвращает мапу (ключ - короткий урл, значение - оригинальный).
-func (l *LocalCache) GetUserURLs(ctx context.Context, userID string) (result map[string]string, err error) {
+// GetUserURLs возвращает не более q.Limit урлов пользователя, следующих за позицией q.After
+// в порядке q.Sort, q.Order, с учетом отборов и поиска по оригинальному урлу.
+func (l *LocalCache) GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) (result []settings.UserURL, err error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	result = make(map[string]string)
+	now := time.Now()
+	search := strings.ToLower(q.Search)
+	var after settings.UserURL
+	if q.After != nil {
+		after = settings.UserURL{ShortURL: q.After.ShortURL, CreatedAt: q.After.CreatedAt, Clicks: q.After.Clicks}
+	}
 	for shortURL, savedUserID := range l.ShortURLUserID {
-		if savedUserID == userID {
-			result[shortURL] = l.ShortOriginalURL[shortURL]
+		if savedUserID != userID {
+			continue
+		}
+		originalURL := l.ShortOriginalURL[shortURL]
+		attrs := l.ShortURLAttrs[shortURL]
+		userURL := settings.UserURL{
+			ShortURL:    shortURL,
+			OriginalURL: originalURL,
+			Health:      attrs.Health,
+			CreatedAt:   attrs.CreatedAt,
+			Clicks:      attrs.Clicks,
+			ExpiresAt:   attrs.ExpiresAt,
+			Deleted:     l.MarkedForDelURL[shortURL],
+			Tags:        attrs.Tags,
+			Folder:      attrs.Folder,
+			Metadata:    attrs.Metadata,
+		}
+		if !q.Deleted.Match(userURL.Deleted) || !q.Expired.Match(attrs.Expired(now)) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(originalURL), search) {
+			continue
 		}
+		if q.Tag != "" && !slices.Contains(attrs.Tags, q.Tag) || q.Folder != "" && attrs.Folder != q.Folder {
+			continue
+		}
+		if q.After != nil && compareUserURLs(userURL, after, q) <= 0 {
+			continue
+		}
+		result = append(result, userURL)
+	}
+	slices.SortFunc(result, func(a, b settings.UserURL) int { return compareUserURLs(a, b, q) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
 	}
 	return result, nil
 }
 
-// MarkRecordsForDeletion помечает запись на удаление.
+// compareUserURLs сравнивает ссылки в порядке выборки q: по полю сортировки, при равенстве - по короткому урлу.
+func compareUserURLs(a, b settings.UserURL, q settings.UserURLsQuery) int {
+	var c int
+	switch q.Sort {
+	case settings.UserURLsSortClicks:
+		c = cmp.Compare(a.Clicks, b.Clicks)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ShortURL, b.ShortURL)
+	}
+	if q.Order == settings.SortDesc {
+		return -c
+	}
+	return c
+}
+
+// GetLinksToCheck возвращает не более limit доступных ссылок, которые не проверялись с момента checkedBefore,
+// начиная с давно проверенных. Возвращает мапу (ключ - короткий урл, значение - оригинальный).
+func (l *LocalCache) GetLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int) (map[string]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	now := time.Now()
+	var shortURLs []string
+	for shortURL, attrs := range l.ShortURLAttrs {
+		if l.MarkedForDelURL[shortURL] || attrs.Expired(now) || !attrs.Health.CheckedAt.Before(checkedBefore) {
+			continue
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	sort.Slice(shortURLs, func(i, j int) bool {
+		return l.ShortURLAttrs[shortURLs[i]].Health.CheckedAt.Before(l.ShortURLAttrs[shortURLs[j]].Health.CheckedAt)
+	})
+	if len(shortURLs) > limit {
+		shortURLs = shortURLs[:limit]
+	}
+	result := make(map[string]string, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		result[shortURL] = l.ShortOriginalURL[shortURL]
+	}
+	return result, nil
+}
+
+// UpdateLinkHealth сохраняет результат проверки доступности оригинального урла.
+// Неудачные проверки подряд накапливаются в счетчике, удачная проверка его сбрасывает.
+func (l *LocalCache) UpdateLinkHealth(ctx context.Context, shortURL string, status int, checkedAt time.Time, healthy bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if health, ok := l.nextHealthLocked(shortURL, status, checkedAt, healthy); ok {
+		l.setHealthLocked(shortURL, health)
+	}
+	return nil
+}
+
+// nextHealthLocked возвращает состояние ссылки после проверки или false, если ссылки уже нет.
+func (l *LocalCache) nextHealthLocked(shortURL string, status int, checkedAt time.Time, healthy bool) (settings.LinkHealth, bool) {
+	attrs, ok := l.ShortURLAttrs[shortURL]
+	if !ok {
+		return settings.LinkHealth{}, false
+	}
+	health := settings.LinkHealth{Status: status, CheckedAt: checkedAt}
+	if !healthy {
+		health.Failures = attrs.Health.Failures + 1
+	}
+	return health, true
+}
+
+func (l *LocalCache) setHealth(shortURL string, health settings.LinkHealth) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setHealthLocked(shortURL, health)
+}
+
+func (l *LocalCache) setHealthLocked(shortURL string, health settings.LinkHealth) {
+	if attrs, ok := l.ShortURLAttrs[shortURL]; ok {
+		attrs.Health = health
+		l.ShortURLAttrs[shortURL] = attrs
+	}
+}
+
+// UpdateLinkMetadata сохраняет сведения о странице назначения ссылки, если она все еще ведет на originalURL.
+// Если адрес назначения успели изменить, возвращает settings.ErrVersionConflict.
+func (l *LocalCache) UpdateLinkMetadata(ctx context.Context, shortURL, originalURL string, meta settings.LinkMetadata) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkOriginalURLLocked(shortURL, originalURL); err != nil {
+		return err
+	}
+	l.setMetadataLocked(shortURL, meta)
+	return nil
+}
+
+func (l *LocalCache) checkOriginalURL(shortURL, originalURL string) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.checkOriginalURLLocked(shortURL, originalURL)
+}
+
+// checkOriginalURLLocked проверяет, что ссылка существует и ведет на originalURL.
+func (l *LocalCache) checkOriginalURLLocked(shortURL, originalURL string) error {
+	current, ok := l.ShortOriginalURL[shortURL]
+	if !ok {
+		return settings.ErrOriginalURLNotFound
+	}
+	if current != originalURL {
+		return settings.ErrVersionConflict
+	}
+	return nil
+}
+
+func (l *LocalCache) setMetadata(shortURL string, meta settings.LinkMetadata) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setMetadataLocked(shortURL, meta)
+}
+
+func (l *LocalCache) setMetadataLocked(shortURL string, meta settings.LinkMetadata) {
+	if attrs, ok := l.ShortURLAttrs[shortURL]; ok {
+		attrs.Metadata = meta
+		l.ShortURLAttrs[shortURL] = attrs
+	}
+}
+
+// GetLinkVersions возвращает версии ссылки от первой до текущей, в том числе у ссылок с истекшим сроком действия.
+// Если ссылку не меняли после создания, возвращает единственную версию - ссылку в текущем виде.
+func (l *LocalCache) GetLinkVersions(ctx context.Context, shortURL string) ([]settings.LinkVersion, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.linkVersionsLocked(shortURL)
+}
+
+func (l *LocalCache) linkVersionsLocked(shortURL string) ([]settings.LinkVersion, error) {
+	if _, ok := l.ShortOriginalURL[shortURL]; !ok {
+		return nil, settings.ErrOriginalURLNotFound
+	}
+	if l.MarkedForDelURL[shortURL] {
+		return nil, ErrRecordMarkedForDel
+	}
+	if versions := l.Versions[shortURL]; len(versions) > 0 {
+		return slices.Clone(versions), nil
+	}
+	return []settings.LinkVersion{l.firstVersionLocked(shortURL)}, nil
+}
+
+// firstVersionLocked возвращает первую версию ссылки, которую не меняли после создания.
+func (l *LocalCache) firstVersionLocked(shortURL string) settings.LinkVersion {
+	attrs := l.ShortURLAttrs[shortURL]
+	return settings.LinkVersion{
+		Version:     1,
+		OriginalURL: l.ShortOriginalURL[shortURL],
+		Attrs:       attrs.Editable(),
+		CreatedAt:   attrs.CreatedAt,
+	}
+}
+
+// UpdateLink применяет к ссылке новую версию и добавляет ее в историю.
+// Номер версии должен следовать за текущим, иначе ссылку уже изменили и возвращается settings.ErrVersionConflict.
+// Счетчик переходов и момент создания ссылки сохраняются, состояние проверок сбрасывается при смене адреса.
+// Вместе с версией меняются теги tags и папка folder, значения nil не меняются.
+func (l *LocalCache) UpdateLink(ctx context.Context, shortURL string, version settings.LinkVersion, tags *[]string, folder *string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkCanUpdateLocked(shortURL, version); err != nil {
+		return err
+	}
+	newTags, newFolder, err := l.labelsLocked(shortURL, tags, folder)
+	if err != nil {
+		return err
+	}
+	l.updateLinkLocked(shortURL, version)
+	l.setLabelsLocked(shortURL, newTags, newFolder)
+	return nil
+}
+
+// checkCanUpdate проверяет, что версию можно применить: номер следует за текущим и новый адрес не нарушает дедупликацию.
+func (l *LocalCache) checkCanUpdate(shortURL string, version settings.LinkVersion) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.checkCanUpdateLocked(shortURL, version)
+}
+
+func (l *LocalCache) checkCanUpdateLocked(shortURL string, version settings.LinkVersion) error {
+	versions, err := l.linkVersionsLocked(shortURL)
+	if err != nil {
+		return err
+	}
+	if version.Version != versions[len(versions)-1].Version+1 {
+		return settings.ErrVersionConflict
+	}
+	if version.OriginalURL != l.ShortOriginalURL[shortURL] &&
+		l.dedupTakenLocked(l.dedupKey(version.OriginalURL, l.ShortURLUserID[shortURL]), time.Now()) {
+		return settings.ErrOriginalURLNotUnique
+	}
+	return nil
+}
+
+func (l *LocalCache) updateLink(shortURL string, version settings.LinkVersion) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateLinkLocked(shortURL, version)
+}
+
+func (l *LocalCache) updateLinkLocked(shortURL string, version settings.LinkVersion) {
+	originalURL, ok := l.ShortOriginalURL[shortURL]
+	if !ok {
+		return
+	}
+	if len(l.Versions[shortURL]) == 0 {
+		l.Versions[shortURL] = []settings.LinkVersion{l.firstVersionLocked(shortURL)}
+	}
+	current := l.ShortURLAttrs[shortURL]
+	attrs := version.Attrs
+	attrs.Clicks = current.Clicks
+	attrs.CreatedAt = current.CreatedAt
+	attrs.Tags = current.Tags
+	attrs.Folder = current.Folder
+	attrs.Metadata = current.Metadata
+	if version.OriginalURL == originalURL {
+		attrs.Health = current.Health
+	}
+	// ключ дедупликации переходит к новому адресу; при прежнем адресе ключ, занятый
+	// после истечения ссылки другой ссылкой, остается у нее
+	if version.OriginalURL != originalURL {
+		userID := l.ShortURLUserID[shortURL]
+		if key := l.dedupKey(originalURL, userID); key != "" && l.OriginalShortURL[key] == shortURL {
+			delete(l.OriginalShortURL, key)
+		}
+		if key := l.dedupKey(version.OriginalURL, userID); key != "" {
+			l.OriginalShortURL[key] = shortURL
+		}
+	}
+	l.ShortOriginalURL[shortURL] = version.OriginalURL
+	l.ShortURLAttrs[shortURL] = attrs
+	l.Versions[shortURL] = append(l.Versions[shortURL], version)
+}
+
+// UpdateLinkLabels меняет теги и папку ссылки. Значения nil не меняются.
+func (l *LocalCache) UpdateLinkLabels(ctx context.Context, shortURL string, tags *[]string, folder *string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	newTags, newFolder, err := l.labelsLocked(shortURL, tags, folder)
+	if err != nil {
+		return err
+	}
+	l.setLabelsLocked(shortURL, newTags, newFolder)
+	return nil
+}
+
+// labels возвращает теги и папку ссылки после изменения: значения nil заменяются текущими.
+func (l *LocalCache) labels(shortURL string, tags *[]string, folder *string) ([]string, string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.labelsLocked(shortURL, tags, folder)
+}
+
+func (l *LocalCache) labelsLocked(shortURL string, tags *[]string, folder *string) ([]string, string, error) {
+	attrs, ok := l.ShortURLAttrs[shortURL]
+	if !ok {
+		return nil, "", settings.ErrOriginalURLNotFound
+	}
+	if tags != nil {
+		attrs.Tags = *tags
+	}
+	if folder != nil {
+		attrs.Folder = *folder
+	}
+	return attrs.Tags, attrs.Folder, nil
+}
+
+func (l *LocalCache) setLabels(shortURL string, tags []string, folder string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setLabelsLocked(shortURL, tags, folder)
+}
+
+func (l *LocalCache) setLabelsLocked(shortURL string, tags []string, folder string) {
+	attrs, ok := l.ShortURLAttrs[shortURL]
+	if !ok {
+		return
+	}
+	attrs.Tags = tags
+	attrs.Folder = folder
+	l.ShortURLAttrs[shortURL] = attrs
+}
+
+// GetUserTags возвращает теги непомеченных на удаление ссылок пользователя с числом ссылок,
+// от самых частых, при равенстве - по алфавиту.
+func (l *LocalCache) GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	counts := make(map[string]int)
+	for shortURL, savedUserID := range l.ShortURLUserID {
+		if savedUserID != userID || l.MarkedForDelURL[shortURL] {
+			continue
+		}
+		for _, tag := range l.ShortURLAttrs[shortURL].Tags {
+			counts[tag]++
+		}
+	}
+	result := make([]settings.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, settings.TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(result, func(a, b settings.TagCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return result, nil
+}
+
+// MergeTags заменяет теги from у ссылок пользователя, в том числе помеченных на удаление, тегом to.
+// Возвращает число измененных ссылок.
+func (l *LocalCache) MergeTags(ctx context.Context, userID string, from []string, to string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	merged := l.mergedTagsLocked(userID, from, to)
+	for shortURL, tags := range merged {
+		l.setLabelsLocked(shortURL, tags, l.ShortURLAttrs[shortURL].Folder)
+	}
+	return len(merged), nil
+}
+
+// mergedTags возвращает новые теги ссылок пользователя, у которых есть теги from, после их замены тегом to.
+func (l *LocalCache) mergedTags(userID string, from []string, to string) map[string][]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.mergedTagsLocked(userID, from, to)
+}
+
+func (l *LocalCache) mergedTagsLocked(userID string, from []string, to string) map[string][]string {
+	merged := make(map[string][]string)
+	for shortURL, savedUserID := range l.ShortURLUserID {
+		tags := l.ShortURLAttrs[shortURL].Tags
+		if savedUserID != userID || !slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(from, tag) }) {
+			continue
+		}
+		newTags := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if slices.Contains(from, tag) {
+				tag = to
+			}
+			if !slices.Contains(newTags, tag) {
+				newTags = append(newTags, tag)
+			}
+		}
+		merged[shortURL] = newTags
+	}
+	return merged
+}
+
+// MarkRecordsForDeletion помечает запись на удаление и запоминает момент пометки.
 func (l *LocalCache) MarkRecordsForDeletion(ctx context.Context, records ...settings.Record) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	deletedAt := time.Now()
+	for _, shortURL := range l.deletableShortURLsLocked(records) {
+		l.markForDeletionLocked(shortURL, deletedAt)
+	}
+	return nil
+}
+
+// deletableShortURLs возвращает короткие урлы из records, которые принадлежат указанным пользователям
+// и еще не помечены на удаление.
+func (l *LocalCache) deletableShortURLs(records []settings.Record) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.deletableShortURLsLocked(records)
+}
+
+func (l *LocalCache) deletableShortURLsLocked(records []settings.Record) []string {
+	var shortURLs []string
 	for _, record := range records {
-		if l.ShortURLUserID[record.ShortURL] == record.UserID {
-			l.MarkedForDelURL[record.ShortURL] = true
+		userID, ok := l.ShortURLUserID[record.ShortURL]
+		if ok && userID == record.UserID && !l.MarkedForDelURL[record.ShortURL] && !slices.Contains(shortURLs, record.ShortURL) {
+			shortURLs = append(shortURLs, record.ShortURL)
 		}
 	}
+	return shortURLs
+}
+
+func (l *LocalCache) markForDeletion(shortURL string, deletedAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.markForDeletionLocked(shortURL, deletedAt)
+}
+
+func (l *LocalCache) markForDeletionLocked(shortURL string, deletedAt time.Time) {
+	if _, ok := l.ShortOriginalURL[shortURL]; !ok {
+		return
+	}
+	l.MarkedForDelURL[shortURL] = true
+	l.DeletedAt[shortURL] = deletedAt
+}
+
+// RestoreShortURL снимает со ссылки пометку на удаление. Для непомеченной ссылки ничего не делает.
+func (l *LocalCache) RestoreShortURL(ctx context.Context, shortURL string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.ShortOriginalURL[shortURL]; !ok {
+		return settings.ErrOriginalURLNotFound
+	}
+	l.restoreLocked(shortURL)
 	return nil
 }
 
+// markedForDeletion сообщает, помечена ли ссылка на удаление. Для отсутствующей ссылки возвращает ошибку.
+func (l *LocalCache) markedForDeletion(shortURL string) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if _, ok := l.ShortOriginalURL[shortURL]; !ok {
+		return false, settings.ErrOriginalURLNotFound
+	}
+	return l.MarkedForDelURL[shortURL], nil
+}
+
+func (l *LocalCache) restore(shortURL string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.restoreLocked(shortURL)
+}
+
+func (l *LocalCache) restoreLocked(shortURL string) {
+	delete(l.MarkedForDelURL, shortURL)
+	delete(l.DeletedAt, shortURL)
+}
+
+// deletedShortURLs возвращает короткие урлы, помеченные на удаление не позже deletedBefore.
+func (l *LocalCache) deletedShortURLs(deletedBefore time.Time) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var shortURLs []string
+	for shortURL, deletedAt := range l.DeletedAt {
+		if !deletedAt.After(deletedBefore) {
+			shortURLs = append(shortURLs, shortURL)
+		}
+	}
+	return shortURLs
+}
+
+// DeleteExpiredRecords безвозвратно удаляет записи, срок действия которых истек до expiredBefore.
+// reserve оставляет короткие урлы удаленных записей занятыми. Возвращает короткие урлы удаленных записей.
+func (l *LocalCache) DeleteExpiredRecords(ctx context.Context, expiredBefore time.Time, reserve bool) ([]string, error) {
+	shortURLs := l.expiredShortURLs(expiredBefore)
+	for _, shortURL := range shortURLs {
+		l.purgeShortURL(shortURL, reserve)
+	}
+	return shortURLs, nil
+}
+
+// PurgeDeletedRecords безвозвратно удаляет записи, помеченные на удаление не позже deletedBefore.
+// reserve оставляет короткие урлы удаленных записей занятыми. Возвращает короткие урлы удаленных записей.
+func (l *LocalCache) PurgeDeletedRecords(ctx context.Context, deletedBefore time.Time, reserve bool) ([]string, error) {
+	shortURLs := l.deletedShortURLs(deletedBefore)
+	for _, shortURL := range shortURLs {
+		l.purgeShortURL(shortURL, reserve)
+	}
+	return shortURLs, nil
+}
+
+// ReserveCodes сдвигает границу счетчика коротких кодов на n и возвращает прежнюю.
+func (l *LocalCache) ReserveCodes(ctx context.Context, n uint64) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := l.CodeCounter
+	l.CodeCounter += n
+	return start, nil
+}
+
+// raiseCodeCounter поднимает границу счетчика коротких кодов до n. Используется при восстановлении из файла.
+func (l *LocalCache) raiseCodeCounter(n uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.CodeCounter = max(l.CodeCounter, n)
+}
+
 // GetURLsCount подсчитывает количество коротких урлов.
 // Возвращает число коротких урлов.
 func (l *LocalCache) GetURLsCount(ctx context.Context) (int, error) {
@@ -152,7 +891,7 @@ type FileStorage struct {
 }
 
 // NewFileStorage создает экземпляр структуры FileStorage.
-func NewFileStorage(fileName string) (*FileStorage, error) {
+func NewFileStorage(fileName string, dedupMode settings.DedupMode) (*FileStorage, error) {
 	fileStorage := &FileStorage{}
 	producer, err := NewProducer(fileName)
 	if err != nil {
@@ -162,7 +901,7 @@ func NewFileStorage(fileName string) (*FileStorage, error) {
 	if err != nil {
 		return fileStorage, err
 	}
-	fileStorage.localCache = NewLocalCahce()
+	fileStorage.localCache = NewLocalCahce(dedupMode)
 	fileStorage.Consumer = consumer
 	fileStorage.Producer = producer
 	err = restoreData(fileStorage)
@@ -178,28 +917,107 @@ func (f *FileStorage) Close() error {
 }
 
 // SaveShortURL добавляет запись короткого и оригинального урлов в файл и в кэш.
-func (f *FileStorage) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string) error {
-	var event Event
+func (f *FileStorage) SaveShortURL(ctx context.Context, shortURL, originalURL, userID string, attrs settings.LinkAttributes) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	f.CurrentUUID++
-	event.UUID = strconv.Itoa(f.CurrentUUID)
+	if err := f.localCache.checkCanSave(shortURL, originalURL, userID); err != nil {
+		return err
+	}
+	return f.saveShortURLLocked(shortURL, originalURL, userID, attrs)
+}
+
+func (f *FileStorage) saveShortURLLocked(shortURL, originalURL, userID string, attrs settings.LinkAttributes) error {
+	var event Event
 	event.ShortURL = shortURL
 	event.OriginalURL = originalURL
 	event.UserID = userID
-	if err := f.Producer.WriteEvent(&event); err != nil {
+	event.ExpiresAt = attrs.ExpiresAt
+	event.MaxClicks = attrs.MaxClicks
+	event.PasswordHash = attrs.PasswordHash
+	event.CreatedAt = attrs.CreatedAt
+	event.Interstitial = attrs.Interstitial
+	event.FallbackURL = attrs.FallbackURL
+	event.QueryPassthrough = attrs.QueryPassthrough
+	event.UTM = attrs.UTM
+	event.RedirectStatus = attrs.RedirectStatus
+	event.Routing = attrs.Routing
+	event.Variants = attrs.Variants
+	event.Tags = attrs.Tags
+	event.Folder = attrs.Folder
+	if err := f.writeEventLocked(&event); err != nil {
 		return err
 	}
-	f.localCache.saveShortURL(shortURL, originalURL, userID)
+	f.localCache.saveShortURL(shortURL, originalURL, userID, attrs)
 	return nil
 }
 
-// GetOriginalURL возвращает оригинальный урл по переданному короткому.
-func (f *FileStorage) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
-	return f.localCache.GetOriginalURL(ctx, shortURL)
+// writeEventLocked присваивает событию очередной UUID и пишет его в файл.
+func (f *FileStorage) writeEventLocked(event *Event) error {
+	f.CurrentUUID++
+	event.UUID = strconv.Itoa(f.CurrentUUID)
+	return f.Producer.WriteEvent(event)
+}
+
+// GetOriginalURL возвращает оригинальный урл и атрибуты ссылки по переданному короткому и засчитывает переход,
+// если accept не вернул ошибку. Переход фиксируется в файле событием с признаком click, поэтому счетчик переходов переживает перезапуск.
+func (f *FileStorage) GetOriginalURL(ctx context.Context, shortURL string, accept settings.AcceptRedirect) (string, settings.LinkAttributes, error) {
+	if err := f.localCache.acceptRedirect(ctx, shortURL, accept); err != nil {
+		return "", settings.LinkAttributes{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, _, err := f.localCache.GetLink(ctx, shortURL); err != nil {
+		return "", settings.LinkAttributes{}, err
+	}
+	// событие пишется до изменения кэша, чтобы ошибка записи не засчитала переход
+	event := Event{ShortURL: shortURL, Click: true}
+	if err := f.writeEventLocked(&event); err != nil {
+		return "", settings.LinkAttributes{}, err
+	}
+	return f.localCache.addClickChecked(shortURL)
+}
+
+// GetLink возвращает оригинальный урл и атрибуты ссылки, не засчитывая переход.
+func (f *FileStorage) GetLink(ctx context.Context, shortURL string) (string, settings.LinkAttributes, error) {
+	return f.localCache.GetLink(ctx, shortURL)
+}
+
+// GetLinkVersions возвращает версии ссылки от первой до текущей.
+func (f *FileStorage) GetLinkVersions(ctx context.Context, shortURL string) ([]settings.LinkVersion, error) {
+	return f.localCache.GetLinkVersions(ctx, shortURL)
+}
+
+// UpdateLink применяет к ссылке новую версию и меняет теги tags и папку folder, значения nil не меняются.
+// Версия пишется в файл событием link_version, новые теги и папка - в том же событии с признаком labels,
+// поэтому изменение не применится частично.
+func (f *FileStorage) UpdateLink(ctx context.Context, shortURL string, version settings.LinkVersion, tags *[]string, folder *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.localCache.checkCanUpdate(shortURL, version); err != nil {
+		return err
+	}
+	event := Event{ShortURL: shortURL, LinkVersion: &version}
+	if tags != nil || folder != nil {
+		newTags, newFolder, err := f.localCache.labels(shortURL, tags, folder)
+		if err != nil {
+			return err
+		}
+		event.Labels, event.Tags, event.Folder = true, newTags, newFolder
+	}
+	if err := f.writeEventLocked(&event); err != nil {
+		return err
+	}
+	f.localCache.updateLink(shortURL, version)
+	if event.Labels {
+		f.localCache.setLabels(shortURL, event.Tags, event.Folder)
+	}
+	return nil
 }
 
 func restoreData(f *FileStorage) error {
+	// saved - число когда-либо сохраненных ссылок. Файлы, записанные до учета границы счетчика,
+	// ее не содержат, а выданные до этого значения счетчика не превышают числа сохраненных ссылок.
+	var saved uint64
 	for {
 		event, err := f.Consumer.ReadEvent()
 		if err != nil {
@@ -208,48 +1026,270 @@ func restoreData(f *FileStorage) error {
 			}
 			return err
 		}
-		f.localCache.saveShortURL(event.ShortURL, event.OriginalURL, event.UserID)
+		switch {
+		case event.CodeCounter != 0:
+			f.localCache.raiseCodeCounter(event.CodeCounter)
+		case event.Purged:
+			f.localCache.purgeShortURL(event.ShortURL, event.Reserved)
+		case event.Click:
+			f.localCache.addClick(event.ShortURL)
+		case event.Health != nil:
+			f.localCache.setHealth(event.ShortURL, *event.Health)
+		case event.LinkVersion != nil:
+			f.localCache.updateLink(event.ShortURL, *event.LinkVersion)
+			if event.Labels {
+				f.localCache.setLabels(event.ShortURL, event.Tags, event.Folder)
+			}
+		case event.MarkedForDel:
+			f.localCache.markForDeletion(event.ShortURL, event.DeletedAt)
+		case event.Restored:
+			f.localCache.restore(event.ShortURL)
+		case event.Labels:
+			f.localCache.setLabels(event.ShortURL, event.Tags, event.Folder)
+		case event.Metadata != nil:
+			f.localCache.setMetadata(event.ShortURL, *event.Metadata)
+		default:
+			attrs := settings.LinkAttributes{
+				ExpiresAt:        event.ExpiresAt,
+				MaxClicks:        event.MaxClicks,
+				PasswordHash:     event.PasswordHash,
+				CreatedAt:        event.CreatedAt,
+				Interstitial:     event.Interstitial,
+				FallbackURL:      event.FallbackURL,
+				QueryPassthrough: event.QueryPassthrough,
+				UTM:              event.UTM,
+				RedirectStatus:   event.RedirectStatus,
+				Routing:          event.Routing,
+				Variants:         event.Variants,
+				Tags:             event.Tags,
+				Folder:           event.Folder,
+			}
+			f.localCache.saveShortURL(event.ShortURL, event.OriginalURL, event.UserID, attrs)
+			saved++
+		}
 		f.CurrentUUID, err = strconv.Atoi(event.UUID)
 		if err != nil {
 			return err
 		}
 	}
 
+	f.localCache.raiseCodeCounter(saved)
 	f.Consumer.Close()
 	return nil
 }
 
+// ReserveCodes сдвигает границу счетчика коротких кодов на n и возвращает прежнюю.
+// Новая граница пишется в файл событием code_counter до выдачи кодов.
+func (f *FileStorage) ReserveCodes(ctx context.Context, n uint64) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.localCache.mu.RLock()
+	start := f.localCache.CodeCounter
+	f.localCache.mu.RUnlock()
+	event := Event{CodeCounter: start + n}
+	if err := f.writeEventLocked(&event); err != nil {
+		return 0, err
+	}
+	f.localCache.raiseCodeCounter(start + n)
+	return start, nil
+}
+
 // Ping - заглушка для закрытия интерфейса.
 func (f *FileStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
 // SaveShortURLs сохраняет список короткий-оригинальный урл.
-func (f *FileStorage) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string) error {
-	for shortURL, originalURL := range shortOriginalURLs {
-		err := f.SaveShortURL(ctx, shortURL, originalURL, userID)
-		if err != nil {
-			return err
+// Записи, конфликтующие с уже сохраненными, в файл не пишутся и возвращаются в мапе конфликтов.
+// Если занят один из алиасов aliases, в файл не пишется ни одна запись.
+func (f *FileStorage) SaveShortURLs(ctx context.Context, shortOriginalURLs map[string]string, userID string, aliases map[string]bool) (map[string]error, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.localCache.mu.RLock()
+	err := f.localCache.checkAliasesLocked(shortOriginalURLs, userID, aliases)
+	f.localCache.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	conflicts := make(map[string]error)
+	attrs := settings.LinkAttributes{CreatedAt: time.Now()}
+	for _, shortURL := range batchOrder(shortOriginalURLs, aliases) {
+		originalURL := shortOriginalURLs[shortURL]
+		if err := f.localCache.checkCanSave(shortURL, originalURL, userID); err != nil {
+			conflicts[shortURL] = err
+			continue
+		}
+		if err := f.saveShortURLLocked(shortURL, originalURL, userID, attrs); err != nil {
+			return conflicts, err
 		}
 	}
-	return nil
+	return conflicts, nil
 }
 
 // GetShortURL получает короткий урл из переданного оригинального.
-func (f *FileStorage) GetShortURL(ctx context.Context, originalURL string) (string, error) {
-	return f.localCache.GetShortURL(ctx, originalURL)
+func (f *FileStorage) GetShortURL(ctx context.Context, originalURL, userID string) (string, error) {
+	return f.localCache.GetShortURL(ctx, originalURL, userID)
+}
+
+// GetURLOwner возвращает id пользователя, создавшего короткий урл.
+func (f *FileStorage) GetURLOwner(ctx context.Context, shortURL string) (string, error) {
+	return f.localCache.GetURLOwner(ctx, shortURL)
 }
 
-// GetUserURLs возвращает список урлов пользователя.
-// Возвращает мапу (ключ - короткий урл, значение - оригинальный).
-func (f *FileStorage) GetUserURLs(ctx context.Context, userID string) (result map[string]string, err error) {
-	return f.localCache.GetUserURLs(ctx, userID)
+// GetUserURLs возвращает страницу урлов пользователя по параметрам выборки q.
+func (f *FileStorage) GetUserURLs(ctx context.Context, userID string, q settings.UserURLsQuery) (result []settings.UserURL, err error) {
+	return f.localCache.GetUserURLs(ctx, userID, q)
+}
+
+// GetLinksToCheck возвращает не более limit доступных ссылок, которые не проверялись с момента checkedBefore.
+func (f *FileStorage) GetLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int) (map[string]string, error) {
+	return f.localCache.GetLinksToCheck(ctx, checkedBefore, limit)
+}
+
+// UpdateLinkHealth сохраняет результат проверки доступности оригинального урла.
+// Событие с признаком health пишется в файл, только если изменились статус или число неудачных проверок,
+// иначе файл рос бы с каждым проходом проверки. Момент проверки без изменений хранится только в памяти,
+// поэтому после перезапуска такие ссылки проверяются повторно раньше срока.
+func (f *FileStorage) UpdateLinkHealth(ctx context.Context, shortURL string, status int, checkedAt time.Time, healthy bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.localCache.mu.RLock()
+	current := f.localCache.ShortURLAttrs[shortURL].Health
+	health, ok := f.localCache.nextHealthLocked(shortURL, status, checkedAt, healthy)
+	f.localCache.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if health.Status == current.Status && health.Failures == current.Failures && !current.CheckedAt.IsZero() {
+		f.localCache.setHealth(shortURL, health)
+		return nil
+	}
+	event := Event{ShortURL: shortURL, Health: &health}
+	if err := f.writeEventLocked(&event); err != nil {
+		return err
+	}
+	f.localCache.setHealth(shortURL, health)
+	return nil
+}
+
+// UpdateLinkMetadata сохраняет сведения о странице назначения ссылки и пишет их в файл событием metadata,
+// если ссылка все еще ведет на originalURL. Иначе возвращает settings.ErrVersionConflict.
+func (f *FileStorage) UpdateLinkMetadata(ctx context.Context, shortURL, originalURL string, meta settings.LinkMetadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.localCache.checkOriginalURL(shortURL, originalURL); err != nil {
+		return err
+	}
+	event := Event{ShortURL: shortURL, Metadata: &meta}
+	if err := f.writeEventLocked(&event); err != nil {
+		return err
+	}
+	f.localCache.setMetadata(shortURL, meta)
+	return nil
 }
 
 // MarkRecordsForDeletion помечает запись на удаление.
+// Пометка фиксируется в файле событием с признаком del и моментом пометки.
 func (f *FileStorage) MarkRecordsForDeletion(ctx context.Context, records ...settings.Record) error {
-	// + Написать обновление записи в файле
-	return f.localCache.MarkRecordsForDeletion(ctx, records...)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deletedAt := time.Now()
+	for _, shortURL := range f.localCache.deletableShortURLs(records) {
+		event := Event{ShortURL: shortURL, MarkedForDel: true, DeletedAt: deletedAt}
+		if err := f.writeEventLocked(&event); err != nil {
+			return err
+		}
+		f.localCache.markForDeletion(shortURL, deletedAt)
+	}
+	return nil
+}
+
+// UpdateLinkLabels меняет теги и папку ссылки. Новые теги и папка пишутся в файл событием с признаком labels.
+func (f *FileStorage) UpdateLinkLabels(ctx context.Context, shortURL string, tags *[]string, folder *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	newTags, newFolder, err := f.localCache.labels(shortURL, tags, folder)
+	if err != nil {
+		return err
+	}
+	return f.setLabelsLocked(shortURL, newTags, newFolder)
+}
+
+func (f *FileStorage) setLabelsLocked(shortURL string, tags []string, folder string) error {
+	event := Event{ShortURL: shortURL, Labels: true, Tags: tags, Folder: folder}
+	if err := f.writeEventLocked(&event); err != nil {
+		return err
+	}
+	f.localCache.setLabels(shortURL, tags, folder)
+	return nil
+}
+
+// GetUserTags возвращает теги непомеченных на удаление ссылок пользователя с числом ссылок.
+func (f *FileStorage) GetUserTags(ctx context.Context, userID string) ([]settings.TagCount, error) {
+	return f.localCache.GetUserTags(ctx, userID)
+}
+
+// MergeTags заменяет теги from у ссылок пользователя тегом to. Новые теги каждой ссылки пишутся в файл
+// событием с признаком labels. Возвращает число измененных ссылок.
+func (f *FileStorage) MergeTags(ctx context.Context, userID string, from []string, to string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var updated int
+	for shortURL, tags := range f.localCache.mergedTags(userID, from, to) {
+		_, folder, err := f.localCache.labels(shortURL, nil, nil)
+		if err != nil {
+			return updated, err
+		}
+		if err := f.setLabelsLocked(shortURL, tags, folder); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// RestoreShortURL снимает со ссылки пометку на удаление. Восстановление фиксируется в файле событием с признаком restored.
+func (f *FileStorage) RestoreShortURL(ctx context.Context, shortURL string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	marked, err := f.localCache.markedForDeletion(shortURL)
+	if err != nil || !marked {
+		return err
+	}
+	event := Event{ShortURL: shortURL, Restored: true}
+	if err := f.writeEventLocked(&event); err != nil {
+		return err
+	}
+	f.localCache.restore(shortURL)
+	return nil
+}
+
+// DeleteExpiredRecords безвозвратно удаляет записи, срок действия которых истек до expiredBefore.
+// Удаление фиксируется в файле событием с признаком purged.
+// Возвращает короткие урлы удаленных записей.
+func (f *FileStorage) DeleteExpiredRecords(ctx context.Context, expiredBefore time.Time, reserve bool) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.purgeLocked(f.localCache.expiredShortURLs(expiredBefore), reserve)
+}
+
+// PurgeDeletedRecords безвозвратно удаляет записи, помеченные на удаление не позже deletedBefore.
+// Удаление фиксируется в файле событием с признаком purged. Возвращает короткие урлы удаленных записей.
+func (f *FileStorage) PurgeDeletedRecords(ctx context.Context, deletedBefore time.Time, reserve bool) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.purgeLocked(f.localCache.deletedShortURLs(deletedBefore), reserve)
+}
+
+func (f *FileStorage) purgeLocked(shortURLs []string, reserve bool) ([]string, error) {
+	for i, shortURL := range shortURLs {
+		event := Event{ShortURL: shortURL, Purged: true, Reserved: reserve}
+		if err := f.writeEventLocked(&event); err != nil {
+			return shortURLs[:i], err
+		}
+		f.localCache.purgeShortURL(shortURL, reserve)
+	}
+	return shortURLs, nil
 }
 
 // GetURLsCount подсчитывает количество коротких урлов.